@@ -0,0 +1,70 @@
+package biz
+
+import (
+	"context"
+	"fmt"
+
+	model "proposal-template/models"
+)
+
+// EventHandler reacts to a domain event. Handlers run in the transaction of the change that
+// emitted the event, returning an error rolls the change back.
+type EventHandler func(ctx context.Context, event model.DomainEvent) error
+
+// EventDispatcher delivers the domain events emitted by the services to the handlers
+// subscribed to them, in process and synchronously.
+type EventDispatcher struct {
+	handlers map[string][]EventHandler
+	// wildcard handlers receive every event
+	wildcard []EventHandler
+}
+
+func NewEventDispatcher() *EventDispatcher {
+	return &EventDispatcher{handlers: make(map[string][]EventHandler)}
+}
+
+// Subscribe registers handler for the events named eventName, see model.DomainEvent.
+func (d *EventDispatcher) Subscribe(eventName string, handler EventHandler) {
+	d.handlers[eventName] = append(d.handlers[eventName], handler)
+}
+
+// SubscribeAll registers handler for every event.
+func (d *EventDispatcher) SubscribeAll(handler EventHandler) {
+	d.wildcard = append(d.wildcard, handler)
+}
+
+// Dispatch calls the handlers of each event in order, those subscribed to every event
+// first. It stops at the first error.
+func (d *EventDispatcher) Dispatch(ctx context.Context, events ...model.DomainEvent) error {
+	for _, event := range events {
+		for _, handler := range d.wildcard {
+			if err := handler(ctx, event); err != nil {
+				return fmt.Errorf("failed to handle %s: %w", event.EventName(), err)
+			}
+		}
+		for _, handler := range d.handlers[event.EventName()] {
+			if err := handler(ctx, event); err != nil {
+				return fmt.Errorf("failed to handle %s: %w", event.EventName(), err)
+			}
+		}
+	}
+	return nil
+}
+
+// IOutbox stores messages in the transaction carried by ctx, to be published once it commits.
+type IOutbox interface {
+	Add(ctx context.Context, msgs ...model.OutboxMessage) error
+}
+
+// OutboxHandler returns the handler storing events in outbox, from where they are
+// published to Kafka. Subscribed to every event, it must be the only way events leave
+// the process so that they are only published for committed changes.
+func OutboxHandler(outbox IOutbox) EventHandler {
+	return func(ctx context.Context, event model.DomainEvent) error {
+		msg, err := model.NewOutboxMessage(event.AggregateType(), event.AggregateID(), event.EventName(), event)
+		if err != nil {
+			return err
+		}
+		return outbox.Add(ctx, msg)
+	}
+}
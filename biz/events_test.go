@@ -0,0 +1,136 @@
+package biz
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	model "proposal-template/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recorder subscribes to every event of a dispatcher and records their names
+type recorder struct {
+	names []string
+}
+
+func (r *recorder) handle(_ context.Context, event model.DomainEvent) error {
+	r.names = append(r.names, event.EventName())
+	return nil
+}
+
+func TestEventDispatcher_Dispatch(t *testing.T) {
+	dispatcher := NewEventDispatcher()
+	var calls []string
+	dispatcher.Subscribe(model.EventUserCreated, func(context.Context, model.DomainEvent) error {
+		calls = append(calls, "created")
+		return nil
+	})
+	dispatcher.SubscribeAll(func(_ context.Context, event model.DomainEvent) error {
+		calls = append(calls, "all "+event.EventName())
+		return nil
+	})
+	dispatcher.Subscribe(model.EventEmailVerified, func(context.Context, model.DomainEvent) error {
+		return errors.New("boom")
+	})
+
+	err := dispatcher.Dispatch(context.Background(), model.UserCreated{}, model.UserUpdated{}, model.EmailVerified{})
+
+	assert.ErrorContains(t, err, "failed to handle EmailVerified: boom")
+	assert.Equal(t, []string{"all UserCreated", "created", "all UserUpdated", "all EmailVerified"}, calls)
+}
+
+type fakeOutbox struct {
+	msgs []model.OutboxMessage
+}
+
+func (f *fakeOutbox) Add(_ context.Context, msgs ...model.OutboxMessage) error {
+	f.msgs = append(f.msgs, msgs...)
+	return nil
+}
+
+func TestOutboxHandler(t *testing.T) {
+	outbox := &fakeOutbox{}
+	event := model.EmailVerified{EventID: "e1", UserID: "u1", Email: "jane@example.com"}
+
+	require.NoError(t, OutboxHandler(outbox)(context.Background(), event))
+
+	require.Len(t, outbox.msgs, 1)
+	msg := outbox.msgs[0]
+	assert.Equal(t, "user", msg.AggregateType)
+	assert.Equal(t, "u1", msg.AggregateID)
+	assert.Equal(t, model.EventEmailVerified, msg.EventType)
+	assert.JSONEq(t, `{"event_id":"e1","occurred_at":"0001-01-01T00:00:00Z","user_id":"u1","email":"jane@example.com"}`, string(msg.Payload))
+}
+
+// fakeUserRepo keeps a single user, the methods it does not override panic
+type fakeUserRepo struct {
+	IUserRepo
+	user model.User
+	// writtenAfterRead simulates a concurrent write right after each read
+	writtenAfterRead bool
+}
+
+func (f *fakeUserRepo) GetByID(_ context.Context, id uuid.UUID) (*model.User, error) {
+	if id != f.user.Id {
+		return nil, model.ErrRecordNotFound
+	}
+	user := f.user
+	if f.writtenAfterRead {
+		f.user.Version++
+	}
+	return &user, nil
+}
+
+func (f *fakeUserRepo) PartialUpdateIfVersion(_ context.Context, _ uuid.UUID, version int64, fields map[string]interface{}) error {
+	if version != f.user.Version {
+		return model.ErrConflict
+	}
+	if verified, ok := fields["email_verified"].(bool); ok {
+		f.user.EmailVerified = verified
+	}
+	f.user.Version++
+	return nil
+}
+
+func TestUserService_VerifyEmailEmitsEvents(t *testing.T) {
+	repo := &fakeUserRepo{user: model.User{BaseModel: model.BaseModel{Id: uuid.New()}, Versioned: model.Versioned{Version: 1}}}
+	events := &recorder{}
+	dispatcher := NewEventDispatcher()
+	dispatcher.SubscribeAll(events.handle)
+	service := NewUserService(repo, WithEventDispatcher(dispatcher))
+	id := repo.user.Id.String()
+
+	user, err := service.VerifyEmail(context.Background(), id, nil)
+	require.NoError(t, err)
+	assert.True(t, user.EmailVerified)
+	assert.Equal(t, []string{model.EventUserUpdated, model.EventEmailVerified}, events.names)
+
+	// Verifying again changes nothing
+	_, err = service.VerifyEmail(context.Background(), id, nil)
+	require.NoError(t, err)
+	assert.Len(t, events.names, 2)
+	assert.Equal(t, int64(2), repo.user.Version)
+}
+
+func TestUserService_VerifyEmailChecksVersion(t *testing.T) {
+	repo := &fakeUserRepo{user: model.User{BaseModel: model.BaseModel{Id: uuid.New()}, Versioned: model.Versioned{Version: 3}}}
+	service := NewUserService(repo)
+	_, err := service.VerifyEmail(context.Background(), repo.user.Id.String(), model.VersionMatch{2})
+
+	assert.ErrorIs(t, err, model.ErrPreconditionFailed)
+	assert.False(t, repo.user.EmailVerified)
+}
+
+func TestUserService_VerifyEmailConflictsWithConcurrentWrite(t *testing.T) {
+	repo := &fakeUserRepo{user: model.User{BaseModel: model.BaseModel{Id: uuid.New()}, Versioned: model.Versioned{Version: 3}}}
+	repo.writtenAfterRead = true
+	service := NewUserService(repo)
+	_, err := service.VerifyEmail(context.Background(), repo.user.Id.String(), model.VersionMatch{3})
+
+	assert.ErrorIs(t, err, model.ErrConflict)
+	assert.False(t, repo.user.EmailVerified)
+}
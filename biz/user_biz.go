@@ -2,21 +2,47 @@ package biz
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	model "proposal-template/models"
 	"proposal-template/pkg/logger"
+	"proposal-template/pkg/query"
 
+	"github.com/google/uuid"
 	// "github.com/golobby/container/v3"
-	"gorm.io/gorm"
 )
 
 type IUserRepo interface {
+	GetByID(ctx context.Context, id uuid.UUID) (*model.User, error)
 	GetByColumn(ctx context.Context, column string, value interface{}) (*model.User, error)
-	List(ctx context.Context, paging model.Paging, query *gorm.DB) ([]model.User, error)
-	Create(ctx context.Context, user model.User) (uint, error)
+	List(ctx context.Context, paging model.Paging, spec query.Spec) (model.Page[model.User], error)
+	Create(ctx context.Context, user model.User) (uuid.UUID, error)
+	Update(ctx context.Context, user model.User) error
+	PartialUpdate(ctx context.Context, id uuid.UUID, fields map[string]interface{}) error
+	PartialUpdateIfVersion(ctx context.Context, id uuid.UUID, version int64, fields map[string]interface{}) error
+	DeleteByID(ctx context.Context, id uuid.UUID) error
+	DeleteByIDIfVersion(ctx context.Context, id uuid.UUID, version int64) error
+	Restore(ctx context.Context, id uuid.UUID) error
+	Exists(ctx context.Context, column string, value interface{}) (bool, error)
+}
+
+// ITxManager runs fn in a transaction that every repository called with the ctx passed to
+// fn takes part in. Nested calls use savepoints.
+type ITxManager interface {
+	Do(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// noTxManager runs functions without a transaction, it is used when none is configured
+type noTxManager struct{}
+
+func (noTxManager) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
 }
 
 type UserService struct {
 	repo IUserRepo
+	txm  ITxManager
+	events *EventDispatcher
 	logger logger.ILogger
 }
 
@@ -25,6 +51,8 @@ func NewUserService(repo IUserRepo, opts ...Option) *UserService {
 
 	userService := &UserService{
 		repo: repo,
+		txm:  noTxManager{},
+		events: NewEventDispatcher(),
 	}
 
 	for _, opt := range opts {
@@ -33,8 +61,296 @@ func NewUserService(repo IUserRepo, opts ...Option) *UserService {
 	return userService
 }
 
-func (s *UserService)  GetById(id string) (*model.User, error) {
-	return s.repo.GetByColumn(context.Background(), "id", id)
+func (s *UserService) GetById(ctx context.Context, id string) (*model.User, error) {
+	userID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, model.ErrInvalidID
+	}
+
+	user, err := s.repo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, translateUserError(err)
+	}
+	return user, nil
+}
+
+func (s *UserService) List(ctx context.Context, paging model.Paging, spec query.Spec) (model.Page[model.User], error) {
+	page, err := s.repo.List(ctx, paging, spec)
+	if err != nil {
+		return page, translateUserError(err)
+	}
+	return page, nil
+}
+
+func (s *UserService) Create(ctx context.Context, input model.UserCreate) (*model.User, error) {
+	var created *model.User
+	err := s.txm.Do(ctx, func(ctx context.Context) error {
+		taken, err := s.repo.Exists(ctx, "email", input.Email)
+		if err != nil {
+			return fmt.Errorf("failed to check email availability: %w", err)
+		}
+		if taken {
+			return model.ErrEmailNotAvailable
+		}
+
+		user := model.User{
+			Name:  input.Name,
+			Email: input.Email,
+		}
+		id, err := s.repo.Create(ctx, user)
+		if err != nil {
+			return translateUserError(err)
+		}
+
+		// Read the row back to get the values generated by the database
+		created, err = s.repo.GetByID(ctx, id)
+		if err != nil {
+			return translateUserError(err)
+		}
+		return s.events.Dispatch(ctx, model.NewUserCreated(*created))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// Update replaces the mutable fields of the user identified by id. When expectedVersions is
+// set, the user is only updated if it is still at one of those versions. A concurrent update
+// between the read and the write is reported as ErrConflict.
+// UserUpdated is emitted, followed by EmailVerified when the email address becomes verified.
+func (s *UserService) Update(ctx context.Context, id string, input model.UserUpdate, expectedVersions model.VersionMatch) (*model.User, error) {
+	var updated *model.User
+	err := s.txm.Do(ctx, func(ctx context.Context) error {
+		user, err := s.GetById(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err := checkVersion(user, expectedVersions); err != nil {
+			return err
+		}
+
+		if input.Email != user.Email {
+			if err := s.ensureEmailAvailable(ctx, input.Email, user.Id); err != nil {
+				return err
+			}
+		}
+
+		wasVerified := user.EmailVerified
+		user.Name = input.Name
+		user.Email = input.Email
+		user.EmailVerified = input.EmailVerified
+		if err := s.repo.Update(ctx, *user); err != nil {
+			return translateUserError(err)
+		}
+
+		updated, err = s.GetById(ctx, id)
+		if err != nil {
+			return err
+		}
+		return s.dispatchUpdated(ctx, wasVerified, updated)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// Patch changes only the fields present in input, see Update for expectedVersions and the
+// events emitted.
+func (s *UserService) Patch(ctx context.Context, id string, input model.UserPatch, expectedVersions model.VersionMatch) (*model.User, error) {
+	userID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, model.ErrInvalidID
+	}
+
+	var patched *model.User
+	err = s.txm.Do(ctx, func(ctx context.Context) error {
+		user, err := s.GetById(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err := checkVersion(user, expectedVersions); err != nil {
+			return err
+		}
+
+		fields := make(map[string]interface{})
+		if input.Name != nil {
+			fields["name"] = *input.Name
+		}
+		if input.Email != nil {
+			if err := s.ensureEmailAvailable(ctx, *input.Email, userID); err != nil {
+				return err
+			}
+			fields["email"] = *input.Email
+		}
+		if input.EmailVerified != nil {
+			fields["email_verified"] = *input.EmailVerified
+		}
+
+		if err := s.repo.PartialUpdateIfVersion(ctx, userID, user.Version, fields); err != nil {
+			return translateUserError(err)
+		}
+
+		patched, err = s.GetById(ctx, id)
+		if err != nil {
+			return err
+		}
+		return s.dispatchUpdated(ctx, user.EmailVerified, patched)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return patched, nil
+}
+
+// VerifyEmail marks the email address of the user identified by id as verified, see Update
+// for expectedVersions and the events emitted. Verifying an address already verified
+// changes nothing and emits no event.
+func (s *UserService) VerifyEmail(ctx context.Context, id string, expectedVersions model.VersionMatch) (*model.User, error) {
+	userID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, model.ErrInvalidID
+	}
+
+	var verified *model.User
+	err = s.txm.Do(ctx, func(ctx context.Context) error {
+		user, err := s.GetById(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err := checkVersion(user, expectedVersions); err != nil {
+			return err
+		}
+		if user.EmailVerified {
+			verified = user
+			return nil
+		}
+
+		if err := s.repo.PartialUpdateIfVersion(ctx, userID, user.Version, map[string]interface{}{"email_verified": true}); err != nil {
+			return translateUserError(err)
+		}
+
+		verified, err = s.GetById(ctx, id)
+		if err != nil {
+			return err
+		}
+		return s.dispatchUpdated(ctx, false, verified)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return verified, nil
+}
+
+// Delete soft-deletes the user identified by id, see Update for expectedVersions.
+// The user can be restored until the purge job removes it.
+func (s *UserService) Delete(ctx context.Context, id string, expectedVersions model.VersionMatch) error {
+	userID, err := uuid.Parse(id)
+	if err != nil {
+		return model.ErrInvalidID
+	}
+
+	if expectedVersions == nil {
+		return translateUserError(s.repo.DeleteByID(ctx, userID))
+	}
+	return s.txm.Do(ctx, func(ctx context.Context) error {
+		user, err := s.GetById(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err := checkVersion(user, expectedVersions); err != nil {
+			return err
+		}
+		return translateUserError(s.repo.DeleteByIDIfVersion(ctx, userID, user.Version))
+	})
+}
+
+// Restore undeletes the soft-deleted user identified by id. It fails with
+// ErrEmailNotAvailable if another user took its email address in the meantime.
+func (s *UserService) Restore(ctx context.Context, id string) (*model.User, error) {
+	userID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, model.ErrInvalidID
+	}
+
+	var restored *model.User
+	err = s.txm.Do(ctx, func(ctx context.Context) error {
+		if err := s.repo.Restore(ctx, userID); err != nil {
+			return translateUserError(err)
+		}
+
+		restored, err = s.GetById(ctx, id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return restored, nil
+}
+
+// dispatchUpdated emits the events of an update that left user in its current state,
+// wasVerified telling whether its email address was verified before.
+func (s *UserService) dispatchUpdated(ctx context.Context, wasVerified bool, user *model.User) error {
+	events := []model.DomainEvent{model.NewUserUpdated(*user)}
+	if user.EmailVerified && !wasVerified {
+		events = append(events, model.NewEmailVerified(*user))
+	}
+	return s.events.Dispatch(ctx, events...)
+}
+
+// checkVersion returns ErrPreconditionFailed if expectedVersions is set and does not name the user's version.
+func checkVersion(user *model.User, expectedVersions model.VersionMatch) error {
+	if !expectedVersions.Matches(user.Version) {
+		return model.ErrPreconditionFailed
+	}
+	return nil
+}
+
+// ensureEmailAvailable returns ErrEmailNotAvailable if a user other than owner already uses email.
+// The unique constraint on users.email still guards against concurrent requests.
+func (s *UserService) ensureEmailAvailable(ctx context.Context, email string, owner uuid.UUID) error {
+	existing, err := s.repo.GetByColumn(ctx, "email", email)
+	if errors.Is(err, model.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check email availability: %w", err)
+	}
+	if existing.Id == owner {
+		return nil
+	}
+	return model.ErrEmailNotAvailable
+}
+
+// translateUserError maps the generic datalayer errors to user domain errors.
+func translateUserError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, model.ErrRecordNotFound):
+		return model.ErrUserNotFound
+	case errors.Is(err, model.ErrDuplicateKey):
+		return model.ErrEmailNotAvailable
+	case errors.Is(err, query.ErrInvalidSpec):
+		return model.ErrInvalidFilter
+	}
+	return err
+}
+
+// WithTxManager makes the service run its multi-step operations in a transaction
+func WithTxManager(txm ITxManager) Option {
+	return func(h *UserService) {
+		h.txm = txm
+	}
+}
+
+// WithEventDispatcher makes the service emit its domain events through dispatcher, events
+// are dropped by default
+func WithEventDispatcher(dispatcher *EventDispatcher) Option {
+	return func(h *UserService) {
+		h.events = dispatcher
+	}
 }
 
 func WithLogger(logger logger.ILogger) Option {
@@ -42,4 +358,3 @@ func WithLogger(logger logger.ILogger) Option {
 		h.logger = logger
 	}
 }
-
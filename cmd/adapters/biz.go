@@ -1,19 +1,38 @@
 package adapters
 
 import (
-	"fmt"
 	"proposal-template/biz"
+	"proposal-template/datalayers/datasources/repositories"
 	"proposal-template/pkg/logger"
+	utils "proposal-template/pkg/utils/config"
 	"proposal-template/presentation/http/handler"
 
 	"github.com/golobby/container/v3"
 )
 
 func IoCBiz() {
+	container.Singleton(func() *biz.EventDispatcher {
+		var (
+			appConfig  utils.AppConfig
+			outboxRepo *repositories.OutboxRepo
+		)
+
+		container.Resolve(&appConfig)
+		container.Resolve(&outboxRepo)
+
+		dispatcher := biz.NewEventDispatcher()
+		// Events only leave the process through the outbox, when they can be published
+		if appConfig.Kafka.Enabled {
+			dispatcher.SubscribeAll(biz.OutboxHandler(outboxRepo))
+		}
+		return dispatcher
+	})
+
 	container.TransientLazy(func() handler.IUserService{
 		var (
 			logger  logger.ILogger
 			userRepo biz.IUserRepo
+			txManager biz.ITxManager
 		)
 
 		err := container.Resolve(&logger)
@@ -24,13 +43,19 @@ func IoCBiz() {
 		if err != nil {
 			panic(err)
 		}
+		err = container.Resolve(&txManager)
+		if err != nil {
+			panic(err)
+		}
 		
-		userService := biz.NewUserService(
+		var eventDispatcher *biz.EventDispatcher
+		container.Resolve(&eventDispatcher)
+
+		return biz.NewUserService(
 			userRepo,
 			biz.WithLogger(logger),
+			biz.WithTxManager(txManager),
+			biz.WithEventDispatcher(eventDispatcher),
 		)
-		fmt.Println("UserService successfully registered in IoC")
-
-		return userService
 	})
 }
\ No newline at end of file
@@ -1,18 +1,31 @@
 package adapters
 
 import (
+	"time"
+
 	utils "proposal-template/pkg/utils/config"
 
 	"github.com/golobby/container/v3"
 )
 
-func IoCConfig() {
+// IoCConfig loads and registers the configuration, and the Reloader applying its
+// reloadable fields. The error lists every invalid field, see utils.ValidationError.
+//
+// The registered AppConfig is the configuration loaded on startup, the components applying
+// a reloadable field subscribe to the Reloader.
+func IoCConfig(opts ...utils.LoadOption) error {
+	cfg, err := utils.LoadConfig(opts...)
+	if err != nil {
+		return err
+	}
 	container.Singleton(func() utils.AppConfig {
-		cfg, err := utils.LoadConfig()
-		if err != nil {
-			panic(err)
-		}
-		// fmt.Println("Config successfully registered in IoC:", cfg.Httpserver.Port)
 		return *cfg
 	})
-}
\ No newline at end of file
+	container.Singleton(func() *utils.Reloader {
+		return utils.NewReloader(*cfg,
+			utils.WithLoadOptions(opts...),
+			utils.WithReloadInterval(time.Duration(cfg.Reload.IntervalInSecs)*time.Second),
+		)
+	})
+	return nil
+}
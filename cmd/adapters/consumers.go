@@ -0,0 +1,32 @@
+package adapters
+
+import (
+	company_kafka "proposal-template/datalayers/datasources/external/consumers"
+	"proposal-template/pkg/kafka"
+	"proposal-template/pkg/logger"
+
+	confluent "github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/golobby/container/v3"
+)
+
+// IoCCompanyConsumer registers the consumer of the company updates, it requires
+// IoCKafkaConsumer.
+func IoCCompanyConsumer() {
+	container.Singleton(func() *company_kafka.CompanyConsumer {
+		var (
+			logger         logger.ILogger
+			consumer       *confluent.Consumer
+			schemaRegistry *kafka.SchemaRegistry
+		)
+
+		container.Resolve(&logger)
+		container.Resolve(&consumer)
+		container.Resolve(&schemaRegistry)
+
+		subscriber, err := kafka.NewKafkaSubscriber(consumer, schemaRegistry, company_kafka.CompanyTopic)
+		if err != nil {
+			panic(err)
+		}
+		return company_kafka.NewCompanyConsumer(subscriber, logger)
+	})
+}
@@ -1,31 +1,164 @@
 package adapters
 
 import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
 	cockroachdb "proposal-template/pkg/database/cockroachDB"
 	"proposal-template/pkg/logger"
+	"proposal-template/pkg/secrets"
+	utils "proposal-template/pkg/utils/config"
 
 	"github.com/golobby/container/v3"
 	"gorm.io/gorm"
 )
-func IoCDatabase() {
+// IoCDatabase registers the database, connecting to it right away: cancelling ctx stops
+// retrying the connection.
+func IoCDatabase(ctx context.Context) {
 	container.Singleton(func() *gorm.DB {
 		var (
 			logger  logger.ILogger
+			cfg     utils.AppConfig
 		)
 
 		err := container.Resolve(&logger)
 		if err != nil {
 			panic(err)
 		}
-	
-		db, err := cockroachdb.NewCockroachDB(
+		container.Resolve(&cfg)
+
+		dbConfig := cfg.Database
+		logLevel, err := cockroachdb.ParseGormLogLevel(dbConfig.LogLevel)
+		if err != nil {
+			panic(err)
+		}
+
+		// The password comes from the URI when set, either may reference a secret
+		var secretManager *secrets.Manager
+		container.Resolve(&secretManager)
+		passwordRef := dbConfig.Password
+		if dbConfig.URI != "" {
+			passwordRef = dbConfig.URI
+		}
+		resolved := dbConfig
+		if err := resolveSecrets(secretManager, &resolved.URI, &resolved.Password); err != nil {
+			panic(err)
+		}
+
+		opts := []cockroachdb.Option{
+			cockroachdb.WithURI(resolved.ConnectionURI()),
+			cockroachdb.WithMaxOpenConns(dbConfig.MaxOpenConns),
+			cockroachdb.WithMaxIdleConns(dbConfig.MaxIdleConns),
+			cockroachdb.WithConnMaxLifetime(dbConfig.ConnMaxLifetimeInSecs),
+			cockroachdb.WithConnMaxIdleTime(dbConfig.ConnMaxIdleTimeInSecs),
+			cockroachdb.WithStatementTimeout(time.Duration(dbConfig.StatementTimeoutInMs)*time.Millisecond),
+			cockroachdb.WithConnectRetry(
+				dbConfig.ConnectAttempts,
+				time.Duration(dbConfig.ConnectRetryIntervalInMs)*time.Millisecond,
+				time.Duration(dbConfig.ConnectMaxRetryIntervalInMs)*time.Millisecond,
+			),
 			cockroachdb.WithLogger(logger),
-		)
+			cockroachdb.WithLogLevel(logLevel),
+			cockroachdb.WithGormLogger(
+				cockroachdb.WithSlowQueryThreshold(time.Duration(dbConfig.SlowQueryThresholdInMs)*time.Millisecond),
+				// Parameters may be personal data, they are kept out of the production logs
+				cockroachdb.WithParamRedaction(cfg.Env == "production"),
+			),
+		}
+		// New connections authenticate with the current secret, the idle ones are reset once
+		// it is rotated
+		if secrets.IsReference(passwordRef) {
+			opts = append(opts, cockroachdb.WithPasswordFunc(func(ctx context.Context) (string, error) {
+				return databasePassword(ctx, secretManager, passwordRef, dbConfig.URI != "")
+			}))
+		}
+
+		db, err := cockroachdb.NewCockroachDB(ctx, opts...)
 		if err != nil {
 			panic(err)
 		}
-		
-		cockroachdb.CockroachDBGooseMigrate(db, cockroachdb.CockroachDBMigrateFS, "migrations")
+		secretManager.Watch(passwordRef, func(string) {
+			if err := cockroachdb.ResetIdleConns(db, dbConfig.MaxIdleConns); err != nil {
+				logger.Error(fmt.Sprintf("Failed to reset the database connections: %s", err))
+			}
+		})
 		return db
 	})
-}
\ No newline at end of file
+
+	container.Singleton(func() *cockroachdb.Migrator {
+		var (
+			db  *gorm.DB
+			cfg utils.AppConfig
+		)
+
+		if err := container.Resolve(&db); err != nil {
+			panic(err)
+		}
+		if err := container.Resolve(&cfg); err != nil {
+			panic(err)
+		}
+
+		migrator, err := cockroachdb.NewMigrator(db, nil,
+			cockroachdb.WithLockTimeout(time.Duration(cfg.Migration.LockTimeoutInSecs)*time.Second),
+		)
+		if err != nil {
+			panic(err)
+		}
+		return migrator
+	})
+
+	// Shared by the TxManager and the repositories so retries are counted in one place
+	container.Singleton(func() *cockroachdb.TxRunner {
+		var db *gorm.DB
+
+		container.Resolve(&db)
+		return cockroachdb.NewTxRunner(db)
+	})
+}
+
+// databasePassword returns the password referenced by ref, the connection URI when isURI
+func databasePassword(ctx context.Context, manager *secrets.Manager, ref string, isURI bool) (string, error) {
+	secret, err := manager.Resolve(ctx, ref)
+	if err != nil || !isURI {
+		return secret, err
+	}
+	u, err := url.Parse(secret)
+	if err != nil {
+		return "", fmt.Errorf("invalid database URI: %w", err)
+	}
+	password, _ := u.User.Password()
+	return password, nil
+}
+
+// AutoMigrate applies the pending migrations when DB_AUTO_MIGRATE is set, it must run
+// before the components using the database are started.
+func AutoMigrate() {
+	var (
+		cfg      utils.AppConfig
+		logger   logger.ILogger
+		migrator *cockroachdb.Migrator
+	)
+
+	if err := container.Resolve(&cfg); err != nil {
+		panic(err)
+	}
+	if !cfg.Migration.AutoMigrate {
+		return
+	}
+	if err := container.Resolve(&logger); err != nil {
+		panic(err)
+	}
+	if err := container.Resolve(&migrator); err != nil {
+		panic(err)
+	}
+
+	results, err := migrator.Up(context.Background(), 0)
+	if err != nil {
+		panic(fmt.Errorf("failed to apply the database migrations: %w", err))
+	}
+	for _, result := range results {
+		logger.Info(fmt.Sprintf("Migration applied: %s", result))
+	}
+}
@@ -0,0 +1,28 @@
+package adapters
+
+import (
+	"proposal-template/pkg/features"
+	utils "proposal-template/pkg/utils/config"
+
+	"github.com/golobby/container/v3"
+)
+
+// IoCFeatures registers the feature flags, they follow the reloads of FEATURE_FLAGS.
+func IoCFeatures() {
+	container.Singleton(func() *features.Flags {
+		var (
+			appConfig utils.AppConfig
+			reloader  *utils.Reloader
+		)
+		container.Resolve(&appConfig)
+		container.Resolve(&reloader)
+
+		flags := features.New(appConfig.Features.Enabled)
+		reloader.Subscribe(func(change utils.Change) {
+			if change.Changed("FEATURE_FLAGS") {
+				flags.Set(change.New.Features.Enabled)
+			}
+		})
+		return flags
+	})
+}
@@ -0,0 +1,156 @@
+package adapters
+
+import (
+	"fmt"
+
+	"proposal-template/datalayers/datasources/repositories"
+	"proposal-template/pkg/kafka"
+	"proposal-template/pkg/logger"
+	"proposal-template/pkg/secrets"
+	utils "proposal-template/pkg/utils/config"
+	"proposal-template/presentation/event"
+
+	confluent "github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/golobby/container/v3"
+)
+
+// IoCKafka registers the Kafka producer and the schema registry when Kafka is enabled,
+// and routes the domain events of the outbox to their topic.
+func IoCKafka() {
+	var appConfig utils.AppConfig
+	container.Resolve(&appConfig)
+	if !appConfig.Kafka.Enabled {
+		return
+	}
+	cfg := appConfig.Kafka
+
+	container.Singleton(func() *confluent.Producer {
+		producer, err := kafka.NewKafkaProducer(
+			kafka.WithBrokers(cfg.Brokers),
+			kafka.WithClientID(cfg.ClientID),
+			kafka.WithSASL(mustKafkaSASL(cfg)),
+		)
+		if err != nil {
+			panic(err)
+		}
+		watchSASLCredentials(cfg, producer.SetSaslCredentials)
+		return producer
+	})
+
+	registerSchemaRegistry(cfg)
+
+	// Replicas not relaying the outbox do not publish
+	var outboxRelay *repositories.OutboxRelay
+	if err := container.Resolve(&outboxRelay); err != nil {
+		return
+	}
+
+	var (
+		producer       *confluent.Producer
+		schemaRegistry *kafka.SchemaRegistry
+	)
+	container.Resolve(&producer)
+	container.Resolve(&schemaRegistry)
+
+	for _, e := range event.UserEvents {
+		schemaID, err := schemaRegistry.FindOrCreateArvoSchema(e.Topic, event.SchemasFS, e.SchemaFile)
+		if err != nil {
+			panic(fmt.Sprintf("failed to register the schema of %s: %s", e.Name, err))
+		}
+		publisher, err := kafka.NewKafkaPublisher(producer, schemaRegistry, schemaID, e.Topic)
+		if err != nil {
+			panic(fmt.Sprintf("failed to create the publisher of %s: %s", e.Name, err))
+		}
+		outboxRelay.Route(e.Name, publisher, e.New)
+	}
+}
+
+// IoCKafkaConsumer registers the Kafka consumer and the schema registry of the consumer
+// pipeline named group. Each pipeline has its own consumer group,
+// KAFKA_CONSUMER_GROUP_ID suffixed with the name of the pipeline.
+func IoCKafkaConsumer(group string) {
+	var appConfig utils.AppConfig
+	container.Resolve(&appConfig)
+	cfg := appConfig.Kafka
+
+	container.Singleton(func() *confluent.Consumer {
+		consumer, err := kafka.NewKafkaConsumer(
+			kafka.WithBrokers(cfg.Brokers),
+			kafka.WithClientID(cfg.ClientID),
+			kafka.WithConsumerGroupID(fmt.Sprintf("%s.%s", cfg.GroupID, group)),
+			kafka.WithAutoOffsetReset(cfg.AutoOffsetReset),
+			kafka.WithEnableAutoCommit(cfg.EnableAutoCommit),
+			kafka.WithMaxPollIntervalMs(cfg.MaxPollIntervalMs),
+			kafka.WithSessionTimeoutMs(cfg.SessionTimeoutMs),
+			kafka.WithHeartbeatIntervalMs(cfg.HeartbeatIntervalMs),
+			kafka.WithRetryBackoffMs(cfg.RetryBackoffMs),
+			kafka.WithFetchMinBytes(cfg.FetchMinBytes),
+			kafka.WithFetchWaitMaxMs(cfg.FetchWaitMaxMs),
+			kafka.WithSASL(mustKafkaSASL(cfg)),
+		)
+		if err != nil {
+			panic(err)
+		}
+		watchSASLCredentials(cfg, consumer.SetSaslCredentials)
+		return consumer
+	})
+
+	registerSchemaRegistry(cfg)
+}
+
+func mustKafkaSASL(cfg utils.KafkaConfig) kafka.SASLConfig {
+	sasl, err := kafkaSASL(cfg)
+	if err != nil {
+		panic(err)
+	}
+	return sasl
+}
+
+// kafkaSASL returns the security settings of cfg, with the credentials referencing a
+// secret resolved
+func kafkaSASL(cfg utils.KafkaConfig) (kafka.SASLConfig, error) {
+	var secretManager *secrets.Manager
+	container.Resolve(&secretManager)
+
+	sasl := kafka.SASLConfig{
+		SecurityProtocol: cfg.SecurityProtocol,
+		Mechanism:        cfg.SASLMechanism,
+		Username:         cfg.SASLUsername,
+		Password:         cfg.SASLPassword,
+	}
+	err := resolveSecrets(secretManager, &sasl.Username, &sasl.Password)
+	return sasl, err
+}
+
+// watchSASLCredentials applies the rotated SASL credentials of cfg with setCredentials, the
+// client keeps its connections and authenticates the new ones with them
+func watchSASLCredentials(cfg utils.KafkaConfig, setCredentials func(username, password string) error) {
+	var (
+		secretManager *secrets.Manager
+		log           logger.ILogger
+	)
+	container.Resolve(&secretManager)
+	container.Resolve(&log)
+
+	rotate := func(string) {
+		sasl, err := kafkaSASL(cfg)
+		if err == nil {
+			err = setCredentials(sasl.Username, sasl.Password)
+		}
+		if err != nil {
+			log.Error(fmt.Sprintf("Failed to apply the rotated Kafka credentials: %s", err))
+		}
+	}
+	secretManager.Watch(cfg.SASLUsername, rotate)
+	secretManager.Watch(cfg.SASLPassword, rotate)
+}
+
+func registerSchemaRegistry(cfg utils.KafkaConfig) {
+	container.Singleton(func() *kafka.SchemaRegistry {
+		schemaRegistry, err := kafka.NewSchemaRegistry(kafka.WithSchemaRegistryURL(cfg.SchemaRegistryURL))
+		if err != nil {
+			panic(err)
+		}
+		return schemaRegistry
+	})
+}
@@ -2,12 +2,26 @@ package adapters
 
 import (
 	"proposal-template/pkg/logger"
+	utils "proposal-template/pkg/utils/config"
 
 	"github.com/golobby/container/v3"
 )
 
-func IoCLogger(){
+func IoCLogger() {
 	container.Singleton(func() logger.ILogger {
-		return logger.NewLogger("debug")
+		var (
+			appConfig utils.AppConfig
+			reloader  *utils.Reloader
+		)
+		container.Resolve(&appConfig)
+		container.Resolve(&reloader)
+
+		log := logger.NewLogger(appConfig.Logger.Level)
+		reloader.Subscribe(func(change utils.Change) {
+			if change.Changed("LOG_LEVEL") {
+				log.SetLevel(change.New.Logger.Level)
+			}
+		})
+		return log
 	})
-}
\ No newline at end of file
+}
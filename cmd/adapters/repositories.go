@@ -2,22 +2,143 @@ package adapters
 
 import (
 	"fmt"
+	"time"
+
 	"proposal-template/biz"
 	"proposal-template/datalayers/datasources/repositories"
+	cockroachdb "proposal-template/pkg/database/cockroachDB"
+	"proposal-template/pkg/logger"
+	"proposal-template/pkg/query"
+	"proposal-template/pkg/secrets"
+	utils "proposal-template/pkg/utils/config"
 
 	"github.com/golobby/container/v3"
 	"gorm.io/gorm"
 )
 
 func IoCRepositories() {
-	container.Singleton(func() biz.IUserRepo {
+	container.Singleton(func() *query.CursorCodec {
+		var (
+			appConfig     utils.AppConfig
+			logger        logger.ILogger
+			secretManager *secrets.Manager
+		)
+
+		container.Resolve(&appConfig)
+		container.Resolve(&logger)
+		container.Resolve(&secretManager)
+		secret := appConfig.Pagination.CursorSecret
+		if secret == "" {
+			logger.Warn("PAGINATION_CURSOR_SECRET is not set, pagination cursors will not survive a restart")
+			return query.NewRandomCursorCodec()
+		}
+		if err := resolveSecrets(secretManager, &secret); err != nil {
+			panic(fmt.Errorf("failed to resolve PAGINATION_CURSOR_SECRET: %w", err))
+		}
+		// A reference is only checked against its secret here
+		if len(secret) < query.MinCursorSecretSize {
+			panic(fmt.Errorf("PAGINATION_CURSOR_SECRET must be at least %d characters long", query.MinCursorSecretSize))
+		}
+		return query.NewCursorCodec([]byte(secret))
+	})
+
+	container.Singleton(func() biz.ITxManager {
+		var (
+			db       *gorm.DB
+			txRunner *cockroachdb.TxRunner
+		)
+
+		container.Resolve(&db)
+		container.Resolve(&txRunner)
+		return repositories.NewTxManager(db, repositories.WithTxRetry(txRunner))
+	})
+
+	container.Singleton(func() *repositories.UserRepo {
 		var (
-			db *gorm.DB
+			db          *gorm.DB
+			cursorCodec *query.CursorCodec
+			txRunner    *cockroachdb.TxRunner
 		)
 
 		container.Resolve(&db)
-		userRepo := repositories.NewUserRepo(db)
-		fmt.Println("UserRepo successfully registered in IoC")
+		container.Resolve(&cursorCodec)
+		container.Resolve(&txRunner)
+		return repositories.NewUserRepo(
+			db,
+			repositories.WithCursorCodec(cursorCodec),
+			repositories.WithRetryOnWrite(txRunner),
+		)
+	})
+
+	container.Singleton(func() biz.IUserRepo {
+		var userRepo *repositories.UserRepo
+
+		container.Resolve(&userRepo)
 		return userRepo
 	})
+
+	container.Singleton(func() *repositories.OutboxRepo {
+		var db *gorm.DB
+
+		container.Resolve(&db)
+		return repositories.NewOutboxRepo(db)
+	})
+
+	var appConfig utils.AppConfig
+	container.Resolve(&appConfig)
+
+	// The relays of the replicas take turns, holding the outbox lock
+	if appConfig.Outbox.RelayEnabled {
+		container.Singleton(func() *repositories.OutboxRelay {
+			var (
+				logger     logger.ILogger
+				db         *gorm.DB
+				outboxRepo *repositories.OutboxRepo
+			)
+
+			container.Resolve(&logger)
+			container.Resolve(&db)
+			container.Resolve(&outboxRepo)
+
+			sqlDB, err := db.DB()
+			if err != nil {
+				panic(err)
+			}
+
+			cfg := appConfig.Outbox
+			return repositories.NewOutboxRelay(
+				outboxRepo,
+				logger,
+				repositories.WithOutboxLease(cockroachdb.NewTableLocker(sqlDB, cockroachdb.WithLockID(cockroachdb.OutboxLockID, "outbox relay"))),
+				repositories.WithOutboxPollInterval(time.Duration(cfg.PollIntervalInMs)*time.Millisecond),
+				repositories.WithOutboxBatchSize(cfg.BatchSize),
+				repositories.WithOutboxRetry(cfg.MaxAttempts, repositories.DefaultOutboxInitialBackoff, repositories.DefaultOutboxMaxBackoff),
+				repositories.WithOutboxRetention(time.Duration(cfg.RetentionInHours)*time.Hour),
+			)
+		})
+	}
+
+	// The purge job is left out of the container when it is disabled
+	if appConfig.SoftDelete.RetentionInDays <= 0 {
+		return
+	}
+
+	container.Singleton(func() *repositories.PurgeJob {
+		var (
+			logger   logger.ILogger
+			userRepo *repositories.UserRepo
+		)
+
+		container.Resolve(&logger)
+		container.Resolve(&userRepo)
+
+		cfg := appConfig.SoftDelete
+		purgeJob := repositories.NewPurgeJob(
+			logger,
+			time.Duration(cfg.RetentionInDays)*24*time.Hour,
+			repositories.WithPurgeInterval(time.Duration(cfg.PurgeIntervalInMins)*time.Minute),
+		)
+		purgeJob.Add("users", userRepo)
+		return purgeJob
+	})
 }
@@ -0,0 +1,55 @@
+package adapters
+
+import (
+	"context"
+	"time"
+
+	"proposal-template/pkg/logger"
+	"proposal-template/pkg/secrets"
+	utils "proposal-template/pkg/utils/config"
+
+	"github.com/golobby/container/v3"
+)
+
+// IoCSecrets registers the secrets.Manager resolving the configuration fields referencing
+// a secret. The encrypted provider is only available with SECRETS_KEY_FILE.
+func IoCSecrets() {
+	container.Singleton(func() *secrets.Manager {
+		var (
+			log logger.ILogger
+			cfg utils.AppConfig
+		)
+		if err := container.Resolve(&log); err != nil {
+			panic(err)
+		}
+		container.Resolve(&cfg)
+
+		opts := []secrets.Option{
+			secrets.WithRefreshInterval(time.Duration(cfg.Secrets.RefreshIntervalInSecs) * time.Second),
+		}
+		if cfg.Secrets.KeyFile != "" {
+			key, err := secrets.LoadKey(cfg.Secrets.KeyFile)
+			if err != nil {
+				panic(err)
+			}
+			provider, err := secrets.NewEncryptedFileProvider(key)
+			if err != nil {
+				panic(err)
+			}
+			opts = append(opts, secrets.WithProvider("encrypted", provider))
+		}
+		return secrets.NewManager(log, opts...)
+	})
+}
+
+// resolveSecrets replaces the values referencing a secret with the secret
+func resolveSecrets(manager *secrets.Manager, values ...*string) error {
+	for _, value := range values {
+		secret, err := manager.Resolve(context.Background(), *value)
+		if err != nil {
+			return err
+		}
+		*value = secret
+	}
+	return nil
+}
@@ -1,9 +1,14 @@
 package adapters
 
 import (
+	"fmt"
+	"strings"
+	"time"
+
 	"proposal-template/pkg/logger"
 	utils "proposal-template/pkg/utils/config"
 	"proposal-template/presentation/http"
+	"proposal-template/presentation/http/middleware"
 
 	"github.com/golobby/container/v3"
 )
@@ -20,15 +25,55 @@ func IoCServer() {
 		if err != nil {
 			panic(err)
 		}
-		
+
 		var appConfig utils.AppConfig
 		container.Resolve(&appConfig)
-		server := httpserver.NewHTTPServer(
+		opts := []httpserver.Option{
 			httpserver.WithLogger(logger),
 			httpserver.WithConfig(appConfig.Httpserver),
-		)
-		
+		}
+
+		resolvers, err := tenantResolvers(appConfig.Tenant)
+		if err != nil {
+			panic(err)
+		}
+		if len(resolvers) > 0 {
+			opts = append(opts, httpserver.WithTenantResolution(appConfig.Tenant.Required, resolvers...))
+		}
+
+		server := httpserver.NewHTTPServer(opts...)
+
+		var reloader *utils.Reloader
+		container.Resolve(&reloader)
+		reloader.Subscribe(func(change utils.Change) {
+			if change.Changed("HTTP_REQUEST_TIMEOUT_SECS") {
+				server.SetRequestTimeout(time.Duration(change.New.Httpserver.RequestTimeoutInSecs) * time.Second)
+			}
+		})
+
 		// fmt.Println("HTTPServer successfully registered in IoC") ==> Debugging
 		return server
 	})
-}
\ No newline at end of file
+}
+
+// tenantResolvers returns the resolvers listed in cfg.Resolvers, in order
+func tenantResolvers(cfg utils.TenantConfig) ([]middleware.TenantResolver, error) {
+	var resolvers []middleware.TenantResolver
+	for _, name := range strings.Split(cfg.Resolvers, ",") {
+		switch strings.TrimSpace(name) {
+		case "":
+		case "header":
+			resolvers = append(resolvers, middleware.HeaderTenant(cfg.Header))
+		case "jwt":
+			resolvers = append(resolvers, middleware.ClaimTenant(cfg.JWTClaimsKey, cfg.JWTClaim))
+		case "subdomain":
+			if cfg.BaseDomain == "" {
+				return nil, fmt.Errorf("tenant resolver %q requires TENANT_BASE_DOMAIN", name)
+			}
+			resolvers = append(resolvers, middleware.SubdomainTenant(cfg.BaseDomain))
+		default:
+			return nil, fmt.Errorf("unknown tenant resolver %q", name)
+		}
+	}
+	return resolvers, nil
+}
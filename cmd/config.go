@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"proposal-template/pkg/cli"
+	utils "proposal-template/pkg/utils/config"
+
+	"github.com/golobby/container/v3"
+)
+
+const configDescription = `Commands:
+  check   load the configuration and report whether it is valid
+  print   print the effective configuration as a dotenv file, the secrets masked
+  docs    document every variable: its default, type and description`
+
+func configCommand() *cli.Command {
+	var format string
+
+	return &cli.Command{
+		Name:        "config",
+		Usage:       "COMMAND",
+		Summary:     "Inspect the configuration",
+		Description: configDescription,
+		Flags: func(flags *flag.FlagSet) {
+			flags.StringVar(&format, "format", "markdown", "format of the docs command: markdown or env, an .env.example")
+		},
+		Run: func(ctx context.Context, globals cli.Globals, args []string) error {
+			if len(args) != 1 {
+				return cli.ErrUsage
+			}
+
+			switch args[0] {
+			case "check", "print":
+				if err := wireBase(globals); err != nil {
+					return err
+				}
+
+				var cfg utils.AppConfig
+				if err := container.Resolve(&cfg); err != nil {
+					return err
+				}
+				if args[0] == "print" {
+					return utils.WriteEnv(os.Stdout, cfg)
+				}
+				fmt.Printf("Configuration of the %s environment is valid\n", cfg.Env)
+				return nil
+			case "docs":
+				switch format {
+				case "markdown":
+					return utils.WriteMarkdown(os.Stdout)
+				case "env":
+					return utils.WriteEnvExample(os.Stdout)
+				default:
+					return cli.Usagef("unknown docs format %q, expected markdown or env", format)
+				}
+			default:
+				return cli.Usagef("unknown config command %q", args[0])
+			}
+		},
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+
+	"proposal-template/cmd/adapters"
+	company_kafka "proposal-template/datalayers/datasources/external/consumers"
+	"proposal-template/pkg/cli"
+	"proposal-template/pkg/lifecycle"
+	"proposal-template/presentation"
+
+	"github.com/golobby/container/v3"
+)
+
+// consumers wire the Kafka consumer pipelines by name, the Kafka consumer is already
+// registered
+var consumers = map[string]func() (lifecycle.Runnable, error){
+	"company": func() (lifecycle.Runnable, error) {
+		adapters.IoCCompanyConsumer()
+
+		var consumer *company_kafka.CompanyConsumer
+		err := container.Resolve(&consumer)
+		return consumer, err
+	},
+}
+
+func consumeCommand() *cli.Command {
+	var group string
+
+	return &cli.Command{
+		Name:    "consume",
+		Summary: "Run a Kafka consumer pipeline",
+		Flags: func(flags *flag.FlagSet) {
+			flags.StringVar(&group, "group", "", "consumer pipeline to run: "+strings.Join(consumerNames(), ", "))
+		},
+		Run: func(ctx context.Context, globals cli.Globals, args []string) error {
+			if len(args) > 0 {
+				return cli.ErrUsage
+			}
+			wire, ok := consumers[group]
+			if !ok {
+				return cli.Usagef("unknown consumer group %q", group)
+			}
+
+			if err := wireBase(globals); err != nil {
+				return err
+			}
+			adapters.IoCKafkaConsumer(group)
+			consumer, err := wire()
+			if err != nil {
+				return err
+			}
+
+			if err := presentation.NewConsumer(group, consumer).Run(ctx); err != nil {
+				return fmt.Errorf("consumer stopped with error: %w", err)
+			}
+			return nil
+		},
+	}
+}
+
+func consumerNames() []string {
+	names := make([]string, 0, len(consumers))
+	for name := range consumers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
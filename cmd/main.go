@@ -1,22 +1,60 @@
 package main
 
 import (
-	"fmt"
+	"context"
+	"errors"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
 	"proposal-template/cmd/adapters"
-	"proposal-template/presentation"
+	"proposal-template/pkg/cli"
+	utils "proposal-template/pkg/utils/config"
 )
 
-func init() {
-	fmt.Println("Initializing IoC container...") // Debugging
-	adapters.IoCConfig()
+func main() {
+	app := cli.New(filepath.Base(os.Args[0]), cli.WithDefaultCommand("serve"))
+	app.Register(
+		serveCommand(),
+		workerCommand(),
+		consumeCommand(),
+		migrateCommand(),
+		configCommand(),
+	)
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if err := app.Run(ctx, os.Args[1:]); err != nil {
+		stop()
+		if errors.Is(err, cli.ErrUsage) {
+			os.Exit(2)
+		}
+		log.Fatalf("%v", err)
+	}
+}
+
+// wireBase registers the configuration, loaded as the global flags say, the logger, the
+// feature flags and the secrets every command needs.
+func wireBase(globals cli.Globals) error {
+	var opts []utils.LoadOption
+	if globals.ConfigFile != "" {
+		opts = append(opts, utils.WithFile(globals.ConfigFile))
+	}
+	if globals.Env != "" {
+		opts = append(opts, utils.WithEnvironment(globals.Env))
+	}
+	if len(globals.Set) > 0 {
+		opts = append(opts, utils.WithOverrides(globals.Set...))
+	}
+
+	if err := adapters.IoCConfig(opts...); err != nil {
+		return err
+	}
 	adapters.IoCLogger()
-	adapters.IoCDatabase()
-	adapters.IoCRepositories()
-	adapters.IoCBiz()
-	adapters.IoCServer()
-	fmt.Println("IoC container initialized.") 
+	adapters.IoCFeatures()
+	adapters.IoCSecrets()
+	return nil
 }
-func main() { 
-	app := presentation.NewServer()
-	app.Run()
-}
\ No newline at end of file
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"proposal-template/cmd/adapters"
+	"proposal-template/pkg/cli"
+	cockroachdb "proposal-template/pkg/database/cockroachDB"
+
+	"github.com/golobby/container/v3"
+	"github.com/pressly/goose/v3"
+)
+
+const migrateDescription = `Commands:
+  up [VERSION]     apply the pending migrations, up to VERSION when given
+  down [VERSION]   roll back the last migration, or every migration newer than VERSION
+  redo             roll back the last migration and apply it again
+  status           list the migrations and whether they are applied
+  version          print the version of the last applied migration
+  create NAME      write an empty migration in DIR
+
+The migrations are not applied on startup for this command, whatever DB_AUTO_MIGRATE says.`
+
+func migrateCommand() *cli.Command {
+	var dir string
+
+	return &cli.Command{
+		Name:        "migrate",
+		Usage:       "COMMAND [ARGS]",
+		Summary:     "Apply, roll back or inspect the database migrations",
+		Description: migrateDescription,
+		Flags: func(flags *flag.FlagSet) {
+			flags.StringVar(&dir, "dir", cockroachdb.MigrationsDir, "directory the create command writes migrations to")
+		},
+		Run: func(ctx context.Context, globals cli.Globals, args []string) error {
+			if len(args) == 0 {
+				return cli.ErrUsage
+			}
+			return runMigrate(ctx, globals, dir, args[0], args[1:])
+		},
+	}
+}
+
+func runMigrate(ctx context.Context, globals cli.Globals, dir, command string, args []string) error {
+	// create only writes a file, it does not need the database
+	if command == "create" {
+		if len(args) != 1 {
+			return cli.Usagef("create expects the name of the migration")
+		}
+		path, err := cockroachdb.CreateMigration(dir, args[0])
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Created %s\n", path)
+		return nil
+	}
+
+	switch command {
+	case "up", "down", "redo", "status", "version":
+	default:
+		return cli.Usagef("unknown migrate command %q", command)
+	}
+
+	migrator, err := newMigrator(ctx, globals)
+	if err != nil {
+		return err
+	}
+	out := os.Stdout
+
+	switch command {
+	case "up":
+		version, err := versionArg(args, 0)
+		if err != nil {
+			return err
+		}
+		results, err := migrator.Up(ctx, version)
+		printResults(out, results)
+		if err == nil && len(results) == 0 {
+			fmt.Fprintln(out, "No migration to apply")
+		}
+		return err
+	case "down":
+		version, err := versionArg(args, -1)
+		if err != nil {
+			return err
+		}
+		results, err := migrator.Down(ctx, version)
+		printResults(out, results)
+		return err
+	case "redo":
+		results, err := migrator.Redo(ctx)
+		printResults(out, results)
+		return err
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		printStatus(out, statuses)
+	case "version":
+		version, err := migrator.Version(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(out, version)
+	}
+	return nil
+}
+
+// newMigrator wires the components the migrations need, and only those.
+func newMigrator(ctx context.Context, globals cli.Globals) (*cockroachdb.Migrator, error) {
+	if err := wireBase(globals); err != nil {
+		return nil, err
+	}
+	adapters.IoCDatabase(ctx)
+
+	var migrator *cockroachdb.Migrator
+	if err := container.Resolve(&migrator); err != nil {
+		return nil, err
+	}
+	return migrator, nil
+}
+
+// versionArg parses the optional version argument of up and down.
+func versionArg(args []string, defaultVersion int64) (int64, error) {
+	switch len(args) {
+	case 0:
+		return defaultVersion, nil
+	case 1:
+		version, err := strconv.ParseInt(args[0], 10, 64)
+		if err != nil || version < 0 {
+			return 0, cli.Usagef("invalid version %q", args[0])
+		}
+		return version, nil
+	default:
+		return 0, cli.Usagef("too many arguments")
+	}
+}
+
+func printResults(out io.Writer, results []*goose.MigrationResult) {
+	for _, result := range results {
+		fmt.Fprintln(out, result)
+	}
+}
+
+func printStatus(out io.Writer, statuses []*goose.MigrationStatus) {
+	w := tabwriter.NewWriter(out, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tMIGRATION\tSTATE\tAPPLIED AT")
+	for _, status := range statuses {
+		appliedAt := ""
+		if !status.AppliedAt.IsZero() {
+			appliedAt = status.AppliedAt.Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", status.Source.Version, status.Source.Path, status.State, appliedAt)
+	}
+	w.Flush()
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"proposal-template/cmd/adapters"
+	"proposal-template/pkg/cli"
+	"proposal-template/presentation"
+)
+
+func serveCommand() *cli.Command {
+	var workers bool
+
+	return &cli.Command{
+		Name:    "serve",
+		Summary: "Serve the HTTP API",
+		Flags: func(flags *flag.FlagSet) {
+			flags.BoolVar(&workers, "workers", true, "also run the background jobs, disable it when they run in a worker process")
+		},
+		Run: func(ctx context.Context, globals cli.Globals, args []string) error {
+			if len(args) > 0 {
+				return cli.ErrUsage
+			}
+
+			if err := wireBase(globals); err != nil {
+				return err
+			}
+			adapters.IoCDatabase(ctx)
+			adapters.AutoMigrate()
+			adapters.IoCRepositories()
+			adapters.IoCKafka()
+			adapters.IoCBiz()
+			adapters.IoCServer()
+
+			if err := presentation.NewServer(workers).Run(ctx); err != nil {
+				return fmt.Errorf("server stopped with error: %w", err)
+			}
+			return nil
+		},
+	}
+}
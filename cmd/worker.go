@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"proposal-template/cmd/adapters"
+	"proposal-template/pkg/cli"
+	"proposal-template/presentation"
+)
+
+func workerCommand() *cli.Command {
+	return &cli.Command{
+		Name:    "worker",
+		Summary: "Run the background jobs: purge of soft-deleted rows and outbox relay",
+		Description: "The jobs are configured as for serve, for example OUTBOX_RELAY_ENABLED. " +
+			"Run serve with --workers=false so that they do not also run in the API processes.",
+		Run: func(ctx context.Context, globals cli.Globals, args []string) error {
+			if len(args) > 0 {
+				return cli.ErrUsage
+			}
+
+			if err := wireBase(globals); err != nil {
+				return err
+			}
+			adapters.IoCDatabase(ctx)
+			adapters.AutoMigrate()
+			adapters.IoCRepositories()
+			adapters.IoCKafka()
+
+			if err := presentation.NewWorker().Run(ctx); err != nil {
+				return fmt.Errorf("worker stopped with error: %w", err)
+			}
+			return nil
+		},
+	}
+}
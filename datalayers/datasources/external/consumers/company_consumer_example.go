@@ -3,20 +3,29 @@
 
 FOR EXAMPLE, WE NEED INFORMATION OF COMPANY ENTITES FROM COMPANY SERVICE VIA MESSAGE QUEUE
 BELOW IS AN EXAMPLE OF HOW TO GET COMPANY INFORMATION FROM COMPANY SERVICE VIA MESSAGE QUEUE
-(not working yet)
+
+It runs in its own process with `consume --group company`.
 */
 
 package company_kafka
 
 import (
+	"context"
+	"fmt"
+	"sync"
 
 	"proposal-template/pkg/kafka"
+	"proposal-template/pkg/lifecycle"
+	"proposal-template/pkg/logger"
 )
 
+// CompanyTopic is the topic the company service publishes the changes of its companies to
+const CompanyTopic = "company-updates"
+
 type CompanyMessage struct {
-	ID   string `json:"id"`
-	Name string `json:"name"`
-	City string `json:"city"`
+	ID   string `json:"id" avro:"id"`
+	Name string `json:"name" avro:"name"`
+	City string `json:"city" avro:"city"`
 }
 
 // EventName implements the ConsumerMessage interface
@@ -24,16 +33,104 @@ func (c *CompanyMessage) EventName() string {
 	return "CompanyMessage"
 }
 
-// // CompanyConsumer handles consuming messages from the "company-updates" topic
+// CompanyHandler processes a company message, the message is committed when it returns nil.
+type CompanyHandler func(ctx context.Context, msg *CompanyMessage) error
+
+// CompanyConsumer handles consuming messages from the "company-updates" topic
 type CompanyConsumer struct {
 	subscriber kafka.Subscriber
+	handler    CompanyHandler
+	logger     logger.ILogger
+
+	stop     chan struct{}
+	stopOnce sync.Once
 }
 
-func NewCompanyConsumer(subscriber kafka.Subscriber)*CompanyConsumer {
-	return &CompanyConsumer{}
+var _ lifecycle.Runnable = (*CompanyConsumer)(nil)
+
+type Option func(*CompanyConsumer)
+
+// WithHandler sets the handler of the messages, they are only logged by default
+func WithHandler(handler CompanyHandler) Option {
+	return func(c *CompanyConsumer) {
+		c.handler = handler
+	}
+}
+
+func NewCompanyConsumer(subscriber kafka.Subscriber, logger logger.ILogger, opts ...Option) *CompanyConsumer {
+	c := &CompanyConsumer{
+		subscriber: subscriber,
+		logger:     logger,
+		stop:       make(chan struct{}),
+	}
+	c.handler = c.logMessage
+
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
 }
 
-// func (c *CompanyConsumer) ConsumeMessages() {
-// 	c.subscriber.ConsumeMessages()
-// }
+// Start consumes the messages until ctx is cancelled or Stop is called. A message whose
+// handler fails is logged and not committed, it is received again after a restart unless
+// a later message is committed.
+func (c *CompanyConsumer) Start(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-c.stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
 
+	if err := c.subscriber.SubscribeToTopic(ctx); err != nil {
+		return err
+	}
+	chMsg, chErr, chCommit := c.subscriber.ConsumeMessages(ctx, func() kafka.ConsumerMessage {
+		return &CompanyMessage{}
+	})
+
+	// The channels are closed together once ctx is cancelled, chErr must be drained until
+	// then or the subscriber blocks
+	for {
+		select {
+		case msg, ok := <-chMsg:
+			if !ok {
+				return nil
+			}
+			chCommit <- c.handle(ctx, msg)
+		case err, ok := <-chErr:
+			if !ok {
+				chErr = nil
+				continue
+			}
+			c.logger.Error(fmt.Sprintf("Company consumer: %s", err))
+		}
+	}
+}
+
+func (c *CompanyConsumer) Stop(_ context.Context) error {
+	c.stopOnce.Do(func() { close(c.stop) })
+	return nil
+}
+
+// handle runs the handler and reports whether the message can be committed
+func (c *CompanyConsumer) handle(ctx context.Context, msg kafka.ConsumerMessage) bool {
+	company, ok := msg.(*CompanyMessage)
+	if !ok {
+		c.logger.Error(fmt.Sprintf("Company consumer: unexpected message %T", msg))
+		return true
+	}
+	if err := c.handler(ctx, company); err != nil {
+		c.logger.Error(fmt.Sprintf("Company consumer: failed to handle company %s: %s", company.ID, err))
+		return false
+	}
+	return true
+}
+
+func (c *CompanyConsumer) logMessage(_ context.Context, msg *CompanyMessage) error {
+	c.logger.Info(fmt.Sprintf("Company %s updated: %s, %s", msg.ID, msg.Name, msg.City))
+	return nil
+}
@@ -0,0 +1,65 @@
+package company_kafka
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"proposal-template/pkg/kafka"
+	"proposal-template/pkg/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSubscriber delivers msgs then blocks until ctx is cancelled, like the Kafka subscriber
+type fakeSubscriber struct {
+	msgs    []kafka.ConsumerMessage
+	commits []bool
+}
+
+func (f *fakeSubscriber) SubscribeToTopic(context.Context) error { return nil }
+
+func (f *fakeSubscriber) ConsumeMessages(ctx context.Context, _ func() kafka.ConsumerMessage) (<-chan kafka.ConsumerMessage, <-chan error, chan<- bool) {
+	chMsg := make(chan kafka.ConsumerMessage)
+	chErr := make(chan error)
+	chCommit := make(chan bool)
+	go func() {
+		defer close(chMsg)
+		defer close(chErr)
+		for _, msg := range f.msgs {
+			chMsg <- msg
+			f.commits = append(f.commits, <-chCommit)
+		}
+		chErr <- errors.New("broker down")
+		<-ctx.Done()
+	}()
+	return chMsg, chErr, chCommit
+}
+
+func TestCompanyConsumer(t *testing.T) {
+	subscriber := &fakeSubscriber{msgs: []kafka.ConsumerMessage{
+		&CompanyMessage{ID: "c1", Name: "Acme"},
+		&CompanyMessage{ID: "c2", Name: "Globex"},
+	}}
+	var handled []string
+	lastHandled := make(chan struct{})
+	consumer := NewCompanyConsumer(subscriber, logger.NewLogger("error"), WithHandler(func(_ context.Context, msg *CompanyMessage) error {
+		handled = append(handled, msg.ID)
+		if msg.ID == "c2" {
+			defer close(lastHandled)
+			return errors.New("invalid company")
+		}
+		return nil
+	}))
+
+	done := make(chan error)
+	go func() { done <- consumer.Start(context.Background()) }()
+
+	<-lastHandled
+	require.NoError(t, consumer.Stop(context.Background()))
+	require.NoError(t, <-done)
+
+	assert.Equal(t, []string{"c1", "c2"}, handled)
+	assert.Equal(t, []bool{true, false}, subscriber.commits)
+}
@@ -0,0 +1,328 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+
+	cockroachdb "proposal-template/pkg/database/cockroachDB"
+	"proposal-template/pkg/query"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DefaultBatchSize is the number of rows per statement of DAOs created without WithBatchSize
+var DefaultBatchSize = 500
+
+// WithBatchSize sets the number of rows CreateBatch, UpsertBatch and UpdateWhere write per
+// statement. Larger chunks mean fewer round trips but bigger statements and savepoints.
+func WithBatchSize(size int) DAOOption {
+	return func(o *daoOptions) {
+		if size > 0 {
+			o.batchSize = size
+		}
+	}
+}
+
+// RowFailure is a row a batch write could not write.
+type RowFailure struct {
+	// Index is the position of the row in the input
+	Index int
+	Err   error
+}
+
+// BatchResult reports the outcome of a batch write row by row.
+type BatchResult[ID comparable] struct {
+	// IDs holds the primary key of each input row, the zero value for failed rows
+	IDs []ID
+	// Written is the number of rows written
+	Written int
+	// Failures lists the rows that could not be written, in input order
+	Failures []RowFailure
+}
+
+// Err returns nil when every row was written, or an error summarising the failures and
+// wrapping the first one.
+func (r BatchResult[ID]) Err() error {
+	if len(r.Failures) == 0 {
+		return nil
+	}
+	first := r.Failures[0]
+	return fmt.Errorf("%d of %d rows failed, first at row %d: %w", len(r.Failures), len(r.IDs), first.Index, first.Err)
+}
+
+// CreateBatch inserts rows in chunks of the DAO's batch size, see WithBatchSize.
+//
+// The chunks are written in the transaction carried by ctx, or in a new one, each under its
+// own savepoint. When a chunk fails, its rows are written one by one to tell the failing
+// rows, e.g. duplicates, apart from the others: they are reported in the result while the
+// rest of the batch is written. The returned error is only set when the whole batch failed,
+// e.g. because ctx was cancelled or the transaction must be retried.
+func (dao *GenericDAO[T, ID]) CreateBatch(ctx context.Context, rows []T) (BatchResult[ID], error) {
+	return dao.writeBatch(ctx, "error inserting data", rows, func(db *gorm.DB, chunk []T) error {
+		return db.Table(dao.tableName).Create(&chunk).Error
+	})
+}
+
+// UpsertBatch inserts rows, or updates the existing rows with the same conflictColumns,
+// which must be the primary key or carry a unique index. Every column but the primary key
+// and the creation timestamp is overwritten, the version of versioned rows is incremented
+// and soft-deleted rows are restored. Rows are written as by CreateBatch.
+//
+// For soft-deletable tables, conflicts on columns other than the primary key are matched
+// against the unique indexes restricted to live rows (WHERE deleted_at IS NULL). For tenant
+// models conflictColumns must include tenant_id, so that a row never overwrites the row of
+// another tenant.
+func (dao *GenericDAO[T, ID]) UpsertBatch(ctx context.Context, rows []T, conflictColumns ...string) (BatchResult[ID], error) {
+	if len(conflictColumns) == 0 {
+		conflictColumns = []string{dao.primaryKey.DBName}
+	}
+	if err := dao.checkColumns(conflictColumns...); err != nil {
+		return BatchResult[ID]{}, err
+	}
+	if dao.tenantField != nil && !slices.Contains(conflictColumns, tenantColumn) {
+		return BatchResult[ID]{}, fmt.Errorf("%w: conflict columns of table %s must include %q", query.ErrInvalidSpec, dao.tableName, tenantColumn)
+	}
+
+	onConflict := clause.OnConflict{
+		DoUpdates: clause.AssignmentColumns(dao.upsertColumns(conflictColumns)),
+	}
+	for _, name := range conflictColumns {
+		onConflict.Columns = append(onConflict.Columns, clause.Column{Name: name})
+	}
+	if dao.versionField != nil {
+		onConflict.DoUpdates = append(onConflict.DoUpdates, clause.Assignment{
+			Column: clause.Column{Name: versionColumn},
+			Value:  gorm.Expr("? + 1", clause.Column{Table: dao.tableName, Name: versionColumn}),
+		})
+	}
+	if dao.softDelete && (len(conflictColumns) != 1 || conflictColumns[0] != dao.primaryKey.DBName) {
+		onConflict.TargetWhere = clause.Where{Exprs: []clause.Expression{
+			clause.Expr{SQL: "? IS NULL", Vars: []interface{}{clause.Column{Name: deletedAtColumn}}},
+		}}
+	}
+
+	return dao.writeBatch(ctx, "error upserting data", rows, func(db *gorm.DB, chunk []T) error {
+		return db.Table(dao.tableName).Clauses(onConflict).Create(&chunk).Error
+	})
+}
+
+// upsertColumns returns the columns overwritten by UpsertBatch.
+func (dao *GenericDAO[T, ID]) upsertColumns(conflictColumns []string) []string {
+	skip := map[string]bool{dao.primaryKey.DBName: true, "created_at": true, versionColumn: true}
+	for _, name := range conflictColumns {
+		skip[name] = true
+	}
+
+	var columns []string
+	for _, name := range dao.dbNames {
+		if !skip[name] {
+			columns = append(columns, name)
+		}
+	}
+	return columns
+}
+
+// UpdateWhere sets fields on every row matching filter and returns the number of rows
+// updated. Rows are updated in chunks of the DAO's batch size, walking the primary key, in
+// the transaction carried by ctx or in a new one. Unlike CreateBatch it stops at the
+// first failure, an empty filter updates the whole table, or the rows of the tenant of ctx.
+// When *T implements BeforeUpdate or AfterUpdate, the rows of each chunk are read and
+// written one by one to call them, see updateEntity.
+func (dao *GenericDAO[T, ID]) UpdateWhere(ctx context.Context, filter query.Filter, fields map[string]interface{}) (int64, error) {
+	if len(fields) == 0 {
+		return 0, nil
+	}
+	if err := dao.checkUpdatable(ctx, fields); err != nil {
+		return 0, err
+	}
+
+	values := make(map[string]interface{}, len(fields)+1)
+	for column, value := range fields {
+		values[column] = value
+	}
+	if dao.versionField != nil {
+		values[versionColumn] = gorm.Expr("? + 1", clause.Column{Name: versionColumn})
+	}
+
+	pk := clause.Column{Name: dao.primaryKey.DBName}
+	var updated int64
+	err := dao.write(ctx, true, func(ctx context.Context, db *gorm.DB) error {
+		updated = 0
+
+		var last *ID
+		for {
+			matching, err := applySpec(dao.scoped(ctx, db).Table(dao.tableName), dao.columns, query.Spec{Filter: filter})
+			if err != nil {
+				return err
+			}
+			if last != nil {
+				matching = matching.Where(clause.Gt{Column: pk, Value: *last})
+			}
+
+			var ids []ID
+			err = matching.
+				Order(clause.OrderByColumn{Column: pk}).
+				Limit(dao.batchSize).
+				Pluck(dao.primaryKey.DBName, &ids).Error
+			if err != nil || len(ids) == 0 {
+				return err
+			}
+
+			n, err := dao.updateChunk(ctx, db, ids, fields, values)
+			if err != nil {
+				return err
+			}
+			updated += n
+
+			if len(ids) < dao.batchSize {
+				return nil
+			}
+			last = &ids[len(ids)-1]
+		}
+	})
+	if err != nil {
+		return 0, wrapError(ctx, "error updating data", err)
+	}
+	return updated, nil
+}
+
+// updateChunk sets values on the rows identified by ids, see UpdateWhere. When *T
+// implements update hooks, the rows are read and fields set on them one by one instead.
+func (dao *GenericDAO[T, ID]) updateChunk(ctx context.Context, db *gorm.DB, ids []ID, fields, values map[string]interface{}) (int64, error) {
+	byIDs := clause.IN{Column: clause.Column{Name: dao.primaryKey.DBName}, Values: toInterfaces(ids)}
+	if !dao.hooks.update {
+		result := db.Table(dao.tableName).Model(new(T)).Where(byIDs).Updates(values)
+		return result.RowsAffected, result.Error
+	}
+
+	var entities []T
+	err := dao.scoped(ctx, db).
+		Table(dao.tableName).
+		Where(byIDs).
+		Order(clause.OrderByColumn{Column: clause.Column{Name: dao.primaryKey.DBName}}).
+		Find(&entities).Error
+	if err != nil {
+		return 0, err
+	}
+	for i := range entities {
+		if err := dao.updateEntity(ctx, db, &entities[i], fields); err != nil {
+			return int64(i), err
+		}
+	}
+	return int64(len(entities)), nil
+}
+
+// writeBatch writes rows with write in chunks, see CreateBatch.
+func (dao *GenericDAO[T, ID]) writeBatch(ctx context.Context, msg string, rows []T, write func(db *gorm.DB, chunk []T) error) (BatchResult[ID], error) {
+	var result BatchResult[ID]
+	if len(rows) == 0 {
+		return result, nil
+	}
+
+	err := dao.write(ctx, true, func(ctx context.Context, db *gorm.DB) error {
+		// Start over from the caller's rows when the transaction is retried
+		result = BatchResult[ID]{IDs: make([]ID, len(rows))}
+		batch := make([]T, len(rows))
+		copy(batch, rows)
+
+		pending := make([]int, 0, len(batch))
+		for i := range batch {
+			if err := dao.prepareCreate(ctx, &batch[i]); err != nil {
+				return err
+			}
+			if hook, ok := any(&batch[i]).(BeforeCreateHook); ok {
+				if err := hook.OnBeforeCreate(ctx); err != nil {
+					result.Failures = append(result.Failures, RowFailure{Index: i, Err: err})
+					continue
+				}
+			}
+			pending = append(pending, i)
+		}
+
+		for start := 0; start < len(pending); start += dao.batchSize {
+			indexes := pending[start:min(start+dao.batchSize, len(pending))]
+			err := dao.writeChunk(ctx, db, batch, indexes, write)
+			if err == nil {
+				for _, i := range indexes {
+					result.IDs[i] = dao.idOf(ctx, &batch[i])
+				}
+				result.Written += len(indexes)
+				continue
+			}
+			if isFatal(ctx, err) {
+				return err
+			}
+
+			// Tell the failing rows apart
+			for _, i := range indexes {
+				if err := dao.writeChunk(ctx, db, batch, []int{i}, write); err != nil {
+					if isFatal(ctx, err) {
+						return err
+					}
+					result.Failures = append(result.Failures, RowFailure{Index: i, Err: wrapError(ctx, msg, err)})
+					continue
+				}
+				result.IDs[i] = dao.idOf(ctx, &batch[i])
+				result.Written++
+			}
+		}
+
+		// Rows failing in BeforeCreate were reported first
+		sort.Slice(result.Failures, func(a, b int) bool {
+			return result.Failures[a].Index < result.Failures[b].Index
+		})
+		return nil
+	})
+	if err != nil {
+		return BatchResult[ID]{}, wrapError(ctx, msg, err)
+	}
+	return result, nil
+}
+
+// writeChunk writes the rows of batch at indexes under a savepoint and calls their
+// AfterCreate hooks. The rows are updated with the values read back, e.g. their ids.
+func (dao *GenericDAO[T, ID]) writeChunk(ctx context.Context, db *gorm.DB, batch []T, indexes []int, write func(db *gorm.DB, chunk []T) error) error {
+	chunk := make([]T, len(indexes))
+	for j, i := range indexes {
+		chunk[j] = batch[i]
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := write(tx, chunk); err != nil {
+			return err
+		}
+		for j := range chunk {
+			if hook, ok := any(&chunk[j]).(AfterCreateHook); ok {
+				if err := hook.OnAfterCreate(ctx); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for j, i := range indexes {
+		batch[i] = chunk[j]
+	}
+	return nil
+}
+
+// isFatal reports whether err fails a whole batch rather than a row: the request is over or
+// the transaction must be retried.
+func isFatal(ctx context.Context, err error) bool {
+	return ctx.Err() != nil || cockroachdb.IsRetryable(err)
+}
+
+func toInterfaces[V any](values []V) []interface{} {
+	result := make([]interface{}, len(values))
+	for i, v := range values {
+		result[i] = v
+	}
+	return result
+}
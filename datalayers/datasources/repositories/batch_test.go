@@ -0,0 +1,108 @@
+package repositories
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	model "proposal-template/models"
+	"proposal-template/pkg/query"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenericDAO_CreateBatchReportsFailingRows(t *testing.T) {
+	db, mock := newMockDB(t)
+	dao := NewGenericDAO[auditLog, int64](db, "audit_logs", WithBatchSize(2))
+	insert := regexp.QuoteMeta(`INSERT INTO "audit_logs" ("message") VALUES `)
+	duplicate := &pgconn.PgError{Code: "23505"}
+
+	mock.ExpectBegin()
+	// First chunk fails, its rows are retried one by one
+	mock.ExpectExec(`SAVEPOINT sp\w+`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(insert+regexp.QuoteMeta(`($1),($2)`)).WithArgs("a", "b").WillReturnError(duplicate)
+	mock.ExpectExec(`ROLLBACK TO SAVEPOINT sp\w+`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`SAVEPOINT sp\w+`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(insert + regexp.QuoteMeta(`($1)`)).WithArgs("a").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectExec(`SAVEPOINT sp\w+`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(insert + regexp.QuoteMeta(`($1)`)).WithArgs("b").WillReturnError(duplicate)
+	mock.ExpectExec(`ROLLBACK TO SAVEPOINT sp\w+`).WillReturnResult(sqlmock.NewResult(0, 0))
+	// Second chunk
+	mock.ExpectExec(`SAVEPOINT sp\w+`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(insert + regexp.QuoteMeta(`($1)`)).WithArgs("c").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+	mock.ExpectCommit()
+
+	result, err := dao.CreateBatch(context.Background(), []auditLog{{Message: "a"}, {Message: "b"}, {Message: "c"}})
+
+	require.NoError(t, err)
+	assert.Equal(t, []int64{1, 0, 3}, result.IDs)
+	assert.Equal(t, 2, result.Written)
+	require.Len(t, result.Failures, 1)
+	assert.Equal(t, 1, result.Failures[0].Index)
+	assert.ErrorIs(t, result.Err(), model.ErrDuplicateKey)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGenericDAO_CreateBatchFailsOnRetryableError(t *testing.T) {
+	db, mock := newMockDB(t)
+	dao := NewGenericDAO[auditLog, int64](db, "audit_logs")
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`SAVEPOINT sp\w+`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "audit_logs"`)).WillReturnError(&pgconn.PgError{Code: "40001"})
+	mock.ExpectExec(`ROLLBACK TO SAVEPOINT sp\w+`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	_, err := dao.CreateBatch(context.Background(), []auditLog{{Message: "a"}})
+
+	assert.Error(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUserRepo_UpsertBatchOnEmail(t *testing.T) {
+	db, mock := newMockDB(t)
+	repo := NewUserRepo(db)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`SAVEPOINT sp\w+`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta(`ON CONFLICT ("email") WHERE "deleted_at" IS NULL DO UPDATE SET "updated_at"="excluded"."updated_at","deleted_at"="excluded"."deleted_at","name"="excluded"."name","email_verified"="excluded"."email_verified","version"="users"."version" + 1 RETURNING "id"`)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(fixedID))
+	mock.ExpectCommit()
+
+	result, err := repo.UpsertBatch(context.Background(), []model.User{{Name: "Jane", Email: "jane@example.com"}}, "email")
+
+	require.NoError(t, err)
+	assert.NoError(t, result.Err())
+	assert.Equal(t, fixedID, result.IDs[0])
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGenericDAO_UpdateWhereWalksPrimaryKey(t *testing.T) {
+	db, mock := newMockDB(t)
+	dao := NewGenericDAO[auditLog, int64](db, "audit_logs", WithBatchSize(2))
+	filter := query.Filter{Conditions: []query.Condition{{Field: "message", Op: query.OpEq, Value: "old"}}}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT "id" FROM "audit_logs" WHERE "message" = $1 ORDER BY "id" LIMIT $2`)).
+		WithArgs("old", 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "audit_logs" SET "message"=$1 WHERE "id" IN ($2,$3)`)).
+		WithArgs("new", int64(1), int64(2)).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT "id" FROM "audit_logs" WHERE "message" = $1 AND "id" > $2 ORDER BY "id" LIMIT $3`)).
+		WithArgs("old", int64(2), 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "audit_logs" SET "message"=$1 WHERE "id" = $2`)).
+		WithArgs("new", int64(5)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	updated, err := dao.UpdateWhere(context.Background(), filter, map[string]interface{}{"message": "new"})
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), updated)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
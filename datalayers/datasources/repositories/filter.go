@@ -0,0 +1,75 @@
+package repositories
+
+import (
+	"fmt"
+	"strings"
+
+	"proposal-template/pkg/query"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// likeEscaper escapes the LIKE wildcards of user input so that OpLike is a plain substring match
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// applySpec adds the WHERE and ORDER BY clauses described by spec to tx. Column names are
+// validated against columns and always quoted, values are always bound as parameters.
+func applySpec(tx *gorm.DB, columns query.Columns, spec query.Spec) (*gorm.DB, error) {
+	if err := columns.Validate(spec); err != nil {
+		return nil, err
+	}
+
+	if !spec.Filter.IsEmpty() {
+		tx = tx.Where(filterExpression(spec.Filter))
+	}
+	for _, s := range spec.Sort {
+		tx = tx.Order(clause.OrderByColumn{Column: clause.Column{Name: s.Field}, Desc: s.Desc})
+	}
+	return tx, nil
+}
+
+func filterExpression(f query.Filter) clause.Expression {
+	exprs := make([]clause.Expression, 0, len(f.Conditions)+len(f.Groups))
+	for _, c := range f.Conditions {
+		exprs = append(exprs, conditionExpression(c))
+	}
+	for _, g := range f.Groups {
+		if !g.IsEmpty() {
+			exprs = append(exprs, filterExpression(g))
+		}
+	}
+
+	if f.Logic == query.Or {
+		return clause.Or(exprs...)
+	}
+	return clause.And(exprs...)
+}
+
+func conditionExpression(c query.Condition) clause.Expression {
+	column := clause.Column{Name: c.Field}
+
+	switch c.Op {
+	case query.OpNe:
+		return clause.Neq{Column: column, Value: c.Value}
+	case query.OpIn:
+		return clause.IN{Column: column, Values: c.Value.([]interface{})}
+	case query.OpGt:
+		return clause.Gt{Column: column, Value: c.Value}
+	case query.OpGte:
+		return clause.Gte{Column: column, Value: c.Value}
+	case query.OpLt:
+		return clause.Lt{Column: column, Value: c.Value}
+	case query.OpLte:
+		return clause.Lte{Column: column, Value: c.Value}
+	case query.OpLike:
+		pattern := "%" + likeEscaper.Replace(fmt.Sprint(c.Value)) + "%"
+		return clause.Expr{SQL: "? ILIKE ?", Vars: []interface{}{column, pattern}}
+	case query.OpIsNull:
+		if c.Value.(bool) {
+			return clause.Expr{SQL: "? IS NULL", Vars: []interface{}{column}}
+		}
+		return clause.Expr{SQL: "? IS NOT NULL", Vars: []interface{}{column}}
+	}
+	return clause.Eq{Column: column, Value: c.Value}
+}
@@ -2,94 +2,961 @@ package repositories
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"reflect"
+	"sync"
 	"time"
 
 	"proposal-template/models"
+	cockroachdb "proposal-template/pkg/database/cockroachDB"
+	"proposal-template/pkg/query"
+	"proposal-template/pkg/tenant"
 
+	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
 )
 
+// GenericDAO implements the common data access methods of a table whose rows map to T
+// and whose primary key has type ID (uuid.UUID for model.BaseModel, int64, string...).
+type GenericDAO[T any, ID comparable] struct {
+	db *gorm.DB
+	tableName string
+	// primaryKey is the schema field of T's primary key
+	primaryKey *schema.Field
+	// versionField is the schema field of T's version column when T embeds model.Versioned
+	versionField *schema.Field
+	// softDelete is set when T embeds model.SoftDeletable
+	softDelete bool
+	// tenantField is the schema field of T's tenant column when T embeds model.TenantModel
+	tenantField *schema.Field
+	// columns is the whitelist of column names accepted in queries, see query.ColumnsOf
+	columns query.Columns
+	// idGenerator, when set, assigns the primary key of new rows instead of the database
+	idGenerator func() ID
+	// clock returns the time written to the creation and update timestamps
+	clock func() time.Time
+	// hooks tells which lifecycle hooks *T implements
+	hooks hooks
+	// defaultSort orders List results when the spec has no sort, the primary key is
+	// always appended as a tiebreak
+	defaultSort query.Sort
+	cursorCodec *query.CursorCodec
+	// writeRunner, when set, runs writes made outside of a transaction through a
+	// transaction retried on serialization failures
+	writeRunner *cockroachdb.TxRunner
+	// batchSize is the number of rows written per statement by the batch methods
+	batchSize int
+	// dbNames are the names of all the columns of T
+	dbNames []string
+	// fields are the schema fields of T by column name
+	fields map[string]*schema.Field
+}
 
+type DAOOption func(*daoOptions)
 
+type daoOptions struct {
+	idGenerator interface{}
+	clock       func() time.Time
+	defaultSort *query.Sort
+	cursorCodec *query.CursorCodec
+	writeRunner *cockroachdb.TxRunner
+	batchSize   int
+}
 
-type GenericDAO[T any] struct {
-	db *gorm.DB
-	tableName string
+const (
+	// versionColumn is the column of model.Versioned
+	versionColumn = "version"
+	// deletedAtColumn is the column of model.SoftDeletable
+	deletedAtColumn = "deleted_at"
+	// tenantColumn is the column of model.TenantModel
+	tenantColumn = "tenant_id"
+)
+
+// defaultClock is the clock of DAOs created without WithClock
+func defaultClock() time.Time {
+	return time.Now().UTC()
 }
 
-func NewGenericDAO[T any](db *gorm.DB, tableName string) *GenericDAO[T] {
-	return &GenericDAO[T]{
+// defaultCursorCodec signs cursors of DAOs created without WithCursorCodec
+var defaultCursorCodec = query.NewRandomCursorCodec()
+
+func NewGenericDAO[T any, ID comparable](db *gorm.DB, tableName string, opts ...DAOOption) *GenericDAO[T, ID] {
+	options := &daoOptions{
+		clock:       defaultClock,
+		cursorCodec: defaultCursorCodec,
+		batchSize:   DefaultBatchSize,
+	}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	modelSchema, err := schema.Parse(new(T), &sync.Map{}, db.NamingStrategy)
+	if err != nil {
+		panic(fmt.Sprintf("GenericDAO: failed to parse model of table %s: %s", tableName, err))
+	}
+	primaryKey := modelSchema.PrioritizedPrimaryField
+	if primaryKey == nil {
+		panic(fmt.Sprintf("GenericDAO: model of table %s has no primary key", tableName))
+	}
+	if primaryKey.FieldType != reflect.TypeOf(*new(ID)) {
+		panic(fmt.Sprintf("GenericDAO: primary key of table %s is %s, not %T", tableName, primaryKey.FieldType, *new(ID)))
+	}
+
+	// Only an int64 "version" column is used for optimistic concurrency control
+	versionField := modelSchema.LookUpField(versionColumn)
+	if versionField != nil && versionField.FieldType.Kind() != reflect.Int64 {
+		versionField = nil
+	}
+
+	deletedAtField := modelSchema.LookUpField(deletedAtColumn)
+	softDelete := deletedAtField != nil && deletedAtField.FieldType == reflect.TypeOf(gorm.DeletedAt{})
+
+	tenantField := modelSchema.LookUpField(tenantColumn)
+	if tenantField != nil && tenantField.FieldType.Kind() != reflect.String {
+		tenantField = nil
+	}
+
+	var idGenerator func() ID
+	if options.idGenerator != nil {
+		generator, ok := options.idGenerator.(func() ID)
+		if !ok {
+			panic(fmt.Sprintf("GenericDAO: ID generator of table %s does not return %T", tableName, *new(ID)))
+		}
+		idGenerator = generator
+	}
+
+	columns := query.ColumnsOf[T]()
+	// Order by creation time when available, random UUID keys alone give an arbitrary order
+	defaultSort := query.Sort{Field: primaryKey.DBName, Desc: true}
+	if col, ok := columns["created_at"]; ok && col.Sortable {
+		defaultSort = query.Sort{Field: "created_at", Desc: true}
+	}
+	if options.defaultSort != nil {
+		defaultSort = *options.defaultSort
+	}
+
+	return &GenericDAO[T, ID]{
 		db: db,
 		tableName: tableName,
+		primaryKey: primaryKey,
+		versionField: versionField,
+		softDelete: softDelete,
+		tenantField: tenantField,
+		columns: columns,
+		idGenerator: idGenerator,
+		clock: options.clock,
+		hooks: hooksOf[T](),
+		defaultSort: defaultSort,
+		cursorCodec: options.cursorCodec,
+		writeRunner: options.writeRunner,
+		batchSize: options.batchSize,
+		dbNames: modelSchema.DBNames,
+		fields: modelSchema.FieldsByDBName,
+	}
+}
+
+// WithIDGenerator makes the DAO generate primary keys on Create, for keys the database
+// cannot generate itself such as UUIDv7. generator must return the DAO's ID type.
+func WithIDGenerator[ID comparable](generator func() ID) DAOOption {
+	return func(o *daoOptions) {
+		o.idGenerator = generator
+	}
+}
+
+// WithClock sets the clock of the creation and update timestamps, time.Now in UTC by
+// default. Tests use a fixed clock to get deterministic rows.
+func WithClock(clock func() time.Time) DAOOption {
+	return func(o *daoOptions) {
+		o.clock = clock
 	}
 }
 
-func (dao *GenericDAO[T]) GetByColumn(ctx context.Context, column string, value interface{}) (*T, error) {
+// WithDefaultSort sets the order of List results when the spec has no sort.
+func WithDefaultSort(sort query.Sort) DAOOption {
+	return func(o *daoOptions) {
+		o.defaultSort = &sort
+	}
+}
+
+// WithCursorCodec sets the codec signing pagination cursors. All replicas must share
+// the same secret for cursors to survive load balancing and restarts.
+func WithCursorCodec(codec *query.CursorCodec) DAOOption {
+	return func(o *daoOptions) {
+		o.cursorCodec = codec
+	}
+}
+
+// WithRetryOnWrite makes Create, Update, PartialUpdate and DeleteByID retry on CockroachDB
+// serialization failures through runner. Writes made in a TxManager transaction are left
+// to the transaction owner, only the outermost transaction can be retried.
+func WithRetryOnWrite(runner *cockroachdb.TxRunner) DAOOption {
+	return func(o *daoOptions) {
+		o.writeRunner = runner
+	}
+}
+
+// NewUUIDv7 returns a time-ordered UUID, to be used with WithIDGenerator.
+func NewUUIDv7() uuid.UUID {
+	return uuid.Must(uuid.NewV7())
+}
+
+// GetByID returns the row whose primary key is id, or model.ErrRecordNotFound.
+func (dao *GenericDAO[T, ID]) GetByID(ctx context.Context, id ID) (*T, error) {
+	return dao.GetByColumn(ctx, dao.primaryKey.DBName, id)
+}
+
+// Columns returns the column whitelist of T.
+func (dao *GenericDAO[T, ID]) Columns() query.Columns {
+	return dao.columns
+}
+
+// GetByColumn returns the first row whose column equals value, or model.ErrRecordNotFound.
+// column must be one of T's `db` tagged columns.
+func (dao *GenericDAO[T, ID]) GetByColumn(ctx context.Context, column string, value interface{}) (*T, error) {
+	if err := dao.checkColumns(column); err != nil {
+		return nil, err
+	}
+
 	var obj T
-	fmt.Println("colum:", column)
-	err := dao.db.WithContext(ctx).
+	err := dao.scoped(ctx, dao.conn(ctx)).
 		Table(dao.tableName).
-		Where(fmt.Sprintf("%s = ?", column), value).
+		Where(clause.Eq{Column: clause.Column{Name: column}, Value: value}).
 		First(&obj).Error
 
 	if err != nil {
-		if err == gorm.ErrRecordNotFound {
-			return nil, nil // No record found
-		}
-		return nil, fmt.Errorf("error retrieving data: %w", err)
+		return nil, wrapError(ctx, "error retrieving data", err)
 	}
 	return &obj, nil
 }
 
+// Exists reports whether at least one row has column equal to value.
+func (dao *GenericDAO[T, ID]) Exists(ctx context.Context, column string, value interface{}) (bool, error) {
+	if err := dao.checkColumns(column); err != nil {
+		return false, err
+	}
+
+	exists, err := dao.exists(dao.scoped(ctx, dao.conn(ctx)), column, value)
+	if err != nil {
+		return false, wrapError(ctx, "error checking existence", err)
+	}
+	return exists, nil
+}
+
+// exists reports whether a row of db has column equal to value.
+func (dao *GenericDAO[T, ID]) exists(db *gorm.DB, column string, value interface{}) (bool, error) {
+	subQuery := db.
+		Table(dao.tableName).
+		Select("1").
+		Where(clause.Eq{Column: clause.Column{Name: column}, Value: value})
+
+	var exists bool
+	err := db.Session(&gorm.Session{NewDB: true}).Raw("SELECT EXISTS (?)", subQuery).Scan(&exists).Error
+	return exists, err
+}
 
-func (dao *GenericDAO[T]) List(ctx context.Context, paging model.Paging, query *gorm.DB) ([]T, error) {
+// List returns a page of the rows matching spec.Filter.
+//
+// Rows are ordered by a single sort column (spec.Sort, or the DAO's default sort) with the
+// primary key as a tiebreak, and each page carries a signed next_cursor token. Passing it
+// back as paging.Cursor reads the following page with a keyset condition instead of OFFSET,
+// which stays fast and stable on large tables. Specs sorting on several columns fall back
+// to OFFSET pagination without cursors.
+func (dao *GenericDAO[T, ID]) List(ctx context.Context, paging model.Paging, spec query.Spec) (model.Page[T], error) {
 	paging.Validate()
+	page := model.Page[T]{Paging: model.PageInfo{Limit: paging.Limit}}
+
+	sorts := spec.Sort
+	if len(sorts) == 0 {
+		sorts = []query.Sort{dao.defaultSort}
+	}
+	keyset := len(sorts) == 1
+
+	tx, err := applySpec(dao.scoped(ctx, dao.conn(ctx)).Table(dao.tableName), dao.columns, query.Spec{Filter: spec.Filter})
+	if err != nil {
+		return page, err
+	}
 
+	if paging.WithTotal {
+		var total int64
+		if err := tx.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+			return page, wrapError(ctx, "error counting data", err)
+		}
+		page.Paging.Total = &total
+	}
+
+	for _, sort := range sorts {
+		if col, ok := dao.columns[sort.Field]; sort.Field != dao.primaryKey.DBName && (!ok || !col.Sortable) {
+			return page, fmt.Errorf("%w: cannot sort on %q", query.ErrInvalidSpec, sort.Field)
+		}
+		tx = tx.Order(clause.OrderByColumn{Column: clause.Column{Name: sort.Field}, Desc: sort.Desc})
+	}
+	if last := sorts[len(sorts)-1]; last.Field != dao.primaryKey.DBName {
+		tx = tx.Order(clause.OrderByColumn{Column: clause.Column{Name: dao.primaryKey.DBName}, Desc: last.Desc})
+	}
+
+	switch {
+	case paging.Cursor != "":
+		if !keyset {
+			return page, fmt.Errorf("%w: cursors require a single sort column", query.ErrInvalidCursor)
+		}
+		after, err := dao.keysetCondition(paging.Cursor, sorts[0])
+		if err != nil {
+			return page, err
+		}
+		tx = tx.Where(after)
+	default:
+		page.Paging.Page = paging.Page
+		tx = tx.Offset((paging.Page - 1) * paging.Limit)
+	}
+
+	// Read one extra row to know whether there is a next page
 	var results []T
-	offset := (paging.Page - 1) * paging.Limit
+	if err := tx.Limit(paging.Limit + 1).Find(&results).Error; err != nil {
+		return page, wrapError(ctx, "error retrieving data", err)
+	}
 
-	err := query.WithContext(ctx).
-		Table(dao.tableName).
-		Limit(paging.Limit).
-		Offset(offset).
-		Order("id DESC").
-		Find(&results).Error
+	if len(results) > paging.Limit {
+		results = results[:paging.Limit]
+		page.Paging.HasMore = true
+		if keyset {
+			cursor, err := dao.cursorAfter(ctx, &results[len(results)-1], sorts[0])
+			if err != nil {
+				return page, err
+			}
+			page.Paging.NextCursor = cursor
+		}
+	}
+	page.Data = results
+	return page, nil
+}
+
+// keysetCondition decodes token and returns the condition selecting the rows after it,
+// i.e. (sort column, primary key) beyond the cursor's values in the sort direction.
+func (dao *GenericDAO[T, ID]) keysetCondition(token string, sort query.Sort) (clause.Expression, error) {
+	cursor, err := dao.cursorCodec.Decode(token)
+	if err != nil {
+		return nil, err
+	}
+	if cursor.Sort != sort {
+		return nil, fmt.Errorf("%w: it was issued for another sort order", query.ErrInvalidCursor)
+	}
+
+	operator := ">"
+	if sort.Desc {
+		operator = "<"
+	}
+	pk := clause.Column{Name: dao.primaryKey.DBName}
+
+	if sort.Field == dao.primaryKey.DBName {
+		if len(cursor.Values) != 1 {
+			return nil, query.ErrInvalidCursor
+		}
+		id, err := query.ParseValue(dao.primaryKey.FieldType, cursor.Values[0])
+		if err != nil {
+			return nil, query.ErrInvalidCursor
+		}
+		return clause.Expr{SQL: "? " + operator + " ?", Vars: []interface{}{pk, id}}, nil
+	}
+
+	if len(cursor.Values) != 2 {
+		return nil, query.ErrInvalidCursor
+	}
+	value, err := query.ParseValue(dao.columns[sort.Field].Type, cursor.Values[0])
+	if err != nil {
+		return nil, query.ErrInvalidCursor
+	}
+	id, err := query.ParseValue(dao.primaryKey.FieldType, cursor.Values[1])
+	if err != nil {
+		return nil, query.ErrInvalidCursor
+	}
+	return clause.Expr{
+		SQL:  "(?, ?) " + operator + " (?, ?)",
+		Vars: []interface{}{clause.Column{Name: sort.Field}, pk, value, id},
+	}, nil
+}
 
+// cursorAfter returns the signed cursor pointing after last.
+func (dao *GenericDAO[T, ID]) cursorAfter(ctx context.Context, last *T, sort query.Sort) (string, error) {
+	var values []interface{}
+	if sort.Field != dao.primaryKey.DBName {
+		value, _ := dao.columns.ValueOf(last, sort.Field)
+		values = append(values, value)
+	}
+	values = append(values, dao.idOf(ctx, last))
+
+	cursor := query.Cursor{Sort: sort}
+	for _, v := range values {
+		formatted, err := query.FormatValue(v)
+		if err != nil {
+			return "", fmt.Errorf("cannot paginate on %q: %w", sort.Field, err)
+		}
+		cursor.Values = append(cursor.Values, formatted)
+	}
+	return dao.cursorCodec.Encode(cursor)
+}
+
+// Create inserts model and returns its primary key, either the one set on model, the one
+// produced by the ID generator or the one generated by the database. The BeforeCreate and
+// AfterCreate hooks of *T are called around the insert.
+func (dao *GenericDAO[T, ID]) Create(ctx context.Context, model T) (ID, error) {
+	var zero ID
+	if err := dao.prepareCreate(ctx, &model); err != nil {
+		return zero, err
+	}
+
+	err := dao.write(ctx, dao.hooks.create, func(ctx context.Context, db *gorm.DB) error {
+		if hook, ok := any(&model).(BeforeCreateHook); ok {
+			if err := hook.OnBeforeCreate(ctx); err != nil {
+				return err
+			}
+		}
+
+		// Insert only non-zero fields (ignore empty fields), a zero primary key is left to
+		// the column default and read back through RETURNING
+		if err := db.Table(dao.tableName).Create(&model).Error; err != nil {
+			return err
+		}
+
+		if hook, ok := any(&model).(AfterCreateHook); ok {
+			return hook.OnAfterCreate(ctx)
+		}
+		return nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("error retrieving data: %w", err)
+		return zero, wrapError(ctx, "error inserting data", err)
 	}
-	return results, nil
+
+	return dao.idOf(ctx, &model), nil
 }
 
-func (dao *GenericDAO[T]) Create(ctx context.Context, model T) (uint, error) {
-	now := time.Now().UTC()
+// prepareCreate sets the timestamps, the initial version, the tenant and the generated
+// primary key of a row about to be inserted.
+func (dao *GenericDAO[T, ID]) prepareCreate(ctx context.Context, obj *T) error {
+	var zero ID
+	now := dao.clock()
+
+	if err := dao.setTenant(ctx, obj); err != nil {
+		return err
+	}
 
 	// Set timestamps if the struct supports it
-	if v, ok := any(model).(interface{ SetCreatedAt(time.Time) }); ok {
+	if v, ok := any(obj).(interface{ SetCreatedAt(time.Time) }); ok {
 		v.SetCreatedAt(now)
 	}
-	if v, ok := any(model).(interface{ SetUpdatedAt(time.Time) }); ok {
+	if v, ok := any(obj).(interface{ SetUpdatedAt(time.Time) }); ok {
 		v.SetUpdatedAt(now)
 	}
 
-	// Insert only non-zero fields (ignore empty fields)
-	err := dao.db.WithContext(ctx).
+	if dao.versionField != nil {
+		if err := dao.versionField.Set(ctx, reflect.ValueOf(obj).Elem(), int64(1)); err != nil {
+			return fmt.Errorf("error setting version: %w", err)
+		}
+	}
+
+	if dao.idGenerator != nil && dao.idOf(ctx, obj) == zero {
+		if err := dao.primaryKey.Set(ctx, reflect.ValueOf(obj).Elem(), dao.idGenerator()); err != nil {
+			return fmt.Errorf("error generating id: %w", err)
+		}
+	}
+	return nil
+}
+
+// Update replaces every column of the row identified by the model's primary key,
+// except the creation and deletion timestamps and the tenant. It returns model.ErrRecordNotFound if no row matched.
+// When T is versioned, the row is only written if its version still equals the model's
+// one, and the version is incremented. model.ErrConflict is returned otherwise.
+// The BeforeUpdate and AfterUpdate hooks of *T are called around the update.
+func (dao *GenericDAO[T, ID]) Update(ctx context.Context, model T) error {
+	if v, ok := any(&model).(interface{ SetUpdatedAt(time.Time) }); ok {
+		v.SetUpdatedAt(dao.clock())
+	}
+
+	var expected int64
+	if dao.versionField != nil {
+		expected = dao.versionOf(ctx, &model)
+		if err := dao.versionField.Set(ctx, reflect.ValueOf(&model).Elem(), expected+1); err != nil {
+			return fmt.Errorf("error setting version: %w", err)
+		}
+	}
+
+	err := dao.write(ctx, dao.hooks.update, func(ctx context.Context, db *gorm.DB) error {
+		if hook, ok := any(&model).(BeforeUpdateHook); ok {
+			if err := hook.OnBeforeUpdate(ctx); err != nil {
+				return err
+			}
+		}
+
+		omit := []string{dao.primaryKey.DBName, "created_at"}
+		if dao.softDelete {
+			omit = append(omit, deletedAtColumn)
+		}
+		if dao.tenantField != nil {
+			omit = append(omit, tenantColumn)
+		}
+		tx := dao.tenantScoped(ctx, db).
+			Table(dao.tableName).
+			Model(&model).
+			Select("*").
+			Omit(omit...)
+		if dao.versionField != nil {
+			tx = tx.Where(clause.Eq{Column: clause.Column{Name: versionColumn}, Value: expected})
+		}
+
+		err := affectedOne(tx.Updates(&model))
+		if errors.Is(err, gorm.ErrRecordNotFound) && dao.versionField != nil {
+			return dao.conflictOrNotFound(ctx, db, dao.idOf(ctx, &model))
+		}
+		if err != nil {
+			return err
+		}
+
+		if hook, ok := any(&model).(AfterUpdateHook); ok {
+			return hook.OnAfterUpdate(ctx)
+		}
+		return nil
+	})
+	if err != nil {
+		return wrapError(ctx, "error updating data", err)
+	}
+	return nil
+}
+
+// PartialUpdate sets only the given columns on the row identified by id and refreshes
+// its update timestamp, and its version when T is versioned. It returns
+// model.ErrRecordNotFound if no row matched.
+// When *T implements BeforeUpdate or AfterUpdate, the row is read first to call them and
+// written back whole, see updateEntity.
+func (dao *GenericDAO[T, ID]) PartialUpdate(ctx context.Context, id ID, fields map[string]interface{}) error {
+	return dao.partialUpdate(ctx, id, nil, fields)
+}
+
+// PartialUpdateIfVersion is PartialUpdate only writing the row while its version equals
+// version, model.ErrConflict is returned otherwise. T must be versioned.
+func (dao *GenericDAO[T, ID]) PartialUpdateIfVersion(ctx context.Context, id ID, version int64, fields map[string]interface{}) error {
+	if dao.versionField == nil {
+		return fmt.Errorf("error updating data: table %s is not versioned", dao.tableName)
+	}
+	return dao.partialUpdate(ctx, id, &version, fields)
+}
+
+// partialUpdate implements PartialUpdate, checking the version of the row when expected is set
+func (dao *GenericDAO[T, ID]) partialUpdate(ctx context.Context, id ID, expected *int64, fields map[string]interface{}) error {
+	if len(fields) == 0 {
+		return nil
+	}
+	if err := dao.checkUpdatable(ctx, fields); err != nil {
+		return err
+	}
+
+	if dao.hooks.update {
+		err := dao.write(ctx, true, func(ctx context.Context, db *gorm.DB) error {
+			var entity T
+			byID := clause.Eq{Column: clause.Column{Name: dao.primaryKey.DBName}, Value: id}
+			if err := dao.tenantScoped(ctx, db).Table(dao.tableName).Where(byID).Take(&entity).Error; err != nil {
+				return err
+			}
+			// updateEntity writes the row only while it is at the version read
+			if expected != nil && dao.versionOf(ctx, &entity) != *expected {
+				return model.ErrConflict
+			}
+			return dao.updateEntity(ctx, db, &entity, fields)
+		})
+		if err != nil {
+			return wrapError(ctx, "error updating data", err)
+		}
+		return nil
+	}
+
+	if dao.versionField != nil {
+		values := make(map[string]interface{}, len(fields)+1)
+		for column, value := range fields {
+			values[column] = value
+		}
+		values[versionColumn] = gorm.Expr("? + 1", clause.Column{Name: versionColumn})
+		fields = values
+	}
+
+	err := dao.write(ctx, false, func(ctx context.Context, db *gorm.DB) error {
+		tx := dao.tenantScoped(ctx, db).
+			Table(dao.tableName).
+			Model(new(T)).
+			Where(clause.Eq{Column: clause.Column{Name: dao.primaryKey.DBName}, Value: id})
+		if expected != nil {
+			tx = tx.Where(clause.Eq{Column: clause.Column{Name: versionColumn}, Value: *expected})
+		}
+
+		err := affectedOne(tx.Updates(fields))
+		if errors.Is(err, gorm.ErrRecordNotFound) && expected != nil {
+			return dao.conflictOrNotFound(ctx, db, id)
+		}
+		return err
+	})
+	if err != nil {
+		return wrapError(ctx, "error updating data", err)
+	}
+	return nil
+}
+
+// updateEntity sets fields on entity, a row just read in db, and writes it back between
+// the BeforeUpdate and AfterUpdate hooks of *T. Like Update, every column but the creation
+// timestamp is written so that the changes of the BeforeUpdate hook are kept; the deletion
+// timestamp and the tenant only when they are among fields.
+func (dao *GenericDAO[T, ID]) updateEntity(ctx context.Context, db *gorm.DB, entity *T, fields map[string]interface{}) error {
+	row := reflect.ValueOf(entity).Elem()
+	for column, value := range fields {
+		if err := dao.fields[column].Set(ctx, row, value); err != nil {
+			return fmt.Errorf("error setting %s: %w", column, err)
+		}
+	}
+	if v, ok := any(entity).(interface{ SetUpdatedAt(time.Time) }); ok {
+		v.SetUpdatedAt(dao.clock())
+	}
+
+	var expected int64
+	if dao.versionField != nil {
+		expected = dao.versionOf(ctx, entity)
+		if err := dao.versionField.Set(ctx, row, expected+1); err != nil {
+			return fmt.Errorf("error setting version: %w", err)
+		}
+	}
+
+	if hook, ok := any(entity).(BeforeUpdateHook); ok {
+		if err := hook.OnBeforeUpdate(ctx); err != nil {
+			return err
+		}
+	}
+
+	omit := []string{dao.primaryKey.DBName, "created_at"}
+	if _, ok := fields[deletedAtColumn]; dao.softDelete && !ok {
+		omit = append(omit, deletedAtColumn)
+	}
+	if _, ok := fields[tenantColumn]; dao.tenantField != nil && !ok {
+		omit = append(omit, tenantColumn)
+	}
+	// The row was read in the scope of the caller, it may be soft-deleted
+	tx := dao.tenantScoped(ctx, db).
+		Unscoped().
 		Table(dao.tableName).
-		Omit("ID"). // Exclude ID if it's auto-generated
-		Create(&model).Error
+		Model(entity).
+		Select("*").
+		Omit(omit...)
+	if dao.versionField != nil {
+		tx = tx.Where(clause.Eq{Column: clause.Column{Name: versionColumn}, Value: expected})
+	}
 
+	err := affectedOne(tx.Updates(entity))
+	if errors.Is(err, gorm.ErrRecordNotFound) && dao.versionField != nil {
+		return model.ErrConflict
+	}
 	if err != nil {
-		return 0, fmt.Errorf("error inserting data: %w", err)
+		return err
 	}
 
-	// Extract ID (assuming `ID` is the primary key)
-	var idField uint
-	if v, ok := any(model).(interface{ GetID() uint }); ok {
-		idField = v.GetID()
+	if hook, ok := any(entity).(AfterUpdateHook); ok {
+		return hook.OnAfterUpdate(ctx)
 	}
+	return nil
+}
+
+// DeleteByID removes the row identified by id. It returns model.ErrRecordNotFound if no row matched.
+// When T is soft-deletable, the row is only marked as deleted, see Restore and Purge.
+// When *T implements BeforeDelete or AfterDelete, the row is read first to call them.
+func (dao *GenericDAO[T, ID]) DeleteByID(ctx context.Context, id ID) error {
+	return dao.deleteByID(ctx, id, nil)
+}
 
-	return idField, nil
+// DeleteByIDIfVersion is DeleteByID only removing the row while its version equals
+// version, model.ErrConflict is returned otherwise. T must be versioned.
+func (dao *GenericDAO[T, ID]) DeleteByIDIfVersion(ctx context.Context, id ID, version int64) error {
+	if dao.versionField == nil {
+		return fmt.Errorf("error deleting data: table %s is not versioned", dao.tableName)
+	}
+	return dao.deleteByID(ctx, id, &version)
 }
 
+// deleteByID implements DeleteByID, checking the version of the row when expected is set
+func (dao *GenericDAO[T, ID]) deleteByID(ctx context.Context, id ID, expected *int64) error {
+	err := dao.write(ctx, dao.hooks.delete, func(ctx context.Context, db *gorm.DB) error {
+		byID := clause.Eq{Column: clause.Column{Name: dao.primaryKey.DBName}, Value: id}
 
+		var entity T
+		if dao.hooks.delete {
+			if err := dao.tenantScoped(ctx, db).Table(dao.tableName).Where(byID).Take(&entity).Error; err != nil {
+				return err
+			}
+		}
+		if hook, ok := any(&entity).(BeforeDeleteHook); ok {
+			if err := hook.OnBeforeDelete(ctx); err != nil {
+				return err
+			}
+		}
+
+		tx := dao.tenantScoped(ctx, db).Table(dao.tableName).Where(byID)
+		if expected != nil {
+			tx = tx.Where(clause.Eq{Column: clause.Column{Name: versionColumn}, Value: *expected})
+		}
+		err := affectedOne(tx.Delete(new(T)))
+		if errors.Is(err, gorm.ErrRecordNotFound) && expected != nil {
+			return dao.conflictOrNotFound(ctx, db, id)
+		}
+		if err != nil {
+			return err
+		}
+
+		if hook, ok := any(&entity).(AfterDeleteHook); ok {
+			return hook.OnAfterDelete(ctx)
+		}
+		return nil
+	})
+	if err != nil {
+		return wrapError(ctx, "error deleting data", err)
+	}
+	return nil
+}
+
+// Restore undeletes the soft-deleted row identified by id. It returns model.ErrRecordNotFound
+// if no soft-deleted row matched. The update hooks of *T are called as by PartialUpdate.
+func (dao *GenericDAO[T, ID]) Restore(ctx context.Context, id ID) error {
+	if !dao.softDelete {
+		return fmt.Errorf("error restoring data: table %s is not soft-deletable", dao.tableName)
+	}
+
+	fields := map[string]interface{}{deletedAtColumn: nil}
+	byID := clause.Eq{Column: clause.Column{Name: dao.primaryKey.DBName}, Value: id}
+
+	err := dao.write(ctx, dao.hooks.update, func(ctx context.Context, db *gorm.DB) error {
+		deleted := dao.scoped(query.OnlyDeleted(ctx), db).Table(dao.tableName).Where(byID)
+		if dao.hooks.update {
+			var entity T
+			if err := deleted.Take(&entity).Error; err != nil {
+				return err
+			}
+			return dao.updateEntity(ctx, db, &entity, fields)
+		}
+
+		if dao.versionField != nil {
+			fields[versionColumn] = gorm.Expr("? + 1", clause.Column{Name: versionColumn})
+		}
+		return affectedOne(deleted.Model(new(T)).Updates(fields))
+	})
+	if err != nil {
+		return wrapError(ctx, "error restoring data", err)
+	}
+	return nil
+}
+
+// purgeBatchSize is the number of rows Purge removes per statement, to keep transactions small
+const purgeBatchSize = 1000
+
+// Purge permanently removes the rows soft-deleted before deletedBefore and returns their
+// number. Rows are removed in batches, each in its own transaction, so a cancelled purge
+// keeps the batches already removed. The rows of tenant models are only purged across
+// tenants with tenant.WithAdminBypass.
+func (dao *GenericDAO[T, ID]) Purge(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	if !dao.softDelete {
+		return 0, nil
+	}
+
+	var purged int64
+	for {
+		batch := dao.tenantScoped(ctx, dao.conn(ctx)).
+			Unscoped().
+			Table(dao.tableName).
+			Select("?", clause.Column{Name: dao.primaryKey.DBName}).
+			Where(clause.Lt{Column: clause.Column{Name: deletedAtColumn}, Value: deletedBefore}).
+			Limit(purgeBatchSize)
+
+		result := dao.tenantScoped(ctx, dao.conn(ctx)).
+			Unscoped().
+			Table(dao.tableName).
+			Where(clause.Expr{SQL: "? IN (?)", Vars: []interface{}{clause.Column{Name: dao.primaryKey.DBName}, batch}}).
+			Delete(new(T))
+		if result.Error != nil {
+			return purged, wrapError(ctx, "error purging data", result.Error)
+		}
+
+		purged += result.RowsAffected
+		if result.RowsAffected < purgeBatchSize {
+			return purged, nil
+		}
+	}
+}
+
+// conn returns the transaction carried by ctx (see TxManager) or the connection pool,
+// bound to ctx.
+func (dao *GenericDAO[T, ID]) conn(ctx context.Context) *gorm.DB {
+	if tx, ok := TxFromContext(ctx); ok {
+		return dao.session(ctx, tx)
+	}
+	return dao.session(ctx, dao.db)
+}
+
+// scoped restricts the reads of db to the rows of the tenant of ctx, see tenantScoped, and
+// of its deleted scope, see query.WithDeleted. The soft delete condition is explicit rather
+// than left to gorm so that raw queries get it too.
+func (dao *GenericDAO[T, ID]) scoped(ctx context.Context, db *gorm.DB) *gorm.DB {
+	db = dao.tenantScoped(ctx, db)
+	if !dao.softDelete {
+		return db
+	}
+
+	deletedAt := clause.Column{Name: deletedAtColumn}
+	switch query.DeletedScopeFrom(ctx) {
+	case query.IncludeDeleted:
+		return db.Unscoped()
+	case query.DeletedOnly:
+		return db.Unscoped().Where(clause.Expr{SQL: "? IS NOT NULL", Vars: []interface{}{deletedAt}})
+	}
+	return db.Unscoped().Where(clause.Expr{SQL: "? IS NULL", Vars: []interface{}{deletedAt}})
+}
+
+// tenantScoped restricts db to the rows of the tenant of ctx when T is a tenant model. Unless
+// ctx is an admin bypass, a missing tenant fails the statement with model.ErrTenantRequired.
+func (dao *GenericDAO[T, ID]) tenantScoped(ctx context.Context, db *gorm.DB) *gorm.DB {
+	if dao.tenantField == nil || tenant.IsAdminBypass(ctx) {
+		return db
+	}
+
+	id, ok := tenant.FromContext(ctx)
+	if !ok {
+		_ = db.AddError(model.ErrTenantRequired)
+		return db
+	}
+	return db.Where(clause.Eq{Column: clause.Column{Name: tenantColumn}, Value: id})
+}
+
+// setTenant sets the tenant of a row about to be inserted to the tenant of ctx. A row
+// naming another tenant is refused with model.ErrCrossTenant, unless ctx is an admin bypass.
+func (dao *GenericDAO[T, ID]) setTenant(ctx context.Context, obj *T) error {
+	if dao.tenantField == nil {
+		return nil
+	}
+
+	value, _ := dao.tenantField.ValueOf(ctx, reflect.ValueOf(obj).Elem())
+	current, _ := value.(string)
+	id, ok := tenant.FromContext(ctx)
+	switch {
+	case current != "" && tenant.IsAdminBypass(ctx):
+		return nil
+	case !ok:
+		return model.ErrTenantRequired
+	case current != "" && current != id:
+		return model.ErrCrossTenant
+	}
+
+	if err := dao.tenantField.Set(ctx, reflect.ValueOf(obj).Elem(), id); err != nil {
+		return fmt.Errorf("error setting tenant: %w", err)
+	}
+	return nil
+}
+
+// session binds db to ctx and makes gorm's automatic timestamps use the DAO's clock.
+func (dao *GenericDAO[T, ID]) session(ctx context.Context, db *gorm.DB) *gorm.DB {
+	return db.Session(&gorm.Session{Context: ctx, NowFunc: dao.clock})
+}
+
+// write runs fn on the connection returned by conn. Outside of a transaction, fn runs in a
+// new one when inTx is set, e.g. to call hooks, or when the DAO was created WithRetryOnWrite,
+// in which case it is retried on serialization failures. The ctx passed to fn carries
+// the transaction fn runs in, if any.
+func (dao *GenericDAO[T, ID]) write(ctx context.Context, inTx bool, fn func(ctx context.Context, db *gorm.DB) error) error {
+	if _, ok := TxFromContext(ctx); ok || (!inTx && dao.writeRunner == nil) {
+		return fn(ctx, dao.conn(ctx))
+	}
+
+	withTx := func(tx *gorm.DB) error {
+		return fn(ContextWithTx(ctx, tx), dao.session(ctx, tx))
+	}
+	if dao.writeRunner != nil {
+		return dao.writeRunner.RunInTx(ctx, withTx)
+	}
+	return dao.conn(ctx).Transaction(withTx)
+}
+
+// conflictOrNotFound tells why a versioned write of the row identified by id matched no row.
+func (dao *GenericDAO[T, ID]) conflictOrNotFound(ctx context.Context, db *gorm.DB, id ID) error {
+	exists, err := dao.exists(dao.scoped(ctx, db), dao.primaryKey.DBName, id)
+	switch {
+	case err != nil:
+		return err
+	case exists:
+		return model.ErrConflict
+	}
+	return gorm.ErrRecordNotFound
+}
+
+// affectedOne returns the error of result, or gorm.ErrRecordNotFound if no row was affected.
+func affectedOne(result *gorm.DB) error {
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// checkColumns returns an error wrapping query.ErrInvalidSpec if a name is neither the
+// primary key nor a whitelisted column of T.
+func (dao *GenericDAO[T, ID]) checkColumns(names ...string) error {
+	for _, name := range names {
+		if name != dao.primaryKey.DBName && !dao.columns.Has(name) {
+			return fmt.Errorf("%w: unknown column %q in table %s", query.ErrInvalidSpec, name, dao.tableName)
+		}
+	}
+	return nil
+}
+
+// checkUpdatable checks the columns of fields with checkColumns. The tenant of a row is
+// only changed with an admin bypass.
+func (dao *GenericDAO[T, ID]) checkUpdatable(ctx context.Context, fields map[string]interface{}) error {
+	for column := range fields {
+		if err := dao.checkColumns(column); err != nil {
+			return err
+		}
+		if dao.tenantField != nil && column == tenantColumn && !tenant.IsAdminBypass(ctx) {
+			return fmt.Errorf("%w: column %q cannot be updated", query.ErrInvalidSpec, column)
+		}
+	}
+	return nil
+}
+
+// idOf returns the primary key of obj.
+func (dao *GenericDAO[T, ID]) idOf(ctx context.Context, obj *T) ID {
+	value, _ := dao.primaryKey.ValueOf(ctx, reflect.ValueOf(obj).Elem())
+	id, _ := value.(ID)
+	return id
+}
+
+// versionOf returns the version of obj, T must be versioned.
+func (dao *GenericDAO[T, ID]) versionOf(ctx context.Context, obj *T) int64 {
+	value, _ := dao.versionField.ValueOf(ctx, reflect.ValueOf(obj).Elem())
+	version, _ := value.(int64)
+	return version
+}
+
+// wrapError annotates err with msg. gorm's not-found and duplicate key errors are
+// translated to model.ErrRecordNotFound and model.ErrDuplicateKey. When the query was
+// aborted because ctx was cancelled or its deadline expired, the context error is kept in
+// the chain so callers can tell it apart from a database failure with errors.Is.
+func wrapError(ctx context.Context, msg string, err error) error {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return model.ErrRecordNotFound
+	case errors.Is(err, model.ErrConflict):
+		return model.ErrConflict
+	case errors.Is(err, model.ErrTenantRequired):
+		return model.ErrTenantRequired
+	case errors.Is(err, model.ErrCrossTenant):
+		return model.ErrCrossTenant
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return fmt.Errorf("%s: %w: %w", msg, model.ErrDuplicateKey, err)
+	}
+	if ctxErr := ctx.Err(); ctxErr != nil && !errors.Is(err, ctxErr) {
+		return fmt.Errorf("%s: %w: %w", msg, ctxErr, err)
+	}
+	return fmt.Errorf("%s: %w", msg, err)
+}
@@ -0,0 +1,186 @@
+package repositories
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	model "proposal-template/models"
+	"proposal-template/pkg/query"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type auditLog struct {
+	ID      int64  `gorm:"primaryKey"`
+	Message string `db:"message" query:"filter"`
+}
+
+func TestGenericDAO_CreateReturnsDatabaseGeneratedUUID(t *testing.T) {
+	db, mock := newMockDB(t)
+	dao := NewGenericDAO[model.User, uuid.UUID](db, usersTableName)
+	id := uuid.New()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "users"`)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(id))
+	mock.ExpectCommit()
+
+	got, err := dao.Create(context.Background(), model.User{Name: "Jane", Email: "jane@example.com"})
+
+	require.NoError(t, err)
+	assert.Equal(t, id, got)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGenericDAO_CreateWithUUIDv7Generator(t *testing.T) {
+	db, mock := newMockDB(t)
+	dao := NewGenericDAO[model.User, uuid.UUID](db, usersTableName, WithIDGenerator(NewUUIDv7))
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "users" ("created_at","updated_at","version","deleted_at","name","email","email_verified","id")`)).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), int64(1), nil, "Jane", "jane@example.com", false, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectCommit()
+
+	got, err := dao.Create(context.Background(), model.User{Name: "Jane", Email: "jane@example.com"})
+
+	require.NoError(t, err)
+	assert.Equal(t, uuid.Version(7), got.Version())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGenericDAO_IntegerPrimaryKey(t *testing.T) {
+	db, mock := newMockDB(t)
+	dao := NewGenericDAO[auditLog, int64](db, "audit_logs")
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "audit_logs" ("message") VALUES ($1) RETURNING "id"`)).
+		WithArgs("created").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(42))
+	mock.ExpectCommit()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "audit_logs" WHERE "id" = $1`)).
+		WithArgs(int64(42), 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "message"}).AddRow(42, "created"))
+
+	id, err := dao.Create(context.Background(), auditLog{Message: "created"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(42), id)
+
+	row, err := dao.GetByID(context.Background(), id)
+	require.NoError(t, err)
+	assert.Equal(t, "created", row.Message)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestNewGenericDAO_PanicsOnMismatchedIDType(t *testing.T) {
+	db, _ := newMockDB(t)
+
+	assert.Panics(t, func() {
+		NewGenericDAO[model.User, int64](db, usersTableName)
+	})
+}
+
+func TestGenericDAO_ListAppliesSpec(t *testing.T) {
+	db, mock := newMockDB(t)
+	dao := NewGenericDAO[model.User, uuid.UUID](db, usersTableName)
+	spec := query.Spec{
+		Filter: query.Filter{
+			Logic: query.And,
+			Conditions: []query.Condition{
+				{Field: "email", Op: query.OpLike, Value: "50%_off"},
+				{Field: "email_verified", Op: query.OpEq, Value: true},
+			},
+			Groups: []query.Filter{{
+				Logic: query.Or,
+				Conditions: []query.Condition{
+					{Field: "name", Op: query.OpEq, Value: "Jane"},
+					{Field: "name", Op: query.OpIsNull, Value: true},
+				},
+			}},
+		},
+		Sort: []query.Sort{{Field: "created_at", Desc: true}},
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "users" WHERE "deleted_at" IS NULL AND ("email" ILIKE $1 AND "email_verified" = $2 AND ("name" = $3 OR "name" IS NULL)) ORDER BY "created_at" DESC,"id" DESC LIMIT $4`)).
+		WithArgs(`%50\%\_off%`, true, "Jane", 11).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	_, err := dao.List(context.Background(), model.Paging{}, spec)
+
+	require.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGenericDAO_RejectsUnknownColumns(t *testing.T) {
+	db, _ := newMockDB(t)
+	dao := NewGenericDAO[model.User, uuid.UUID](db, usersTableName)
+	ctx := context.Background()
+
+	_, err := dao.GetByColumn(ctx, "email = email OR 1=1 --", "x")
+	assert.ErrorIs(t, err, query.ErrInvalidSpec)
+
+	_, err = dao.List(ctx, model.Paging{}, query.Where(query.Condition{Field: "password", Op: query.OpEq, Value: "x"}))
+	assert.ErrorIs(t, err, query.ErrInvalidSpec)
+
+	err = dao.PartialUpdate(ctx, uuid.New(), map[string]interface{}{"is_admin": true})
+	assert.ErrorIs(t, err, query.ErrInvalidSpec)
+}
+
+func TestGenericDAO_ListKeysetPagination(t *testing.T) {
+	db, mock := newMockDB(t)
+	dao := NewGenericDAO[model.User, uuid.UUID](db, usersTableName, WithCursorCodec(query.NewCursorCodec([]byte("secret"))))
+	ctx := context.Background()
+	first, second := uuid.New(), uuid.New()
+	createdAt := time.Date(2025, 3, 1, 10, 0, 0, 123456000, time.UTC)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "users"`)).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(5))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "users" WHERE "deleted_at" IS NULL ORDER BY "created_at" DESC,"id" DESC LIMIT $1`)).
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "email"}).
+			AddRow(first, createdAt, "a@example.com").
+			AddRow(second, createdAt, "b@example.com"))
+
+	page, err := dao.List(ctx, model.Paging{Limit: 1, WithTotal: true}, query.Spec{})
+
+	require.NoError(t, err)
+	require.Len(t, page.Data, 1)
+	assert.True(t, page.Paging.HasMore)
+	assert.Equal(t, int64(5), *page.Paging.Total)
+	require.NotEmpty(t, page.Paging.NextCursor)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "users" WHERE "deleted_at" IS NULL AND ("created_at", "id") < ($1, $2) ORDER BY "created_at" DESC,"id" DESC LIMIT $3`)).
+		WithArgs(createdAt, first, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "email"}).AddRow(second, createdAt, "b@example.com"))
+
+	page, err = dao.List(ctx, model.Paging{Limit: 1, Cursor: page.Paging.NextCursor}, query.Spec{})
+
+	require.NoError(t, err)
+	require.Len(t, page.Data, 1)
+	assert.False(t, page.Paging.HasMore)
+	assert.Empty(t, page.Paging.NextCursor)
+	assert.Zero(t, page.Paging.Page)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGenericDAO_ListRejectsInvalidCursors(t *testing.T) {
+	db, _ := newMockDB(t)
+	codec := query.NewCursorCodec([]byte("secret"))
+	dao := NewGenericDAO[model.User, uuid.UUID](db, usersTableName, WithCursorCodec(codec))
+	ctx := context.Background()
+
+	forged, err := query.NewCursorCodec([]byte("other")).Encode(query.Cursor{Sort: query.Sort{Field: "created_at", Desc: true}})
+	require.NoError(t, err)
+	otherSort, err := codec.Encode(query.Cursor{Sort: query.Sort{Field: "email"}, Values: []string{"a", uuid.NewString()}})
+	require.NoError(t, err)
+
+	for _, token := range []string{"garbage", forged, otherSort} {
+		_, err := dao.List(ctx, model.Paging{Cursor: token}, query.Spec{})
+		assert.ErrorIs(t, err, query.ErrInvalidCursor)
+	}
+}
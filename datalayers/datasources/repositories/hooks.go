@@ -0,0 +1,61 @@
+package repositories
+
+import "context"
+
+// Lifecycle hooks GenericDAO[T, ID] calls when *T implements them. Hooks run in the same
+// transaction as the write: their ctx carries it, so repositories called from a hook take
+// part in it, and an error returned by a hook aborts the write and rolls it back.
+// Before hooks may modify the entity, their changes are written.
+//
+// These are unrelated to gorm's own hooks, which take a *gorm.DB. The methods are prefixed
+// with On as gorm warns about models with a BeforeCreate... method of another signature.
+
+type BeforeCreateHook interface {
+	OnBeforeCreate(ctx context.Context) error
+}
+
+type AfterCreateHook interface {
+	OnAfterCreate(ctx context.Context) error
+}
+
+// BeforeUpdateHook and AfterUpdateHook are called by Update, and by PartialUpdate,
+// UpdateWhere and Restore on the rows being updated, which are read first when T implements
+// one of them.
+type BeforeUpdateHook interface {
+	OnBeforeUpdate(ctx context.Context) error
+}
+
+type AfterUpdateHook interface {
+	OnAfterUpdate(ctx context.Context) error
+}
+
+// BeforeDeleteHook and AfterDeleteHook are called by DeleteByID on the row being deleted,
+// which is read first when T implements one of them.
+type BeforeDeleteHook interface {
+	OnBeforeDelete(ctx context.Context) error
+}
+
+type AfterDeleteHook interface {
+	OnAfterDelete(ctx context.Context) error
+}
+
+// hooks records which kinds of hooks *T implements, writes calling hooks run in a transaction.
+type hooks struct {
+	create, update, delete bool
+}
+
+func hooksOf[T any]() hooks {
+	entity := any(new(T))
+	_, beforeCreate := entity.(BeforeCreateHook)
+	_, afterCreate := entity.(AfterCreateHook)
+	_, beforeUpdate := entity.(BeforeUpdateHook)
+	_, afterUpdate := entity.(AfterUpdateHook)
+	_, beforeDelete := entity.(BeforeDeleteHook)
+	_, afterDelete := entity.(AfterDeleteHook)
+
+	return hooks{
+		create: beforeCreate || afterCreate,
+		update: beforeUpdate || afterUpdate,
+		delete: beforeDelete || afterDelete,
+	}
+}
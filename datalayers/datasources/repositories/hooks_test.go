@@ -0,0 +1,193 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	model "proposal-template/models"
+	"proposal-template/pkg/query"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// hookedNote records the hooks called on it
+type hookedNote struct {
+	model.BaseModel
+	Title string `db:"title"`
+
+	calls []string `gorm:"-"`
+	fail  string   `gorm:"-"`
+}
+
+func (n *hookedNote) call(ctx context.Context, name string) error {
+	if _, ok := TxFromContext(ctx); !ok {
+		return errors.New(name + " called outside of a transaction")
+	}
+	n.calls = append(n.calls, name)
+	if n.fail == name {
+		return errors.New(name + " failed")
+	}
+	return nil
+}
+
+func (n *hookedNote) OnBeforeCreate(ctx context.Context) error {
+	n.Title = "before: " + n.Title
+	return n.call(ctx, "BeforeCreate")
+}
+func (n *hookedNote) OnAfterCreate(ctx context.Context) error  { return n.call(ctx, "AfterCreate") }
+func (n *hookedNote) OnBeforeDelete(ctx context.Context) error { return n.call(ctx, "BeforeDelete") }
+
+var (
+	fixedNow = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	fixedID  = uuid.MustParse("0190a7c4-0000-7000-8000-000000000001")
+)
+
+func newNoteDAO(t *testing.T) (*GenericDAO[hookedNote, uuid.UUID], sqlmock.Sqlmock) {
+	db, mock := newMockDB(t)
+	dao := NewGenericDAO[hookedNote, uuid.UUID](db, "notes",
+		WithClock(func() time.Time { return fixedNow }),
+		WithIDGenerator(func() uuid.UUID { return fixedID }),
+	)
+	return dao, mock
+}
+
+func TestGenericDAO_CreateIsDeterministicAndCallsHooks(t *testing.T) {
+	dao, mock := newNoteDAO(t)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "notes" ("created_at","updated_at","title","id") VALUES ($1,$2,$3,$4)`)).
+		WithArgs(fixedNow, fixedNow, "before: hello", fixedID).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(fixedID))
+	mock.ExpectCommit()
+
+	id, err := dao.Create(context.Background(), hookedNote{Title: "hello"})
+
+	require.NoError(t, err)
+	assert.Equal(t, fixedID, id)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGenericDAO_FailingHookRollsBack(t *testing.T) {
+	dao, mock := newNoteDAO(t)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "notes"`)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(fixedID))
+	mock.ExpectRollback()
+
+	_, err := dao.Create(context.Background(), hookedNote{Title: "hello", fail: "AfterCreate"})
+
+	assert.ErrorContains(t, err, "AfterCreate failed")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGenericDAO_DeleteReadsRowForHooks(t *testing.T) {
+	dao, mock := newNoteDAO(t)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "notes" WHERE "id" = $1 LIMIT $2`)).
+		WithArgs(fixedID, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title"}).AddRow(fixedID, "hello"))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "notes" WHERE "id" = $1`)).
+		WithArgs(fixedID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	require.NoError(t, dao.DeleteByID(context.Background(), fixedID))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGenericDAO_PartialUpdateUsesClock(t *testing.T) {
+	dao, mock := newNoteDAO(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "notes" SET "title"=$1,"updated_at"=$2 WHERE "id" = $3`)).
+		WithArgs("hi", fixedNow, fixedID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	require.NoError(t, dao.PartialUpdate(context.Background(), fixedID, map[string]interface{}{"title": "hi"}))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// checkedNote trims its title and requires one before every update
+type checkedNote struct {
+	model.BaseModel
+	Title string `db:"title" query:"filter"`
+}
+
+func (n *checkedNote) OnBeforeUpdate(ctx context.Context) error {
+	if _, ok := TxFromContext(ctx); !ok {
+		return errors.New("BeforeUpdate called outside of a transaction")
+	}
+	n.Title = strings.TrimSpace(n.Title)
+	if n.Title == "" {
+		return errors.New("title is required")
+	}
+	return nil
+}
+
+func newCheckedNoteDAO(t *testing.T) (*GenericDAO[checkedNote, uuid.UUID], sqlmock.Sqlmock) {
+	db, mock := newMockDB(t)
+	return NewGenericDAO[checkedNote, uuid.UUID](db, "notes", WithClock(func() time.Time { return fixedNow })), mock
+}
+
+func TestGenericDAO_PartialUpdateCallsUpdateHooks(t *testing.T) {
+	dao, mock := newCheckedNoteDAO(t)
+	created := fixedNow.Add(-time.Hour)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "notes" WHERE "id" = $1 LIMIT $2`)).
+		WithArgs(fixedID, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "updated_at", "title"}).AddRow(fixedID, created, created, "hello"))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "notes" SET "updated_at"=$1,"title"=$2 WHERE "id" = $3`)).
+		WithArgs(fixedNow, "hi", fixedID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	require.NoError(t, dao.PartialUpdate(context.Background(), fixedID, map[string]interface{}{"title": "  hi  "}))
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	// A failing hook rolls the update back
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "notes"`)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title"}).AddRow(fixedID, "hi"))
+	mock.ExpectRollback()
+
+	err := dao.PartialUpdate(context.Background(), fixedID, map[string]interface{}{"title": " "})
+	assert.ErrorContains(t, err, "title is required")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGenericDAO_UpdateWhereCallsUpdateHooks(t *testing.T) {
+	dao, mock := newCheckedNoteDAO(t)
+	otherID := uuid.MustParse("0190a7c4-0000-7000-8000-000000000002")
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT "id" FROM "notes" WHERE "title" = $1 ORDER BY "id" LIMIT $2`)).
+		WithArgs("old", DefaultBatchSize).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(fixedID).AddRow(otherID))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "notes" WHERE "id" IN ($1,$2) ORDER BY "id"`)).
+		WithArgs(fixedID, otherID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title"}).AddRow(fixedID, "old").AddRow(otherID, "old"))
+	for _, id := range []uuid.UUID{fixedID, otherID} {
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "notes" SET "updated_at"=$1,"title"=$2 WHERE "id" = $3`)).
+			WithArgs(fixedNow, "new", id).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+	mock.ExpectCommit()
+
+	filter := query.Filter{Conditions: []query.Condition{{Field: "title", Op: query.OpEq, Value: "old"}}}
+	updated, err := dao.UpdateWhere(context.Background(), filter, map[string]interface{}{"title": "new "})
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), updated)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
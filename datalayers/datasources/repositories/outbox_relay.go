@@ -0,0 +1,339 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"proposal-template/models"
+	cockroachdb "proposal-template/pkg/database/cockroachDB"
+	"proposal-template/pkg/kafka"
+	"proposal-template/pkg/lifecycle"
+	"proposal-template/pkg/logger"
+)
+
+// OutboxStore is the storage the OutboxRelay reads the outbox from, OutboxRepo implements it.
+type OutboxStore interface {
+	Pending(ctx context.Context, now time.Time, limit int) ([]model.OutboxMessage, error)
+	MarkPublished(ctx context.Context, id int64, at time.Time) error
+	MarkFailed(ctx context.Context, msg model.OutboxMessage) error
+	DeletePublished(ctx context.Context, before time.Time) (int64, error)
+}
+
+// OutboxLease keeps the relays of several replicas from reading the outbox at the same
+// time, cockroachdb.TableLocker implements it.
+type OutboxLease interface {
+	// TryRun runs fn unless another relay holds the lease, and reports whether it did.
+	// The context of fn is cancelled when the lease is lost.
+	TryRun(ctx context.Context, fn func(ctx context.Context) error) (bool, error)
+}
+
+var _ OutboxLease = (*cockroachdb.TableLocker)(nil)
+
+var (
+	DefaultOutboxPollInterval   = time.Second
+	DefaultOutboxBatchSize      = 100
+	DefaultOutboxMaxAttempts    = 10
+	DefaultOutboxInitialBackoff = time.Second
+	DefaultOutboxMaxBackoff     = 5 * time.Minute
+	DefaultOutboxRetention      = 24 * time.Hour
+)
+
+// outboxCleanupInterval is the time between two deletions of published messages
+const outboxCleanupInterval = time.Hour
+
+type outboxRoute struct {
+	publisher kafka.Publisher
+	newValue  func() interface{}
+}
+
+// OutboxRelay publishes the messages of the outbox, giving at-least-once delivery: a message
+// is marked as published after the publisher acknowledged it, so a crash in between
+// publishes it again. Consumers must therefore be idempotent.
+//
+// Messages of the same aggregate are published in the order they were added: while one is
+// waiting for a retry, the following ones are held back. They are sent with the aggregate
+// id as key to publishers implementing kafka.KeyedPublisher. A message still failing after
+// the maximum number of attempts is given up on, which releases its aggregate.
+//
+// Several relays reading the same outbox would publish messages twice and out of order:
+// when several replicas run one, each batch is relayed holding the lease set with
+// WithOutboxLease.
+type OutboxRelay struct {
+	store  OutboxStore
+	lease  OutboxLease
+	routes map[string]outboxRoute
+	logger logger.ILogger
+
+	interval       time.Duration
+	batchSize      int
+	maxAttempts    int
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+	retention      time.Duration
+	clock          func() time.Time
+
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+var _ lifecycle.Runnable = (*OutboxRelay)(nil)
+
+type OutboxRelayOption func(*OutboxRelay)
+
+func NewOutboxRelay(store OutboxStore, logger logger.ILogger, opts ...OutboxRelayOption) *OutboxRelay {
+	relay := &OutboxRelay{
+		store:          store,
+		routes:         make(map[string]outboxRoute),
+		logger:         logger,
+		interval:       DefaultOutboxPollInterval,
+		batchSize:      DefaultOutboxBatchSize,
+		maxAttempts:    DefaultOutboxMaxAttempts,
+		initialBackoff: DefaultOutboxInitialBackoff,
+		maxBackoff:     DefaultOutboxMaxBackoff,
+		retention:      DefaultOutboxRetention,
+		clock:          defaultClock,
+		stop:           make(chan struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(relay)
+	}
+	return relay
+}
+
+// Route makes the relay publish the messages of eventType with publisher. newValue returns
+// a pointer to the type the JSON payload is decoded into, e.g. the Avro record of the event.
+// When newValue is nil the payload is published as json.RawMessage. Messages of an event
+// type without route fail and are retried until it gets one or the relay gives up.
+func (r *OutboxRelay) Route(eventType string, publisher kafka.Publisher, newValue func() interface{}) {
+	r.routes[eventType] = outboxRoute{publisher: publisher, newValue: newValue}
+}
+
+// Start relays the outbox every poll interval, and right away while messages are queued,
+// until ctx is cancelled or Stop is called. Published messages past the retention period
+// are deleted along the way.
+func (r *OutboxRelay) Start(ctx context.Context) error {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	var nextCleanup time.Time
+	for {
+		more, err := r.relay(ctx)
+		if err != nil && ctx.Err() == nil {
+			r.logger.Error(fmt.Sprintf("Outbox relay failed: %s", err))
+		}
+
+		if now := r.clock(); r.retention > 0 && !now.Before(nextCleanup) {
+			if _, err := r.Cleanup(ctx); err != nil && ctx.Err() == nil {
+				r.logger.Error(fmt.Sprintf("Outbox cleanup failed: %s", err))
+			}
+			nextCleanup = now.Add(outboxCleanupInterval)
+		}
+
+		if more {
+			ticker.Reset(r.interval)
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-r.stop:
+				return nil
+			default:
+				continue
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-r.stop:
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *OutboxRelay) Stop(_ context.Context) error {
+	r.stopOnce.Do(func() { close(r.stop) })
+	return nil
+}
+
+// RunOnce publishes one batch of pending messages.
+func (r *OutboxRelay) RunOnce(ctx context.Context) error {
+	_, err := r.relay(ctx)
+	return err
+}
+
+// relay publishes one batch of pending messages and reports whether more are likely
+// waiting, i.e. the batch was full and made progress. Nothing is published while the
+// lease is held by another relay.
+func (r *OutboxRelay) relay(ctx context.Context) (more bool, err error) {
+	if r.lease == nil {
+		return r.relayBatch(ctx)
+	}
+	_, err = r.lease.TryRun(ctx, func(ctx context.Context) error {
+		more, err = r.relayBatch(ctx)
+		return err
+	})
+	return more, err
+}
+
+func (r *OutboxRelay) relayBatch(ctx context.Context) (bool, error) {
+	now := r.clock()
+	msgs, err := r.store.Pending(ctx, now, r.batchSize)
+	if err != nil {
+		return false, err
+	}
+
+	// Aggregates with a message failing in this batch, their following messages wait for
+	// its retry. Pending already leaves out the aggregates waiting for one.
+	blocked := make(map[string]bool)
+	handled := 0
+	for _, msg := range msgs {
+		key := msg.AggregateKey()
+		if blocked[key] {
+			continue
+		}
+
+		if err := r.publish(ctx, msg); err != nil {
+			if ctx.Err() != nil {
+				return false, ctx.Err()
+			}
+			blocked[key] = true
+			if err := r.fail(ctx, msg, err, now); err != nil {
+				return false, err
+			}
+			// Waiting for a retry, it no longer holds its aggregate in the next batch
+			handled++
+			continue
+		}
+
+		// Failing here publishes the message again on the next batch
+		if err := r.store.MarkPublished(ctx, msg.ID, now); err != nil {
+			return false, err
+		}
+		handled++
+	}
+
+	return len(msgs) == r.batchSize && handled > 0, nil
+}
+
+// publish sends msg with the publisher of its event type.
+func (r *OutboxRelay) publish(ctx context.Context, msg model.OutboxMessage) error {
+	route, ok := r.routes[msg.EventType]
+	if !ok {
+		return fmt.Errorf("no publisher for event type %q", msg.EventType)
+	}
+
+	var value interface{} = msg.Payload
+	if route.newValue != nil {
+		v := route.newValue()
+		if err := json.Unmarshal(msg.Payload, v); err != nil {
+			return fmt.Errorf("failed to decode %s payload: %w", msg.EventType, err)
+		}
+		value = reflect.Indirect(reflect.ValueOf(v)).Interface()
+	}
+
+	if keyed, ok := route.publisher.(kafka.KeyedPublisher); ok {
+		return keyed.SendKeyedMessage(ctx, msg.AggregateID, value)
+	}
+	return route.publisher.SendMessage(ctx, value)
+}
+
+// fail records the failed publication of msg and schedules its retry, or gives up on it
+// after the maximum number of attempts.
+func (r *OutboxRelay) fail(ctx context.Context, msg model.OutboxMessage, cause error, now time.Time) error {
+	msg.Attempts++
+	lastError := cause.Error()
+	msg.LastError = &lastError
+
+	if msg.Attempts >= r.maxAttempts {
+		msg.FailedAt = &now
+		r.logger.Error(fmt.Sprintf("Giving up on outbox message %d (%s of %s) after %d attempts: %s",
+			msg.ID, msg.EventType, msg.AggregateKey(), msg.Attempts, cause))
+	} else {
+		msg.NextAttemptAt = now.Add(r.backoff(msg.Attempts))
+		r.logger.Warn(fmt.Sprintf("Failed to publish outbox message %d (%s of %s), attempt %d: %s",
+			msg.ID, msg.EventType, msg.AggregateKey(), msg.Attempts, cause))
+	}
+	return r.store.MarkFailed(ctx, msg)
+}
+
+// backoff returns the delay before the attempt following the given number of failed ones,
+// doubling from the initial backoff up to the maximum.
+func (r *OutboxRelay) backoff(attempts int) time.Duration {
+	delay := r.initialBackoff
+	for i := 1; i < attempts && delay < r.maxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, r.maxBackoff)
+}
+
+// Cleanup deletes the messages published for longer than the retention period.
+func (r *OutboxRelay) Cleanup(ctx context.Context) (int64, error) {
+	deleted, err := r.store.DeletePublished(ctx, r.clock().Add(-r.retention))
+	if deleted > 0 {
+		r.logger.Info(fmt.Sprintf("Deleted %d published outbox messages", deleted))
+	}
+	return deleted, err
+}
+
+// === optional configuration ===
+
+// WithOutboxPollInterval sets the time between two reads of an empty outbox
+func WithOutboxPollInterval(interval time.Duration) OutboxRelayOption {
+	return func(r *OutboxRelay) {
+		if interval > 0 {
+			r.interval = interval
+		}
+	}
+}
+
+// WithOutboxBatchSize sets the number of messages read at once
+func WithOutboxBatchSize(size int) OutboxRelayOption {
+	return func(r *OutboxRelay) {
+		if size > 0 {
+			r.batchSize = size
+		}
+	}
+}
+
+// WithOutboxRetry sets the number of attempts before a message is given up on, and the
+// bounds of the exponential delay between them
+func WithOutboxRetry(maxAttempts int, initialBackoff time.Duration, maxBackoff time.Duration) OutboxRelayOption {
+	return func(r *OutboxRelay) {
+		if maxAttempts > 0 {
+			r.maxAttempts = maxAttempts
+		}
+		if initialBackoff > 0 {
+			r.initialBackoff = initialBackoff
+		}
+		if maxBackoff >= r.initialBackoff {
+			r.maxBackoff = maxBackoff
+		}
+	}
+}
+
+// WithOutboxRetention sets how long published messages are kept, 0 keeps them forever
+func WithOutboxRetention(retention time.Duration) OutboxRelayOption {
+	return func(r *OutboxRelay) {
+		r.retention = retention
+	}
+}
+
+// WithOutboxLease makes the relay hold lease while it publishes a batch
+func WithOutboxLease(lease OutboxLease) OutboxRelayOption {
+	return func(r *OutboxRelay) {
+		r.lease = lease
+	}
+}
+
+// WithOutboxClock sets the clock retries and the retention period are computed from
+func WithOutboxClock(clock func() time.Time) OutboxRelayOption {
+	return func(r *OutboxRelay) {
+		r.clock = clock
+	}
+}
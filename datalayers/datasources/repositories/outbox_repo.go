@@ -0,0 +1,146 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"proposal-template/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Define the table name for the transactional outbox
+var outboxTableName = "outbox"
+
+var (
+	publishedAtColumn = clause.Column{Name: "published_at"}
+	failedAtColumn    = clause.Column{Name: "failed_at"}
+
+	nextAttemptAtColumn = clause.Column{Name: "next_attempt_at"}
+)
+
+// outboxBlockedSQL excludes the messages following, in their aggregate, a pending message
+// waiting for a retry
+const outboxBlockedSQL = `NOT EXISTS (SELECT 1 FROM "outbox" AS "blocking"
+	WHERE "blocking"."aggregate_type" = "outbox"."aggregate_type" AND "blocking"."aggregate_id" = "outbox"."aggregate_id"
+	AND "blocking"."published_at" IS NULL AND "blocking"."failed_at" IS NULL AND "blocking"."next_attempt_at" > ?
+	AND ("blocking"."created_at", "blocking"."id") < ("outbox"."created_at", "outbox"."id"))`
+
+// OutboxRepo stores the messages of the transactional outbox, see model.OutboxMessage.
+// Messages are added in the transaction of the entity they describe and read back by the
+// OutboxRelay, whose OutboxStore it is.
+type OutboxRepo struct {
+	dao *GenericDAO[model.OutboxMessage, int64]
+}
+
+var _ OutboxStore = (*OutboxRepo)(nil)
+
+// NewOutboxRepo creates a new OutboxRepo instance
+func NewOutboxRepo(db *gorm.DB, opts ...DAOOption) *OutboxRepo {
+	return &OutboxRepo{
+		dao: NewGenericDAO[model.OutboxMessage, int64](db, outboxTableName, opts...),
+	}
+}
+
+// Add inserts msgs in the transaction carried by ctx, see TxManager. Called outside of a
+// transaction, the messages are no longer tied to the change they describe.
+func (r *OutboxRepo) Add(ctx context.Context, msgs ...model.OutboxMessage) error {
+	if len(msgs) == 0 {
+		return nil
+	}
+
+	now := r.dao.clock()
+	rows := make([]model.OutboxMessage, len(msgs))
+	for i, msg := range msgs {
+		if msg.NextAttemptAt.IsZero() {
+			msg.NextAttemptAt = now
+		}
+		rows[i] = msg
+	}
+
+	if err := r.dao.conn(ctx).Table(outboxTableName).Create(&rows).Error; err != nil {
+		return wrapError(ctx, "error adding outbox messages", err)
+	}
+	return nil
+}
+
+// Pending returns up to limit messages due at now, oldest first. Messages neither
+// published nor given up on are pending; those waiting for a retry are not returned, nor
+// are the messages following them in their aggregate, so that a batch never fills up with
+// messages the relay cannot publish yet.
+func (r *OutboxRepo) Pending(ctx context.Context, now time.Time, limit int) ([]model.OutboxMessage, error) {
+	var msgs []model.OutboxMessage
+	err := r.dao.conn(ctx).
+		Table(outboxTableName).
+		Where(clause.Expr{SQL: "? IS NULL AND ? IS NULL", Vars: []interface{}{publishedAtColumn, failedAtColumn}}).
+		Where(clause.Lte{Column: nextAttemptAtColumn, Value: now}).
+		Where(clause.Expr{SQL: outboxBlockedSQL, Vars: []interface{}{now}}).
+		Order(clause.OrderBy{Columns: []clause.OrderByColumn{
+			{Column: clause.Column{Name: "created_at"}},
+			{Column: clause.Column{Name: "id"}},
+		}}).
+		Limit(limit).
+		Find(&msgs).Error
+	if err != nil {
+		return nil, wrapError(ctx, "error retrieving outbox messages", err)
+	}
+	return msgs, nil
+}
+
+// MarkPublished records that the message identified by id was published at the given time.
+func (r *OutboxRepo) MarkPublished(ctx context.Context, id int64, at time.Time) error {
+	err := r.dao.conn(ctx).
+		Table(outboxTableName).
+		Where(clause.Eq{Column: clause.Column{Name: "id"}, Value: id}).
+		UpdateColumn(publishedAtColumn.Name, at).Error
+	if err != nil {
+		return wrapError(ctx, "error updating outbox message", err)
+	}
+	return nil
+}
+
+// MarkFailed records a failed publication of msg: its attempts, next attempt time, last
+// error and, when the relay gave up, failure time.
+func (r *OutboxRepo) MarkFailed(ctx context.Context, msg model.OutboxMessage) error {
+	err := r.dao.conn(ctx).
+		Table(outboxTableName).
+		Where(clause.Eq{Column: clause.Column{Name: "id"}, Value: msg.ID}).
+		UpdateColumns(map[string]interface{}{
+			"attempts":          msg.Attempts,
+			"next_attempt_at":   msg.NextAttemptAt,
+			"last_error":        msg.LastError,
+			failedAtColumn.Name: msg.FailedAt,
+		}).Error
+	if err != nil {
+		return wrapError(ctx, "error updating outbox message", err)
+	}
+	return nil
+}
+
+// DeletePublished removes the messages published before the given time and returns their
+// number. Messages given up on are kept for inspection. Rows are removed in batches, as by
+// GenericDAO.Purge.
+func (r *OutboxRepo) DeletePublished(ctx context.Context, before time.Time) (int64, error) {
+	var deleted int64
+	for {
+		batch := r.dao.conn(ctx).
+			Table(outboxTableName).
+			Select("?", clause.Column{Name: "id"}).
+			Where(clause.Lt{Column: publishedAtColumn, Value: before}).
+			Limit(purgeBatchSize)
+
+		result := r.dao.conn(ctx).
+			Table(outboxTableName).
+			Where(clause.Expr{SQL: "? IN (?)", Vars: []interface{}{clause.Column{Name: "id"}, batch}}).
+			Delete(new(model.OutboxMessage))
+		if result.Error != nil {
+			return deleted, wrapError(ctx, "error deleting outbox messages", result.Error)
+		}
+
+		deleted += result.RowsAffected
+		if result.RowsAffected < purgeBatchSize {
+			return deleted, nil
+		}
+	}
+}
@@ -0,0 +1,235 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+	"testing"
+	"time"
+
+	model "proposal-template/models"
+	"proposal-template/pkg/kafka"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeOutbox is an in-memory OutboxStore
+type fakeOutbox struct {
+	msgs          []model.OutboxMessage
+	deletedBefore time.Time
+}
+
+func (f *fakeOutbox) add(aggregateID string, eventType string, payload string) {
+	f.msgs = append(f.msgs, model.OutboxMessage{
+		ID:            int64(len(f.msgs) + 1),
+		AggregateType: "user",
+		AggregateID:   aggregateID,
+		EventType:     eventType,
+		Payload:       json.RawMessage(payload),
+		NextAttemptAt: fixedNow,
+	})
+}
+
+func (f *fakeOutbox) Pending(_ context.Context, now time.Time, limit int) ([]model.OutboxMessage, error) {
+	var pending []model.OutboxMessage
+	waiting := make(map[string]bool)
+	for _, msg := range f.msgs {
+		if msg.PublishedAt != nil || msg.FailedAt != nil {
+			continue
+		}
+		if msg.NextAttemptAt.After(now) {
+			waiting[msg.AggregateKey()] = true
+		}
+		if !waiting[msg.AggregateKey()] && len(pending) < limit {
+			pending = append(pending, msg)
+		}
+	}
+	return pending, nil
+}
+
+func (f *fakeOutbox) MarkPublished(_ context.Context, id int64, at time.Time) error {
+	f.msgs[id-1].PublishedAt = &at
+	return nil
+}
+
+func (f *fakeOutbox) MarkFailed(_ context.Context, msg model.OutboxMessage) error {
+	f.msgs[msg.ID-1] = msg
+	return nil
+}
+
+func (f *fakeOutbox) DeletePublished(_ context.Context, before time.Time) (int64, error) {
+	f.deletedBefore = before
+	return 0, nil
+}
+
+// fakeLease is an OutboxLease held by another relay until released
+type fakeLease struct {
+	held bool
+}
+
+func (f *fakeLease) TryRun(ctx context.Context, fn func(ctx context.Context) error) (bool, error) {
+	if f.held {
+		return false, nil
+	}
+	return true, fn(ctx)
+}
+
+type userCreated struct {
+	Name string `json:"name"`
+}
+
+func newTestRelay(store OutboxStore, opts ...OutboxRelayOption) (*OutboxRelay, *kafka.MemoryPublisher) {
+	opts = append([]OutboxRelayOption{WithOutboxClock(func() time.Time { return fixedNow })}, opts...)
+	relay := NewOutboxRelay(store, nopLogger{}, opts...)
+	publisher := kafka.NewMemoryPublisher()
+	relay.Route("UserCreated", publisher, func() interface{} { return new(userCreated) })
+	return relay, publisher
+}
+
+func TestOutboxRelay_PublishesInAggregateOrder(t *testing.T) {
+	store := &fakeOutbox{}
+	store.add("a", "Unrouted", `{}`)
+	store.add("a", "UserCreated", `{"name":"Ann"}`)
+	store.add("b", "UserCreated", `{"name":"Bob"}`)
+	relay, publisher := newTestRelay(store)
+
+	require.NoError(t, relay.RunOnce(context.Background()))
+
+	// The failing message of a holds the next one back, b goes on
+	assert.Equal(t, []kafka.MemoryMessage{{Key: "b", Value: userCreated{Name: "Bob"}}}, publisher.Messages())
+	failed := store.msgs[0]
+	assert.Equal(t, 1, failed.Attempts)
+	assert.Equal(t, fixedNow.Add(DefaultOutboxInitialBackoff), failed.NextAttemptAt)
+	assert.Equal(t, `no publisher for event type "Unrouted"`, *failed.LastError)
+	assert.Nil(t, store.msgs[1].PublishedAt)
+	assert.Equal(t, &fixedNow, store.msgs[2].PublishedAt)
+}
+
+func TestOutboxRelay_HoldsTheLease(t *testing.T) {
+	store := &fakeOutbox{}
+	store.add("a", "UserCreated", `{"name":"Ann"}`)
+	lease := &fakeLease{held: true}
+	relay, publisher := newTestRelay(store, WithOutboxLease(lease))
+
+	require.NoError(t, relay.RunOnce(context.Background()))
+	assert.Empty(t, publisher.Messages())
+
+	lease.held = false
+	require.NoError(t, relay.RunOnce(context.Background()))
+	assert.Equal(t, []kafka.MemoryMessage{{Key: "a", Value: userCreated{Name: "Ann"}}}, publisher.Messages())
+}
+
+func TestOutboxRelay_GivesUpAfterMaxAttempts(t *testing.T) {
+	store := &fakeOutbox{}
+	store.add("a", "Unrouted", `{}`)
+	store.add("a", "UserCreated", `{"name":"Ann"}`)
+	store.msgs[0].Attempts = 2
+	relay, publisher := newTestRelay(store, WithOutboxRetry(3, time.Second, time.Minute))
+
+	require.NoError(t, relay.RunOnce(context.Background()))
+	assert.Equal(t, &fixedNow, store.msgs[0].FailedAt)
+	assert.Empty(t, publisher.Messages())
+
+	// The aggregate is released
+	require.NoError(t, relay.RunOnce(context.Background()))
+	assert.Len(t, publisher.Messages(), 1)
+}
+
+func TestOutboxRelay_RetriesWhenPublisherFails(t *testing.T) {
+	store := &fakeOutbox{}
+	store.add("a", "UserCreated", `{"name":"Ann"}`)
+	relay, publisher := newTestRelay(store)
+
+	publisher.SetError(assert.AnError)
+	require.NoError(t, relay.RunOnce(context.Background()))
+	assert.Nil(t, store.msgs[0].PublishedAt)
+
+	// Not due yet
+	publisher.SetError(nil)
+	require.NoError(t, relay.RunOnce(context.Background()))
+	assert.Empty(t, publisher.Messages())
+
+	store.msgs[0].NextAttemptAt = fixedNow
+	require.NoError(t, relay.RunOnce(context.Background()))
+	assert.Len(t, publisher.Messages(), 1)
+	assert.NotNil(t, store.msgs[0].PublishedAt)
+}
+
+func TestOutboxRelay_DoesNotStarveOtherAggregates(t *testing.T) {
+	store := &fakeOutbox{}
+	store.add("a", "Unrouted", `{}`)
+	store.add("a", "UserCreated", `{"name":"Ann"}`)
+	store.add("b", "UserCreated", `{"name":"Bob"}`)
+	relay, publisher := newTestRelay(store, WithOutboxBatchSize(2))
+
+	// The batch is full of messages of a, whose first one fails
+	require.NoError(t, relay.RunOnce(context.Background()))
+	assert.Empty(t, publisher.Messages())
+
+	// a is waiting for a retry, b goes on
+	require.NoError(t, relay.RunOnce(context.Background()))
+	assert.Equal(t, []kafka.MemoryMessage{{Key: "b", Value: userCreated{Name: "Bob"}}}, publisher.Messages())
+	assert.Nil(t, store.msgs[1].PublishedAt)
+}
+
+func TestOutboxRelay_Backoff(t *testing.T) {
+	relay := NewOutboxRelay(&fakeOutbox{}, nopLogger{}, WithOutboxRetry(10, time.Second, 10*time.Second))
+
+	assert.Equal(t, time.Second, relay.backoff(1))
+	assert.Equal(t, 2*time.Second, relay.backoff(2))
+	assert.Equal(t, 8*time.Second, relay.backoff(4))
+	assert.Equal(t, 10*time.Second, relay.backoff(5))
+	assert.Equal(t, 10*time.Second, relay.backoff(60))
+}
+
+func TestOutboxRelay_Cleanup(t *testing.T) {
+	store := &fakeOutbox{}
+	relay, _ := newTestRelay(store, WithOutboxRetention(time.Hour))
+
+	_, err := relay.Cleanup(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, fixedNow.Add(-time.Hour), store.deletedBefore)
+}
+
+func TestOutboxRepo_AddInTransaction(t *testing.T) {
+	db, mock := newMockDB(t)
+	repo := NewOutboxRepo(db, WithClock(func() time.Time { return fixedNow }))
+	txm := NewTxManager(db)
+	msg, err := model.NewOutboxMessage("user", "a", "UserCreated", userCreated{Name: "Ann"})
+	require.NoError(t, err)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "outbox" ("aggregate_type","aggregate_id","event_type","payload","created_at","attempts","next_attempt_at","last_error","published_at","failed_at") VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10) RETURNING "id"`)).
+		WithArgs("user", "a", "UserCreated", sqlmock.AnyArg(), fixedNow, 0, fixedNow, nil, nil, nil).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+
+	err = txm.Do(context.Background(), func(ctx context.Context) error {
+		return repo.Add(ctx, msg)
+	})
+
+	require.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestOutboxRepo_Pending(t *testing.T) {
+	db, mock := newMockDB(t)
+	repo := NewOutboxRepo(db)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "outbox" WHERE ("published_at" IS NULL AND "failed_at" IS NULL) AND "next_attempt_at" <= $1 AND `+
+		`(NOT EXISTS (SELECT 1 FROM "outbox" AS "blocking" WHERE "blocking"."aggregate_type" = "outbox"."aggregate_type" AND "blocking"."aggregate_id" = "outbox"."aggregate_id" `+
+		`AND "blocking"."published_at" IS NULL AND "blocking"."failed_at" IS NULL AND "blocking"."next_attempt_at" > $2 `+
+		`AND ("blocking"."created_at", "blocking"."id") < ("outbox"."created_at", "outbox"."id"))) ORDER BY "created_at","id" LIMIT $3`)).
+		WithArgs(fixedNow, fixedNow, 10).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "aggregate_id"}).AddRow(1, "a"))
+
+	msgs, err := repo.Pending(context.Background(), fixedNow, 10)
+
+	require.NoError(t, err)
+	require.Len(t, msgs, 1)
+	assert.Equal(t, "a", msgs[0].AggregateID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
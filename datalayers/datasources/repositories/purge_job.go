@@ -0,0 +1,125 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"proposal-template/pkg/lifecycle"
+	"proposal-template/pkg/logger"
+	"proposal-template/pkg/tenant"
+)
+
+// Purger permanently removes the rows soft-deleted before deletedBefore, GenericDAO
+// implements it for soft-deletable models.
+type Purger interface {
+	Purge(ctx context.Context, deletedBefore time.Time) (int64, error)
+}
+
+var DefaultPurgeInterval = time.Hour
+
+type namedPurger struct {
+	name   string
+	purger Purger
+}
+
+// PurgeJob periodically purges the rows soft-deleted for longer than the retention period.
+// Failed purges are logged and retried at the next run, they do not stop the job.
+type PurgeJob struct {
+	purgers   []namedPurger
+	retention time.Duration
+	interval  time.Duration
+	clock     func() time.Time
+	logger    logger.ILogger
+
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+var _ lifecycle.Runnable = (*PurgeJob)(nil)
+
+type PurgeJobOption func(*PurgeJob)
+
+func NewPurgeJob(logger logger.ILogger, retention time.Duration, opts ...PurgeJobOption) *PurgeJob {
+	job := &PurgeJob{
+		retention: retention,
+		interval:  DefaultPurgeInterval,
+		clock:     defaultClock,
+		logger:    logger,
+		stop:      make(chan struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(job)
+	}
+	return job
+}
+
+// Add registers the rows of purger under name, e.g. the table name, used in logs.
+func (j *PurgeJob) Add(name string, purger Purger) {
+	j.purgers = append(j.purgers, namedPurger{name: name, purger: purger})
+}
+
+// Start purges right away, then every interval until ctx is cancelled or Stop is called.
+func (j *PurgeJob) Start(ctx context.Context) error {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := j.RunOnce(ctx); err != nil {
+			j.logger.Error(fmt.Sprintf("Purge of soft-deleted rows failed: %s", err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-j.stop:
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (j *PurgeJob) Stop(_ context.Context) error {
+	j.stopOnce.Do(func() { close(j.stop) })
+	return nil
+}
+
+// RunOnce purges every registered purger once and returns their errors joined.
+// The retention period applies to all tenants, so purgers run with an admin bypass.
+func (j *PurgeJob) RunOnce(ctx context.Context) error {
+	ctx = tenant.WithAdminBypass(ctx)
+	deletedBefore := j.clock().Add(-j.retention)
+
+	var errs []error
+	for _, p := range j.purgers {
+		purged, err := p.purger.Purge(ctx, deletedBefore)
+		if purged > 0 {
+			j.logger.Info(fmt.Sprintf("Purged %d soft-deleted rows of %s", purged, p.name))
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", p.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// === optional configuration ===
+
+// WithPurgeInterval sets the time between two purges, DefaultPurgeInterval by default
+func WithPurgeInterval(interval time.Duration) PurgeJobOption {
+	return func(j *PurgeJob) {
+		if interval > 0 {
+			j.interval = interval
+		}
+	}
+}
+
+// WithPurgeClock sets the clock the retention period is computed from
+func WithPurgeClock(clock func() time.Time) PurgeJobOption {
+	return func(j *PurgeJob) {
+		j.clock = clock
+	}
+}
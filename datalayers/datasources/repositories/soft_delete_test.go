@@ -0,0 +1,129 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	model "proposal-template/models"
+	"proposal-template/pkg/query"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserRepo_DeletedScopes(t *testing.T) {
+	tests := map[string]struct {
+		ctx   context.Context
+		query string
+	}{
+		"default": {context.Background(), `SELECT * FROM "users" WHERE "deleted_at" IS NULL AND "id" = $1`},
+		"with":    {query.WithDeleted(context.Background()), `SELECT * FROM "users" WHERE "id" = $1`},
+		"only":    {query.OnlyDeleted(context.Background()), `SELECT * FROM "users" WHERE "deleted_at" IS NOT NULL AND "id" = $1`},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			db, mock := newMockDB(t)
+			repo := NewUserRepo(db)
+			id := uuid.New()
+
+			mock.ExpectQuery("^"+regexp.QuoteMeta(tt.query)).
+				WithArgs(id, 1).
+				WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(id))
+
+			_, err := repo.GetByID(tt.ctx, id)
+
+			require.NoError(t, err)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestUserRepo_Restore(t *testing.T) {
+	db, mock := newMockDB(t)
+	repo := NewUserRepo(db)
+	id := uuid.New()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "users" SET "deleted_at"=$1,"version"="version" + 1,"updated_at"=$2 WHERE "deleted_at" IS NOT NULL AND "id" = $3`)).
+		WithArgs(nil, sqlmock.AnyArg(), id).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	err := repo.Restore(context.Background(), id)
+
+	assert.ErrorIs(t, err, model.ErrRecordNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUserRepo_PurgeInBatches(t *testing.T) {
+	db, mock := newMockDB(t)
+	repo := NewUserRepo(db)
+	before := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	purge := regexp.QuoteMeta(`DELETE FROM "users" WHERE "id" IN (SELECT "id" FROM "users" WHERE "deleted_at" < $1 LIMIT $2)`)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(purge).WithArgs(before, purgeBatchSize).WillReturnResult(sqlmock.NewResult(0, purgeBatchSize))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec(purge).WithArgs(before, purgeBatchSize).WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectCommit()
+
+	purged, err := repo.Purge(context.Background(), before)
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(purgeBatchSize+3), purged)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+type fakePurger struct {
+	deletedBefore time.Time
+	err           error
+}
+
+func (f *fakePurger) Purge(_ context.Context, deletedBefore time.Time) (int64, error) {
+	f.deletedBefore = deletedBefore
+	return 1, f.err
+}
+
+func TestPurgeJob_RunOnce(t *testing.T) {
+	now := time.Date(2024, 5, 31, 0, 0, 0, 0, time.UTC)
+	job := NewPurgeJob(nopLogger{}, 30*24*time.Hour, WithPurgeClock(func() time.Time { return now }))
+	users, notes := &fakePurger{}, &fakePurger{err: errors.New("boom")}
+	job.Add("users", users)
+	job.Add("notes", notes)
+
+	err := job.RunOnce(context.Background())
+
+	assert.ErrorContains(t, err, "notes: boom")
+	assert.Equal(t, time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), users.deletedBefore)
+}
+
+func TestPurgeJob_StopsStart(t *testing.T) {
+	job := NewPurgeJob(nopLogger{}, time.Hour)
+	done := make(chan error)
+	go func() { done <- job.Start(context.Background()) }()
+
+	require.NoError(t, job.Stop(context.Background()))
+
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("Start did not return after Stop")
+	}
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Debug(string, ...interface{}) {}
+func (nopLogger) Info(string, ...interface{})  {}
+func (nopLogger) Warn(string, ...interface{})  {}
+func (nopLogger) Error(string, ...interface{}) {}
+func (nopLogger) GetLevel() string             { return "debug" }
+func (nopLogger) SetLevel(string)              {}
@@ -0,0 +1,150 @@
+package repositories
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	model "proposal-template/models"
+	"proposal-template/pkg/query"
+	"proposal-template/pkg/tenant"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type tenantNote struct {
+	ID int64 `gorm:"primaryKey"`
+	model.TenantModel
+	model.SoftDeletable
+	Title string `db:"title" query:"filter"`
+}
+
+func newTenantNoteDAO(t *testing.T) (*GenericDAO[tenantNote, int64], sqlmock.Sqlmock) {
+	db, mock := newMockDB(t)
+	return NewGenericDAO[tenantNote, int64](db, "notes"), mock
+}
+
+var acme = tenant.WithID(context.Background(), "acme")
+
+func TestGenericDAO_ReadsAreScopedToTenant(t *testing.T) {
+	dao, mock := newTenantNoteDAO(t)
+
+	mock.ExpectQuery("^"+regexp.QuoteMeta(`SELECT * FROM "notes" WHERE "tenant_id" = $1 AND "deleted_at" IS NULL AND "id" = $2`)).
+		WithArgs("acme", int64(1), 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "tenant_id"}).AddRow(1, "acme"))
+
+	note, err := dao.GetByID(acme, 1)
+
+	require.NoError(t, err)
+	assert.Equal(t, "acme", note.TenantID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGenericDAO_TenantRequired(t *testing.T) {
+	dao, mock := newTenantNoteDAO(t)
+	ctx := context.Background()
+
+	_, err := dao.GetByID(ctx, 1)
+	assert.ErrorIs(t, err, model.ErrTenantRequired)
+
+	_, err = dao.Exists(ctx, "title", "x")
+	assert.ErrorIs(t, err, model.ErrTenantRequired)
+
+	_, err = dao.List(ctx, model.Paging{}, query.Spec{})
+	assert.ErrorIs(t, err, model.ErrTenantRequired)
+
+	_, err = dao.Create(ctx, tenantNote{Title: "x"})
+	assert.ErrorIs(t, err, model.ErrTenantRequired)
+
+	err = dao.PartialUpdate(ctx, 1, map[string]interface{}{"title": "x"})
+	assert.ErrorIs(t, err, model.ErrTenantRequired)
+
+	err = dao.DeleteByID(ctx, 1)
+	assert.ErrorIs(t, err, model.ErrTenantRequired)
+
+	_, err = dao.Purge(ctx, time.Now())
+	assert.ErrorIs(t, err, model.ErrTenantRequired)
+
+	// No statement reached the database
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGenericDAO_AdminBypassLiftsTenantScope(t *testing.T) {
+	dao, mock := newTenantNoteDAO(t)
+
+	mock.ExpectQuery("^"+regexp.QuoteMeta(`SELECT * FROM "notes" WHERE "deleted_at" IS NULL AND "id" = $1`)).
+		WithArgs(int64(1), 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "tenant_id"}).AddRow(1, "globex"))
+
+	note, err := dao.GetByID(tenant.WithAdminBypass(context.Background()), 1)
+
+	require.NoError(t, err)
+	assert.Equal(t, "globex", note.TenantID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGenericDAO_CreateSetsTenant(t *testing.T) {
+	dao, mock := newTenantNoteDAO(t)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "notes" ("tenant_id","deleted_at","title") VALUES ($1,$2,$3)`)).
+		WithArgs("acme", nil, "hello").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+
+	id, err := dao.Create(acme, tenantNote{Title: "hello"})
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), id)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGenericDAO_CreateRefusesOtherTenant(t *testing.T) {
+	dao, mock := newTenantNoteDAO(t)
+	note := tenantNote{TenantModel: model.TenantModel{TenantID: "globex"}, Title: "hello"}
+
+	_, err := dao.Create(acme, note)
+	assert.ErrorIs(t, err, model.ErrCrossTenant)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "notes"`)).
+		WithArgs("globex", nil, "hello").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+
+	_, err = dao.Create(tenant.WithAdminBypass(acme), note)
+	require.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGenericDAO_WritesAreScopedToTenant(t *testing.T) {
+	dao, mock := newTenantNoteDAO(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "notes" SET "title"=$1 WHERE "tenant_id" = $2 AND "id" = $3 AND "notes"."deleted_at" IS NULL`)).
+		WithArgs("new", "acme", int64(1)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "notes" SET "deleted_at"=$1 WHERE "tenant_id" = $2 AND "id" = $3 AND "notes"."deleted_at" IS NULL`)).
+		WithArgs(sqlmock.AnyArg(), "acme", int64(1)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	require.NoError(t, dao.PartialUpdate(acme, 1, map[string]interface{}{"title": "new"}))
+	assert.ErrorIs(t, dao.DeleteByID(acme, 1), model.ErrRecordNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGenericDAO_TenantIsNotUpdatable(t *testing.T) {
+	dao, _ := newTenantNoteDAO(t)
+
+	err := dao.PartialUpdate(acme, 1, map[string]interface{}{"tenant_id": "globex"})
+	assert.ErrorIs(t, err, query.ErrInvalidSpec)
+
+	_, err = dao.UpsertBatch(acme, []tenantNote{{Title: "x"}}, "title")
+	assert.ErrorIs(t, err, query.ErrInvalidSpec)
+}
@@ -0,0 +1,70 @@
+package repositories
+
+import (
+	"context"
+
+	cockroachdb "proposal-template/pkg/database/cockroachDB"
+
+	"gorm.io/gorm"
+)
+
+type txKey struct{}
+
+// TxManager runs functions inside a database transaction carried by their context.
+// Every GenericDAO method picks that transaction up, so writes of several repositories
+// called with the same ctx commit or roll back together.
+type TxManager struct {
+	db *gorm.DB
+	// runner, when set, retries outermost transactions on serialization failures
+	runner *cockroachdb.TxRunner
+}
+
+type TxManagerOption func(*TxManager)
+
+func NewTxManager(db *gorm.DB, opts ...TxManagerOption) *TxManager {
+	txManager := &TxManager{db: db}
+
+	for _, opt := range opts {
+		opt(txManager)
+	}
+	return txManager
+}
+
+// WithTxRetry makes Do run outermost transactions through runner, which retries them on
+// CockroachDB serialization failures. fn may then be called several times.
+func WithTxRetry(runner *cockroachdb.TxRunner) TxManagerOption {
+	return func(m *TxManager) {
+		m.runner = runner
+	}
+}
+
+// Do runs fn in a transaction. The transaction is committed when fn returns nil and rolled
+// back when it returns an error or panics, in which case the panic is propagated.
+// When ctx already carries a transaction, Do creates a savepoint instead, so a failing
+// nested call only rolls back its own writes.
+// Only the outermost transaction is retried, a savepoint cannot recover from a
+// serialization failure.
+func (m *TxManager) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	withTx := func(tx *gorm.DB) error {
+		return fn(ContextWithTx(ctx, tx))
+	}
+
+	if tx, ok := TxFromContext(ctx); ok {
+		return tx.WithContext(ctx).Transaction(withTx)
+	}
+	if m.runner != nil {
+		return m.runner.RunInTx(ctx, withTx)
+	}
+	return m.db.WithContext(ctx).Transaction(withTx)
+}
+
+// ContextWithTx returns a copy of ctx carrying tx.
+func ContextWithTx(ctx context.Context, tx *gorm.DB) context.Context {
+	return context.WithValue(ctx, txKey{}, tx)
+}
+
+// TxFromContext returns the transaction carried by ctx, if any.
+func TxFromContext(ctx context.Context) (*gorm.DB, bool) {
+	tx, ok := ctx.Value(txKey{}).(*gorm.DB)
+	return tx, ok
+}
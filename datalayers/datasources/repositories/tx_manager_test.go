@@ -0,0 +1,113 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+
+	cockroachdb "proposal-template/pkg/database/cockroachDB"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTxManager_DoCommitsAndSharesTx(t *testing.T) {
+	db, mock := newMockDB(t)
+	txm := NewTxManager(db)
+	repo := NewUserRepo(db)
+	id := uuid.New()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "users" SET "deleted_at"=$1 WHERE "id" = $2 AND "users"."deleted_at" IS NULL`)).
+		WithArgs(sqlmock.AnyArg(), id).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err := txm.Do(context.Background(), func(ctx context.Context) error {
+		_, ok := TxFromContext(ctx)
+		assert.True(t, ok)
+		return repo.DeleteByID(ctx, id)
+	})
+
+	require.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTxManager_DoRollsBackOnError(t *testing.T) {
+	db, mock := newMockDB(t)
+	txm := NewTxManager(db)
+	boom := errors.New("boom")
+
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+
+	err := txm.Do(context.Background(), func(ctx context.Context) error {
+		return boom
+	})
+
+	assert.ErrorIs(t, err, boom)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTxManager_DoRollsBackOnPanic(t *testing.T) {
+	db, mock := newMockDB(t)
+	txm := NewTxManager(db)
+
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+
+	assert.PanicsWithValue(t, "boom", func() {
+		_ = txm.Do(context.Background(), func(ctx context.Context) error {
+			panic("boom")
+		})
+	})
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTxManager_NestedDoUsesSavepoint(t *testing.T) {
+	db, mock := newMockDB(t)
+	txm := NewTxManager(db)
+	inner := errors.New("inner failed")
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`SAVEPOINT sp\w+`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`ROLLBACK TO SAVEPOINT sp\w+`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	err := txm.Do(context.Background(), func(ctx context.Context) error {
+		err := txm.Do(ctx, func(ctx context.Context) error {
+			return inner
+		})
+		assert.ErrorIs(t, err, inner)
+		return nil
+	})
+
+	require.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGenericDAO_RetryOnWrite(t *testing.T) {
+	db, mock := newMockDB(t)
+	runner := cockroachdb.NewTxRunner(db, cockroachdb.WithRetryPolicy(cockroachdb.RetryPolicy{MaxAttempts: 2}))
+	repo := NewUserRepo(db, WithRetryOnWrite(runner))
+	id := uuid.New()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "users" SET "deleted_at"=$1 WHERE "id" = $2 AND "users"."deleted_at" IS NULL`)).
+		WithArgs(sqlmock.AnyArg(), id).
+		WillReturnError(&pgconn.PgError{Code: cockroachdb.SerializationFailureCode})
+	mock.ExpectRollback()
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "users" SET "deleted_at"=$1 WHERE "id" = $2 AND "users"."deleted_at" IS NULL`)).
+		WithArgs(sqlmock.AnyArg(), id).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	require.NoError(t, repo.DeleteByID(context.Background(), id))
+	assert.Equal(t, int64(1), runner.Metrics().Snapshot().Retries)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -4,6 +4,7 @@ import (
 
 	"proposal-template/models"
 
+	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
@@ -11,13 +12,13 @@ import (
 var usersTableName = "users"
 
 type UserRepo struct {
-	*GenericDAO[model.User] 
+	*GenericDAO[model.User, uuid.UUID]
 }
 
 // NewUserRepo creates a new UserRepo instance
-func NewUserRepo(db *gorm.DB) *UserRepo {
+func NewUserRepo(db *gorm.DB, opts ...DAOOption) *UserRepo {
 	return &UserRepo{
-		GenericDAO: NewGenericDAO[model.User](db, usersTableName), 
+		GenericDAO: NewGenericDAO[model.User, uuid.UUID](db, usersTableName, opts...),
 	}
 }
 // === Implement other methods of UserRepo below ==
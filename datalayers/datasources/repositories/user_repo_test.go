@@ -1,5 +1,204 @@
 package repositories
 
+import (
+	"context"
+	"regexp"
+	"testing"
 
+	model "proposal-template/models"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newMockDB returns a gorm DB backed by sqlmock, configured like cockroachdb.NewCockroachDB.
+func newMockDB(t *testing.T) (*gorm.DB, sqlmock.Sqlmock) {
+	t.Helper()
+
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB}), &gorm.Config{
+		TranslateError: true,
+		Logger:         logger.Default.LogMode(logger.Silent),
+	})
+	require.NoError(t, err)
+	return db, mock
+}
 
 // TestNewUserRepo verifies that UserRepo is initialized correctly
+func TestNewUserRepo(t *testing.T) {
+	db, _ := newMockDB(t)
+
+	repo := NewUserRepo(db)
+
+	require.NotNil(t, repo.GenericDAO)
+	assert.Equal(t, usersTableName, repo.tableName)
+}
+
+func TestUserRepo_GetByColumnNotFound(t *testing.T) {
+	db, mock := newMockDB(t)
+	repo := NewUserRepo(db)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "users" WHERE "deleted_at" IS NULL AND "email" = $1`)).
+		WithArgs("ghost@example.com", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	user, err := repo.GetByColumn(context.Background(), "email", "ghost@example.com")
+
+	assert.Nil(t, user)
+	assert.ErrorIs(t, err, model.ErrRecordNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUserRepo_CreateDuplicateEmail(t *testing.T) {
+	db, mock := newMockDB(t)
+	repo := NewUserRepo(db)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "users"`)).
+		WillReturnError(&pgconn.PgError{Code: "23505", ConstraintName: "users_email_key"})
+	mock.ExpectRollback()
+
+	_, err := repo.Create(context.Background(), model.User{Name: "Jane", Email: "jane@example.com"})
+
+	assert.ErrorIs(t, err, model.ErrDuplicateKey)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUserRepo_Exists(t *testing.T) {
+	db, mock := newMockDB(t)
+	repo := NewUserRepo(db)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT EXISTS (SELECT 1 FROM "users" WHERE "deleted_at" IS NULL AND "email" = $1)`)).
+		WithArgs("jane@example.com").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+	exists, err := repo.Exists(context.Background(), "email", "jane@example.com")
+
+	require.NoError(t, err)
+	assert.True(t, exists)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUserRepo_DeleteMissingRow(t *testing.T) {
+	db, mock := newMockDB(t)
+	repo := NewUserRepo(db)
+	id := uuid.New()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "users" SET "deleted_at"=$1 WHERE "id" = $2 AND "users"."deleted_at" IS NULL`)).
+		WithArgs(sqlmock.AnyArg(), id).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	err := repo.DeleteByID(context.Background(), id)
+
+	assert.ErrorIs(t, err, model.ErrRecordNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUserRepo_PartialUpdate(t *testing.T) {
+	db, mock := newMockDB(t)
+	repo := NewUserRepo(db)
+	id := uuid.New()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "users" SET "name"=$1,"version"="version" + 1,"updated_at"=$2 WHERE "id" = $3`)).
+		WithArgs("Jane", sqlmock.AnyArg(), id).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err := repo.PartialUpdate(context.Background(), id, map[string]interface{}{"name": "Jane"})
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUserRepo_UpdateIncrementsVersion(t *testing.T) {
+	db, mock := newMockDB(t)
+	repo := NewUserRepo(db)
+	user := model.User{BaseModel: model.BaseModel{Id: uuid.New()}, Versioned: model.Versioned{Version: 3}, Name: "Jane"}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "users" SET "updated_at"=$1,"version"=$2,"name"=$3,"email"=$4,"email_verified"=$5 WHERE "version" = $6 AND "users"."deleted_at" IS NULL AND "id" = $7`)).
+		WithArgs(sqlmock.AnyArg(), int64(4), "Jane", "", false, int64(3), user.Id).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err := repo.Update(context.Background(), user)
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUserRepo_UpdateStaleVersion(t *testing.T) {
+	for name, exists := range map[string]bool{"conflict": true, "not found": false} {
+		t.Run(name, func(t *testing.T) {
+			db, mock := newMockDB(t)
+			repo := NewUserRepo(db)
+			user := model.User{BaseModel: model.BaseModel{Id: uuid.New()}, Versioned: model.Versioned{Version: 3}}
+
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta(`UPDATE "users" SET`)).
+				WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectCommit()
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT EXISTS (SELECT 1 FROM "users" WHERE "deleted_at" IS NULL AND "id" = $1)`)).
+				WithArgs(user.Id).
+				WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(exists))
+
+			err := repo.Update(context.Background(), user)
+
+			if exists {
+				assert.ErrorIs(t, err, model.ErrConflict)
+			} else {
+				assert.ErrorIs(t, err, model.ErrRecordNotFound)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestUserRepo_PartialUpdateIfVersion(t *testing.T) {
+	db, mock := newMockDB(t)
+	repo := NewUserRepo(db)
+	id := uuid.New()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "users" SET "name"=$1,"version"="version" + 1,"updated_at"=$2 WHERE "id" = $3 AND "version" = $4`)).
+		WithArgs("Jane", sqlmock.AnyArg(), id, int64(3)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err := repo.PartialUpdateIfVersion(context.Background(), id, 3, map[string]interface{}{"name": "Jane"})
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUserRepo_DeleteByIDIfVersionStale(t *testing.T) {
+	db, mock := newMockDB(t)
+	repo := NewUserRepo(db)
+	id := uuid.New()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "users" SET "deleted_at"=$1 WHERE "id" = $2 AND "version" = $3 AND "users"."deleted_at" IS NULL`)).
+		WithArgs(sqlmock.AnyArg(), id, int64(3)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT EXISTS (SELECT 1 FROM "users" WHERE "deleted_at" IS NULL AND "id" = $1)`)).
+		WithArgs(id).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+	err := repo.DeleteByIDIfVersion(context.Background(), id, 3)
+
+	assert.ErrorIs(t, err, model.ErrConflict)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
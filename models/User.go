@@ -3,9 +3,30 @@ package model
 
 type User struct {
 	BaseModel
-	Name          string     `json:"name" db:"name"`
-	Email         string     `json:"email" db:"email"`
-	EmailVerified bool       `json:"email_verified" db:"email_verified"`
+	Versioned
+	SoftDeletable
+	Name          string     `json:"name" db:"name" query:"filter,sort"`
+	Email         string     `json:"email" db:"email" query:"filter,sort"`
+	EmailVerified bool       `json:"email_verified" db:"email_verified" query:"filter"`
 }
 
+// UserCreate is the payload of POST /users
+type UserCreate struct {
+	Name  string `json:"name" binding:"required,max=255"`
+	Email string `json:"email" binding:"required,email,max=255"`
+}
+
+// UserUpdate is the payload of PUT /users/:id, it replaces every mutable field
+type UserUpdate struct {
+	Name          string `json:"name" binding:"required,max=255"`
+	Email         string `json:"email" binding:"required,email,max=255"`
+	EmailVerified bool   `json:"email_verified"`
+}
+
+// UserPatch is the payload of PATCH /users/:id, only the fields present are changed
+type UserPatch struct {
+	Name          *string `json:"name" binding:"omitempty,min=1,max=255"`
+	Email         *string `json:"email" binding:"omitempty,email,max=255"`
+	EmailVerified *bool   `json:"email_verified"`
+}
 
@@ -1,14 +1,60 @@
 package model
 
 import (
+	"slices"
 	"time"
 
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 type BaseModel struct {
-	Id        uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
-	CreatedAt time.Time
-	UpdatedAt time.Time
+	Id        uuid.UUID `json:"id" db:"id" query:"filter" gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	CreatedAt time.Time `db:"created_at" query:"filter,sort"`
+	UpdatedAt time.Time `db:"updated_at" query:"filter,sort"`
 }
 
+
+func (m *BaseModel) SetCreatedAt(t time.Time) {
+	m.CreatedAt = t
+}
+
+func (m *BaseModel) SetUpdatedAt(t time.Time) {
+	m.UpdatedAt = t
+}
+
+// Versioned is embedded next to BaseModel by models using optimistic concurrency control.
+// GenericDAO.Update only writes a row whose version is still the one read and increments it,
+// so concurrent writers cannot silently overwrite each other.
+type Versioned struct {
+	Version int64 `json:"version" db:"version" query:"filter"`
+}
+
+func (v Versioned) GetVersion() int64 {
+	return v.Version
+}
+
+// VersionMatch is the precondition of a conditional write: the versions named by the
+// If-Match header of the request. A nil VersionMatch accepts any version, an empty one none.
+type VersionMatch []int64
+
+// Matches reports whether an entity at version satisfies the precondition.
+func (m VersionMatch) Matches(version int64) bool {
+	return m == nil || slices.Contains(m, version)
+}
+
+// SoftDeletable is embedded next to BaseModel by models whose rows must not be removed
+// right away. GenericDAO.DeleteByID then only sets DeletedAt, reads hide such rows unless
+// the context asks for them (see query.WithDeleted), and GenericDAO.Purge removes them
+// for good once the retention period is over.
+type SoftDeletable struct {
+	DeletedAt gorm.DeletedAt `json:"deleted_at" db:"deleted_at"`
+}
+
+// TenantModel is embedded next to BaseModel by models whose rows belong to a tenant.
+// GenericDAO scopes every read and write of such models to the tenant of the context
+// (see tenant.WithID) and sets TenantID on Create. Reaching the rows of other tenants
+// requires tenant.WithAdminBypass.
+type TenantModel struct {
+	TenantID string `json:"tenant_id" db:"tenant_id" query:"filter"`
+}
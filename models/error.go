@@ -6,6 +6,32 @@ var (
 	ErrUnknown       = utils.NewCustomError("unknown")
 	ErrMalformedJSON = utils.NewCustomError("malformed_json")
 	ErrUnimplemented = utils.NewCustomError("unimplemented method")
+	ErrInvalidInput  = utils.NewCustomError("invalid_input")
+	ErrInvalidID     = utils.NewCustomError("invalid_id")
+	ErrInvalidFilter = utils.NewCustomError("invalid_filter")
+)
+
+// Errors returned by the datalayer, biz services translate them into domain errors
+var (
+	ErrRecordNotFound = utils.NewCustomError("record_not_found")
+	ErrDuplicateKey   = utils.NewCustomError("duplicate_key")
+	// ErrConflict is returned when a versioned row was changed by someone else since it was read
+	ErrConflict = utils.NewCustomError("conflict")
+	// ErrTenantRequired is returned when the rows of a tenant model are accessed without a
+	// tenant in the context
+	ErrTenantRequired = utils.NewCustomError("tenant_required")
+	// ErrCrossTenant is returned when a row of another tenant than the context's one is written
+	ErrCrossTenant = utils.NewCustomError("cross_tenant_access")
+)
+
+var (
+	// ErrPreconditionFailed is returned when the If-Match version of a request is not current
+	ErrPreconditionFailed = utils.NewCustomError("precondition_failed")
+)
+
+var (
+	ErrRequestCanceled = utils.NewCustomError("request_canceled")
+	ErrRequestTimeout  = utils.NewCustomError("request_timeout")
 )
 
 var (
@@ -0,0 +1,100 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DomainEvent is a change of an aggregate, such as a user, that other components and
+// services react to. Events are published to Kafka as Avro records, their fields carry
+// both json tags (outbox payload) and avro tags (Kafka message).
+type DomainEvent interface {
+	EventName() string
+	AggregateType() string
+	AggregateID() string
+}
+
+// Names of the user events
+const (
+	EventUserCreated   = "UserCreated"
+	EventUserUpdated   = "UserUpdated"
+	EventEmailVerified = "EmailVerified"
+)
+
+// UserAggregate is the aggregate type of the user events
+const UserAggregate = "user"
+
+// UserCreated is emitted when a user is created.
+type UserCreated struct {
+	EventID    string    `json:"event_id" avro:"event_id"`
+	OccurredAt time.Time `json:"occurred_at" avro:"occurred_at"`
+	UserID     string    `json:"user_id" avro:"user_id"`
+	Name       string    `json:"name" avro:"name"`
+	Email      string    `json:"email" avro:"email"`
+}
+
+func (e UserCreated) EventName() string     { return EventUserCreated }
+func (e UserCreated) AggregateType() string { return UserAggregate }
+func (e UserCreated) AggregateID() string   { return e.UserID }
+
+// UserUpdated is emitted when any field of a user changes, it carries the new state.
+type UserUpdated struct {
+	EventID       string    `json:"event_id" avro:"event_id"`
+	OccurredAt    time.Time `json:"occurred_at" avro:"occurred_at"`
+	UserID        string    `json:"user_id" avro:"user_id"`
+	Name          string    `json:"name" avro:"name"`
+	Email         string    `json:"email" avro:"email"`
+	EmailVerified bool      `json:"email_verified" avro:"email_verified"`
+	Version       int64     `json:"version" avro:"version"`
+}
+
+func (e UserUpdated) EventName() string     { return EventUserUpdated }
+func (e UserUpdated) AggregateType() string { return UserAggregate }
+func (e UserUpdated) AggregateID() string   { return e.UserID }
+
+// EmailVerified is emitted, after UserUpdated, when the email address of a user becomes verified.
+type EmailVerified struct {
+	EventID    string    `json:"event_id" avro:"event_id"`
+	OccurredAt time.Time `json:"occurred_at" avro:"occurred_at"`
+	UserID     string    `json:"user_id" avro:"user_id"`
+	Email      string    `json:"email" avro:"email"`
+}
+
+func (e EmailVerified) EventName() string     { return EventEmailVerified }
+func (e EmailVerified) AggregateType() string { return UserAggregate }
+func (e EmailVerified) AggregateID() string   { return e.UserID }
+
+// NewUserCreated returns the event of the creation of user.
+func NewUserCreated(user User) UserCreated {
+	return UserCreated{
+		EventID:    uuid.NewString(),
+		OccurredAt: user.CreatedAt,
+		UserID:     user.Id.String(),
+		Name:       user.Name,
+		Email:      user.Email,
+	}
+}
+
+// NewUserUpdated returns the event of an update that left user in its current state.
+func NewUserUpdated(user User) UserUpdated {
+	return UserUpdated{
+		EventID:       uuid.NewString(),
+		OccurredAt:    user.UpdatedAt,
+		UserID:        user.Id.String(),
+		Name:          user.Name,
+		Email:         user.Email,
+		EmailVerified: user.EmailVerified,
+		Version:       user.Version,
+	}
+}
+
+// NewEmailVerified returns the event of the verification of the email address of user.
+func NewEmailVerified(user User) EmailVerified {
+	return EmailVerified{
+		EventID:    uuid.NewString(),
+		OccurredAt: user.UpdatedAt,
+		UserID:     user.Id.String(),
+		Email:      user.Email,
+	}
+}
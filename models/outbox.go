@@ -0,0 +1,47 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// OutboxMessage is an event stored in the outbox table in the transaction of the entity it
+// describes, the aggregate. The outbox relay publishes it afterwards, so the event is
+// neither lost when the process dies after the commit nor sent for a rolled back change.
+type OutboxMessage struct {
+	ID            int64           `json:"id" db:"id" gorm:"primaryKey"`
+	AggregateType string          `json:"aggregate_type" db:"aggregate_type"`
+	AggregateID   string          `json:"aggregate_id" db:"aggregate_id"`
+	EventType     string          `json:"event_type" db:"event_type"`
+	Payload       json.RawMessage `json:"payload" db:"payload"`
+	CreatedAt     time.Time       `json:"created_at" db:"created_at"`
+	// Attempts is the number of failed publications
+	Attempts      int        `json:"attempts" db:"attempts"`
+	NextAttemptAt time.Time  `json:"next_attempt_at" db:"next_attempt_at"`
+	LastError     *string    `json:"last_error" db:"last_error"`
+	PublishedAt   *time.Time `json:"published_at" db:"published_at"`
+	// FailedAt is set when the relay gave up publishing the message
+	FailedAt *time.Time `json:"failed_at" db:"failed_at"`
+}
+
+// NewOutboxMessage returns the message of an event of type eventType about the aggregate
+// identified by aggregateType and aggregateID. The payload is stored as JSON.
+func NewOutboxMessage(aggregateType string, aggregateID string, eventType string, payload interface{}) (OutboxMessage, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return OutboxMessage{}, fmt.Errorf("failed to encode %s payload: %w", eventType, err)
+	}
+	return OutboxMessage{
+		AggregateType: aggregateType,
+		AggregateID:   aggregateID,
+		EventType:     eventType,
+		Payload:       data,
+	}, nil
+}
+
+// AggregateKey identifies the aggregate of the message, messages of the same aggregate are
+// published in order.
+func (m OutboxMessage) AggregateKey() string {
+	return m.AggregateType + "/" + m.AggregateID
+}
@@ -1,7 +1,15 @@
 package model
+
+const MaxPagingLimit = 100
+
 type Paging struct {
-	Page  int `json:"page"`
-	Limit int `json:"limit"`
+	Page  int `json:"page" form:"page"`
+	Limit int `json:"limit" form:"limit"`
+	// Cursor is the next_cursor returned with the previous page. When set, Page is ignored
+	// and rows are read with a keyset query instead of OFFSET.
+	Cursor string `json:"-" form:"cursor"`
+	// WithTotal requests the number of rows matching the filter, at the cost of a COUNT query
+	WithTotal bool `json:"-" form:"with_total"`
 }
 
 func (p *Paging) Validate() {
@@ -11,4 +19,25 @@ func (p *Paging) Validate() {
 	if p.Limit < 1 {
 		p.Limit = 10
 	}
-}
\ No newline at end of file
+	if p.Limit > MaxPagingLimit {
+		p.Limit = MaxPagingLimit
+	}
+}
+
+// PageInfo is the paging metadata returned with a page of results
+type PageInfo struct {
+	// Page is only set for OFFSET pagination, i.e. when no cursor was given
+	Page  int `json:"page,omitempty"`
+	Limit int `json:"limit"`
+	// NextCursor is the token to pass as Paging.Cursor to read the next page
+	NextCursor string `json:"next_cursor,omitempty"`
+	HasMore    bool   `json:"has_more"`
+	// Total is only set when Paging.WithTotal was requested
+	Total *int64 `json:"total,omitempty"`
+}
+
+// Page is the standard response envelope of list endpoints
+type Page[T any] struct {
+	Data   []T      `json:"data"`
+	Paging PageInfo `json:"paging"`
+}
@@ -0,0 +1,206 @@
+// Package cli runs the subcommands of a binary, such as serve or migrate, on top of the
+// standard flag package.
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// ErrUsage is returned when the command line is invalid, the usage has been printed.
+var ErrUsage = errors.New("invalid usage")
+
+// Usagef returns an ErrUsage explaining what is wrong with the command line.
+func Usagef(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrUsage, fmt.Sprintf(format, args...))
+}
+
+// Globals are the flags shared by every command. They are accepted before or after the
+// command name: app --env test serve, or app serve --env test.
+type Globals struct {
+	// ConfigFile is the configuration file, YAML, TOML or dotenv
+	ConfigFile string
+	// Env selects the environment (APP_ENV), development, test or production
+	Env string
+	// Set overrides configuration fields, as KEY=VALUE pairs
+	Set []string
+}
+
+func (g *Globals) register(flags *flag.FlagSet) {
+	flags.StringVar(&g.ConfigFile, "config", g.ConfigFile, "configuration file: YAML, TOML or dotenv")
+	flags.StringVar(&g.Env, "env", g.Env, "environment to run in: development, test or production")
+	flags.Var((*stringList)(&g.Set), "set", "override a configuration field, KEY=VALUE, repeatable")
+}
+
+// stringList is a flag that can be repeated, each value is appended
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// Command is a subcommand of the binary.
+type Command struct {
+	Name string
+	// Usage lists the arguments after the flags, e.g. "COMMAND [VERSION]"
+	Usage string
+	// Summary is a one-line description shown in the command list
+	Summary string
+	// Description is shown in the help of the command, after the summary
+	Description string
+	// Flags declares the flags of the command, the global flags are added to them
+	Flags func(flags *flag.FlagSet)
+	// Run executes the command with the arguments left after the flags. ctx is cancelled
+	// when the process receives SIGINT or SIGTERM.
+	Run func(ctx context.Context, globals Globals, args []string) error
+}
+
+// App dispatches the command line to its commands.
+type App struct {
+	name     string
+	commands map[string]*Command
+	// defaultCommand runs when the command line names no command
+	defaultCommand string
+	output         io.Writer
+}
+
+type Option func(*App)
+
+// WithDefaultCommand sets the command run when the command line names none.
+func WithDefaultCommand(name string) Option {
+	return func(a *App) {
+		a.defaultCommand = name
+	}
+}
+
+// WithOutput sets where the usage and flag errors are written, os.Stderr by default.
+func WithOutput(w io.Writer) Option {
+	return func(a *App) {
+		a.output = w
+	}
+}
+
+func New(name string, opts ...Option) *App {
+	a := &App{
+		name:     name,
+		commands: make(map[string]*Command),
+		output:   os.Stderr,
+	}
+
+	for _, opt := range opts {
+		opt(a)
+	}
+	return a
+}
+
+// Register adds commands to the app.
+func (a *App) Register(commands ...*Command) {
+	for _, cmd := range commands {
+		a.commands[cmd.Name] = cmd
+	}
+}
+
+// Run parses args, without the program name, and runs the command they name. It returns
+// ErrUsage when they are invalid and nil when help was requested.
+func (a *App) Run(ctx context.Context, args []string) error {
+	var globals Globals
+
+	root := flag.NewFlagSet(a.name, flag.ContinueOnError)
+	root.SetOutput(a.output)
+	root.Usage = a.usage
+	globals.register(root)
+	if err := root.Parse(args); err != nil {
+		return a.parseError(err)
+	}
+
+	name := root.Arg(0)
+	args = root.Args()
+	if name == "" {
+		name = a.defaultCommand
+	} else {
+		args = args[1:]
+	}
+	cmd, ok := a.commands[name]
+	if !ok {
+		if name != "" {
+			fmt.Fprintf(a.output, "unknown command %q\n\n", name)
+		}
+		a.usage()
+		return ErrUsage
+	}
+
+	flags := flag.NewFlagSet(fmt.Sprintf("%s %s", a.name, cmd.Name), flag.ContinueOnError)
+	flags.SetOutput(a.output)
+	flags.Usage = func() { a.commandUsage(cmd, flags) }
+	if cmd.Flags != nil {
+		cmd.Flags(flags)
+	}
+	globals.register(flags)
+	if err := flags.Parse(args); err != nil {
+		return a.parseError(err)
+	}
+
+	if err := cmd.Run(ctx, globals, flags.Args()); err != nil {
+		if errors.Is(err, ErrUsage) {
+			if err != ErrUsage {
+				fmt.Fprintf(a.output, "%s\n\n", err)
+			}
+			flags.Usage()
+		}
+		return err
+	}
+	return nil
+}
+
+// parseError turns a flag error into ErrUsage, the flag package already printed it
+func (a *App) parseError(err error) error {
+	if errors.Is(err, flag.ErrHelp) {
+		return nil
+	}
+	return ErrUsage
+}
+
+func (a *App) usage() {
+	fmt.Fprintf(a.output, "Usage: %s [--config FILE] [--env ENV] [--set KEY=VALUE] COMMAND [FLAGS] [ARGS]\n\nCommands:\n", a.name)
+
+	names := make([]string, 0, len(a.commands))
+	for name := range a.commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	w := tabwriter.NewWriter(a.output, 0, 0, 3, ' ', 0)
+	for _, name := range names {
+		summary := a.commands[name].Summary
+		if name == a.defaultCommand {
+			summary += " (default)"
+		}
+		fmt.Fprintf(w, "  %s\t%s\n", name, summary)
+	}
+	w.Flush()
+	fmt.Fprintf(a.output, "\nRun '%s COMMAND -h' for the flags of a command.\n", a.name)
+}
+
+func (a *App) commandUsage(cmd *Command, flags *flag.FlagSet) {
+	fmt.Fprintf(a.output, "Usage: %s %s [FLAGS] %s\n\n", a.name, cmd.Name, cmd.Usage)
+	if summary := strings.TrimSpace(cmd.Summary); summary != "" {
+		fmt.Fprintf(a.output, "%s\n\n", summary)
+	}
+	if description := strings.TrimSpace(cmd.Description); description != "" {
+		fmt.Fprintf(a.output, "%s\n\n", description)
+	}
+	fmt.Fprintln(a.output, "Flags:")
+	flags.PrintDefaults()
+}
@@ -0,0 +1,91 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type call struct {
+	globals Globals
+	args    []string
+	verbose bool
+}
+
+func newTestApp(out *bytes.Buffer, calls *[]call) *App {
+	var verbose bool
+
+	app := New("app", WithDefaultCommand("serve"), WithOutput(out))
+	app.Register(
+		&Command{
+			Name:    "serve",
+			Summary: "Serve the API",
+			Run: func(_ context.Context, globals Globals, args []string) error {
+				*calls = append(*calls, call{globals: globals, args: args})
+				return nil
+			},
+		},
+		&Command{
+			Name:    "migrate",
+			Summary: "Migrate the database",
+			Flags: func(flags *flag.FlagSet) {
+				flags.BoolVar(&verbose, "v", false, "verbose")
+			},
+			Run: func(_ context.Context, globals Globals, args []string) error {
+				*calls = append(*calls, call{globals: globals, args: args, verbose: verbose})
+				if len(args) == 0 {
+					return Usagef("missing command")
+				}
+				return nil
+			},
+		},
+	)
+	return app
+}
+
+func TestApp_Run(t *testing.T) {
+	var (
+		out   bytes.Buffer
+		calls []call
+	)
+	app := newTestApp(&out, &calls)
+
+	require.NoError(t, app.Run(context.Background(), nil))
+	require.NoError(t, app.Run(context.Background(), []string{"--env", "test", "--set", "HTTP_PORT=9090", "migrate", "-v", "--config", "app.yaml", "--set", "logger.level=debug", "up", "3"}))
+
+	require.Len(t, calls, 2)
+	assert.Equal(t, call{}, calls[0])
+	assert.Equal(t, call{
+		globals: Globals{ConfigFile: "app.yaml", Env: "test", Set: []string{"HTTP_PORT=9090", "logger.level=debug"}},
+		args:    []string{"up", "3"},
+		verbose: true,
+	}, calls[1])
+}
+
+func TestApp_RunUsage(t *testing.T) {
+	var (
+		out   bytes.Buffer
+		calls []call
+	)
+	app := newTestApp(&out, &calls)
+
+	assert.ErrorIs(t, app.Run(context.Background(), []string{"unknown"}), ErrUsage)
+	assert.Contains(t, out.String(), `unknown command "unknown"`)
+	assert.Contains(t, out.String(), "serve     Serve the API (default)")
+
+	out.Reset()
+	assert.ErrorIs(t, app.Run(context.Background(), []string{"migrate", "--bogus"}), ErrUsage)
+	assert.Contains(t, out.String(), "flag provided but not defined: -bogus")
+
+	out.Reset()
+	assert.ErrorIs(t, app.Run(context.Background(), []string{"migrate"}), ErrUsage)
+	assert.Contains(t, out.String(), "invalid usage: missing command")
+	assert.Contains(t, out.String(), "Usage: app migrate [FLAGS]")
+
+	assert.NoError(t, app.Run(context.Background(), []string{"migrate", "-h"}))
+	assert.Len(t, calls, 1)
+}
@@ -2,15 +2,14 @@ package cockroachdb
 
 import (
 	"context"
-
-	"embed"
 	"fmt"
-	"log"
+	"strconv"
 	"time"
 
-	config "proposal-template/pkg/utils/config"
+	"proposal-template/pkg/utils"
 
-	"github.com/pressly/goose/v3"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
@@ -19,76 +18,103 @@ import (
 // region: ======= CockroachDB Configuration =======
 
 var DefaultConfig = CockroachDBConfig{
-	URI:                   "postgresql://root@localhost:26257/defaultdb?sslmode=disable",
-	MaxOpenConns:          25,
-	MaxIdleConns:          25,
-	ConnMaxLifetimeInSecs: 300,
-}
-
-var _ config.IConfig = (*CockroachDBConfig)(nil)
-
-func (c *CockroachDBConfig) Load() error {
-	log.Printf("Loading CockroachDBConfig")
-	return config.ParseConfig(c)
+	URI:                     "postgresql://root@localhost:26257/defaultdb?sslmode=disable",
+	MaxOpenConns:            25,
+	MaxIdleConns:            25,
+	ConnMaxLifetimeInSecs:   300,
+	ConnMaxIdleTimeInSecs:   60,
+	ConnectAttempts:         10,
+	ConnectRetryInterval:    500 * time.Millisecond,
+	ConnectMaxRetryInterval: 5 * time.Second,
+	LogLevel:                logger.Warn,
 }
 
-func NewCockroachDB(opts ...Option) (*gorm.DB, error) {
+// NewCockroachDB connects to the database, retrying while it is starting. Cancelling ctx
+// stops the retries.
+func NewCockroachDB(ctx context.Context, opts ...Option) (*gorm.DB, error) {
 	cfg := DefaultConfig
 
 	for _, opt := range opts {
 		opt(&cfg)
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-
-	// Configure GORM database connection
-	gormConfig := &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Info),
-	}
-
-	db, err := gorm.Open(postgres.Open(cfg.URI), gormConfig)
+	connConfig, err := pgx.ParseConfig(cfg.URI)
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to CockroachDB: %w", err)
+		return nil, fmt.Errorf("invalid CockroachDB URI: %w", err)
+	}
+	if cfg.StatementTimeout > 0 {
+		connConfig.RuntimeParams["statement_timeout"] = strconv.FormatInt(cfg.StatementTimeout.Milliseconds(), 10)
 	}
 
-	sqlDB, err := db.DB()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get underlying sql.DB: %w", err)
+	var connOpts []stdlib.OptionOpenDB
+	if cfg.PasswordFunc != nil {
+		connOpts = append(connOpts, stdlib.OptionBeforeConnect(func(ctx context.Context, connConfig *pgx.ConnConfig) error {
+			password, err := cfg.PasswordFunc(ctx)
+			if err != nil {
+				return fmt.Errorf("failed to get the database password: %w", err)
+			}
+			connConfig.Password = password
+			return nil
+		}))
 	}
 
+	sqlDB := stdlib.OpenDB(*connConfig, connOpts...)
 	sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
 	sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
 	sqlDB.SetConnMaxLifetime(time.Duration(cfg.ConnMaxLifetimeInSecs) * time.Second)
+	sqlDB.SetConnMaxIdleTime(time.Duration(cfg.ConnMaxIdleTimeInSecs) * time.Second)
+
+	retryOpts := []utils.RetryOption{
+		utils.WithRetryContext(ctx),
+		utils.WithMaxInterval(cfg.ConnectMaxRetryInterval),
+	}
+	if cfg.Logger != nil {
+		retryOpts = append(retryOpts, utils.WithRetryLogger(cfg.Logger))
+	}
+	_, err = utils.Retry(max(cfg.ConnectAttempts, 1), cfg.ConnectRetryInterval, func() (struct{}, error) {
+		ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		defer cancel()
+		return struct{}{}, sqlDB.PingContext(ctx)
+	}, retryOpts...)
+	if err != nil {
+		sqlDB.Close()
+		return nil, fmt.Errorf("failed to connect to CockroachDB: %w", err)
+	}
 
-	if err = sqlDB.PingContext(ctx); err != nil {
-		return nil, fmt.Errorf("failed to ping database: %w", err)
+	// Configure GORM database connection
+	gormConfig := &gorm.Config{
+		Logger: queryLogger(cfg),
+		// Map driver errors such as unique violations to gorm.ErrDuplicatedKey
+		TranslateError: true,
+		// The connection was checked above
+		DisableAutomaticPing: true,
 	}
 
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB}), gormConfig)
+	if err != nil {
+		sqlDB.Close()
+		return nil, fmt.Errorf("failed to open CockroachDB: %w", err)
+	}
 	return db, nil
 }
 
-// region: ======= CockroachDB Migration =======
-
-func CockroachDBGooseMigrate(db *gorm.DB, baseFS embed.FS, migrationFolder string) error {
+// ResetIdleConns closes the idle connections of db so that the next queries open new
+// ones, e.g. with a rotated password. The connections in use are closed by their maximum
+// lifetime. maxIdle restores the size of the idle pool.
+func ResetIdleConns(db *gorm.DB, maxIdle int) error {
 	sqlDB, err := db.DB()
 	if err != nil {
-		return fmt.Errorf("failed to get sql.DB from GORM: %v", err)
-	}
-	
-	log.Printf("Running CockroachDB migrations using goose\n")
-
-	// Set the base filesystem for migrations
-	goose.SetBaseFS(baseFS)
-	if err := goose.SetDialect("postgres"); err != nil {
-		return fmt.Errorf("CockroachDB migrations: could not set dialect: %v", err)
+		return err
 	}
+	sqlDB.SetMaxIdleConns(0)
+	sqlDB.SetMaxIdleConns(maxIdle)
+	return nil
+}
 
-	// Run migrations
-	if err := goose.Up(sqlDB, migrationFolder); err != nil {
-		return fmt.Errorf("cockroachDB migrations: could not apply migrations: %v", err)
+// queryLogger writes the query logs to cfg.Logger, or to stdout when it is not set
+func queryLogger(cfg CockroachDBConfig) logger.Interface {
+	if cfg.Logger == nil {
+		return logger.Default.LogMode(cfg.LogLevel)
 	}
-	
-	log.Printf("CockroachDB migrations applied successfully")
-	return nil
-}
\ No newline at end of file
+	return NewGormLogger(cfg.Logger, cfg.LogLevel, cfg.LoggerOptions...)
+}
@@ -0,0 +1,59 @@
+package cockroachdb
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewCockroachDB_RetriesConnecting(t *testing.T) {
+	start := time.Now()
+	_, err := NewCockroachDB(context.Background(),
+		// Nothing listens on port 1
+		WithURI("postgresql://root@127.0.0.1:1/defaultdb?sslmode=disable&connect_timeout=1"),
+		WithConnectRetry(3, 10*time.Millisecond, time.Second),
+	)
+
+	assert.ErrorContains(t, err, "failed to connect to CockroachDB: after 3 attempts")
+	// Two waits, the second one doubled
+	assert.GreaterOrEqual(t, time.Since(start), 30*time.Millisecond)
+}
+
+func TestNewCockroachDB_InvalidURI(t *testing.T) {
+	_, err := NewCockroachDB(context.Background(), WithURI("postgresql://root@localhost:26257/db?sslmode=bogus"))
+
+	assert.ErrorContains(t, err, "invalid CockroachDB URI")
+}
+
+func TestNewCockroachDB_PasswordFunc(t *testing.T) {
+	calls := 0
+	_, err := NewCockroachDB(context.Background(),
+		WithURI("postgresql://root@127.0.0.1:1/defaultdb?sslmode=disable&connect_timeout=1"),
+		WithConnectRetry(2, time.Millisecond, time.Second),
+		WithPasswordFunc(func(context.Context) (string, error) {
+			calls++
+			return "", errors.New("secret store unavailable")
+		}),
+	)
+
+	assert.ErrorContains(t, err, "failed to get the database password: secret store unavailable")
+	// Asked again for each connection attempt
+	assert.Equal(t, 2, calls)
+}
+
+func TestNewCockroachDB_StopsRetryingWhenCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	start := time.Now()
+	_, err := NewCockroachDB(ctx,
+		WithURI("postgresql://root@127.0.0.1:1/defaultdb?sslmode=disable&connect_timeout=1"),
+		WithConnectRetry(10, time.Hour, time.Hour),
+	)
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Less(t, time.Since(start), time.Minute)
+}
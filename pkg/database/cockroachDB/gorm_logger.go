@@ -0,0 +1,162 @@
+package cockroachdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"proposal-template/pkg/logger"
+	"proposal-template/pkg/tracing"
+
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// region: ======= GORM logger =======
+
+// DefaultSlowQueryThreshold is the duration above which a query is logged as slow
+var DefaultSlowQueryThreshold = 200 * time.Millisecond
+
+// GormLogger writes the GORM logs to a logger.ILogger, with the request id and trace
+// context of the query context, see tracing.Fields.
+//
+// Failed queries are logged at error from the Error level, queries slower than the slow
+// query threshold at warn from the Warn level and, at the Info level, every other query
+// at debug.
+type GormLogger struct {
+	logger        logger.ILogger
+	level         gormlogger.LogLevel
+	slowThreshold time.Duration
+	// redactParams hides the parameter values of the logged queries, see ParamsFilter
+	redactParams bool
+}
+
+var (
+	_ gormlogger.Interface = (*GormLogger)(nil)
+	_ gorm.ParamsFilter    = (*GormLogger)(nil)
+)
+
+type GormLoggerOption func(*GormLogger)
+
+// WithSlowQueryThreshold sets the duration above which a query is logged at warn, 0 disables it.
+func WithSlowQueryThreshold(threshold time.Duration) GormLoggerOption {
+	return func(l *GormLogger) {
+		l.slowThreshold = threshold
+	}
+}
+
+// WithParamRedaction replaces the parameter values, which may be personal data, with
+// RedactedParam in the logged queries. It is meant for production.
+func WithParamRedaction(redact bool) GormLoggerOption {
+	return func(l *GormLogger) {
+		l.redactParams = redact
+	}
+}
+
+func NewGormLogger(logger logger.ILogger, level gormlogger.LogLevel, opts ...GormLoggerOption) *GormLogger {
+	l := &GormLogger{
+		logger:        logger,
+		level:         level,
+		slowThreshold: DefaultSlowQueryThreshold,
+	}
+
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// ParseGormLogLevel parses silent, error, warn or info.
+func ParseGormLogLevel(level string) (gormlogger.LogLevel, error) {
+	switch strings.ToLower(level) {
+	case "silent":
+		return gormlogger.Silent, nil
+	case "error":
+		return gormlogger.Error, nil
+	case "warn":
+		return gormlogger.Warn, nil
+	case "info":
+		return gormlogger.Info, nil
+	}
+	return 0, fmt.Errorf("invalid database log level %q, expected silent, error, warn or info", level)
+}
+
+// LogMode returns a copy of the logger logging at level.
+func (l *GormLogger) LogMode(level gormlogger.LogLevel) gormlogger.Interface {
+	copy := *l
+	copy.level = level
+	return &copy
+}
+
+func (l *GormLogger) Info(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= gormlogger.Info {
+		l.logger.Info(l.format(ctx, fmt.Sprintf(msg, args...)))
+	}
+}
+
+func (l *GormLogger) Warn(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= gormlogger.Warn {
+		l.logger.Warn(l.format(ctx, fmt.Sprintf(msg, args...)))
+	}
+}
+
+func (l *GormLogger) Error(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= gormlogger.Error {
+		l.logger.Error(l.format(ctx, fmt.Sprintf(msg, args...)))
+	}
+}
+
+// Trace logs a query once it ran. Record not found errors are expected by the repositories,
+// they are not logged as failures.
+func (l *GormLogger) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	if l.level <= gormlogger.Silent {
+		return
+	}
+
+	elapsed := time.Since(begin)
+	switch {
+	case err != nil && !errors.Is(err, gormlogger.ErrRecordNotFound) && l.level >= gormlogger.Error:
+		sql, rows := fc()
+		l.logger.Error(l.format(ctx, fmt.Sprintf("SQL failed: %s %s: %s", querySummary(elapsed, rows), sql, err)))
+	case l.slowThreshold > 0 && elapsed > l.slowThreshold && l.level >= gormlogger.Warn:
+		sql, rows := fc()
+		l.logger.Warn(l.format(ctx, fmt.Sprintf("Slow SQL >= %s: %s %s", l.slowThreshold, querySummary(elapsed, rows), sql)))
+	case l.level >= gormlogger.Info:
+		sql, rows := fc()
+		l.logger.Debug(l.format(ctx, fmt.Sprintf("SQL %s %s", querySummary(elapsed, rows), sql)))
+	}
+}
+
+// RedactedParam replaces the parameter values in the logged queries when they are redacted
+const RedactedParam = "<redacted>"
+
+// ParamsFilter replaces the parameters of the logged query with RedactedParam when they are
+// redacted. It only changes the log, the query itself runs with its parameters.
+func (l *GormLogger) ParamsFilter(_ context.Context, sql string, params ...interface{}) (string, []interface{}) {
+	if !l.redactParams {
+		return sql, params
+	}
+	redacted := make([]interface{}, len(params))
+	for i := range redacted {
+		redacted[i] = RedactedParam
+	}
+	return sql, redacted
+}
+
+// format appends the request id and the trace context of ctx to msg
+func (l *GormLogger) format(ctx context.Context, msg string) string {
+	if fields := tracing.Fields(ctx); fields != "" {
+		return msg + " " + fields
+	}
+	return msg
+}
+
+func querySummary(elapsed time.Duration, rows int64) string {
+	ms := float64(elapsed.Nanoseconds()) / 1e6
+	if rows == -1 {
+		return fmt.Sprintf("[%.3fms] [rows:-]", ms)
+	}
+	return fmt.Sprintf("[%.3fms] [rows:%d]", ms, rows)
+}
@@ -0,0 +1,120 @@
+package cockroachdb
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"proposal-template/pkg/tracing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+type entry struct {
+	level string
+	msg   string
+}
+
+// recordingLogger is a logger.ILogger keeping its entries
+type recordingLogger struct {
+	entries []entry
+}
+
+func (r *recordingLogger) Debug(msg string, _ ...interface{}) { r.add("debug", msg) }
+func (r *recordingLogger) Info(msg string, _ ...interface{})  { r.add("info", msg) }
+func (r *recordingLogger) Warn(msg string, _ ...interface{})  { r.add("warn", msg) }
+func (r *recordingLogger) Error(msg string, _ ...interface{}) { r.add("error", msg) }
+func (r *recordingLogger) GetLevel() string                   { return "debug" }
+func (r *recordingLogger) SetLevel(string)                    {}
+
+func (r *recordingLogger) add(level, msg string) {
+	r.entries = append(r.entries, entry{level: level, msg: msg})
+}
+
+func TestGormLogger_Trace(t *testing.T) {
+	ctx := tracing.WithRequestID(context.Background(), "req-1")
+	ctx = tracing.WithSpanContext(ctx, tracing.SpanContext{TraceID: "t1", SpanID: "s1"})
+	query := func() (string, int64) { return "SELECT 1", 1 }
+
+	tests := map[string]struct {
+		level   gormlogger.LogLevel
+		elapsed time.Duration
+		err     error
+		want    []entry
+	}{
+		"info logs every query at debug": {
+			level: gormlogger.Info,
+			want:  []entry{{"debug", "SQL [Xms] [rows:1] SELECT 1 request_id=req-1 trace_id=t1 span_id=s1"}},
+		},
+		"warn skips fast queries": {
+			level: gormlogger.Warn,
+		},
+		"slow query": {
+			level:   gormlogger.Warn,
+			elapsed: time.Second,
+			want:    []entry{{"warn", "Slow SQL >= 100ms: [Xms] [rows:1] SELECT 1 request_id=req-1 trace_id=t1 span_id=s1"}},
+		},
+		"failed query": {
+			level: gormlogger.Error,
+			err:   errors.New("boom"),
+			want:  []entry{{"error", "SQL failed: [Xms] [rows:1] SELECT 1: boom request_id=req-1 trace_id=t1 span_id=s1"}},
+		},
+		"record not found is not a failure": {
+			level: gormlogger.Error,
+			err:   gorm.ErrRecordNotFound,
+		},
+		"silent": {
+			level:   gormlogger.Silent,
+			elapsed: time.Second,
+			err:     errors.New("boom"),
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			recorder := &recordingLogger{}
+			l := NewGormLogger(recorder, tt.level, WithSlowQueryThreshold(100*time.Millisecond))
+
+			l.Trace(ctx, time.Now().Add(-tt.elapsed), query, tt.err)
+
+			// The measured duration varies, keep it out of the comparison
+			for i := range recorder.entries {
+				recorder.entries[i].msg = duration.ReplaceAllString(recorder.entries[i].msg, "[Xms]")
+			}
+			assert.Equal(t, tt.want, recorder.entries)
+		})
+	}
+}
+
+var duration = regexp.MustCompile(`\[[0-9.]+ms\]`)
+
+func TestGormLogger_RedactsParams(t *testing.T) {
+	for _, redact := range []bool{false, true} {
+		sqlDB, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		recorder := &recordingLogger{}
+		db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB}), &gorm.Config{
+			Logger: NewGormLogger(recorder, gormlogger.Info, WithParamRedaction(redact)),
+		})
+		require.NoError(t, err)
+
+		mock.ExpectExec("UPDATE users").WithArgs("jane@example.com", 1).WillReturnResult(sqlmock.NewResult(0, 1))
+		require.NoError(t, db.Exec("UPDATE users SET email = ? WHERE id = ?", "jane@example.com", 1).Error)
+		require.NoError(t, mock.ExpectationsWereMet())
+
+		require.Len(t, recorder.entries, 1)
+		if redact {
+			assert.Contains(t, recorder.entries[0].msg, "UPDATE users SET email = '<redacted>' WHERE id = '<redacted>'")
+		} else {
+			assert.Contains(t, recorder.entries[0].msg, "UPDATE users SET email = 'jane@example.com' WHERE id = 1")
+		}
+		sqlDB.Close()
+	}
+}
@@ -0,0 +1,307 @@
+package cockroachdb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pressly/goose/v3/lock"
+)
+
+// region: ======= Migration lock =======
+
+// ErrLockTimeout is returned when the lock is still held by another process once the
+// lock timeout elapsed.
+var ErrLockTimeout = errors.New("timed out waiting for the lock")
+
+// ErrLockLost is returned by SessionUnlock when the lease could not be refreshed before
+// it expired, another process may have held the lock at the same time.
+var ErrLockLost = errors.New("lost the lock")
+
+// Rows of the goose_lock table, one per lock
+const (
+	MigrationLockID = 1
+	OutboxLockID    = 2
+)
+
+var _ lock.SessionLocker = (*TableLocker)(nil)
+
+// TableLocker keeps replicas from migrating the database at the same time. CockroachDB
+// does not support the PostgreSQL advisory locks goose uses by default, so the lock is a
+// lease: a row of the goose_lock table naming its owner and when it expires. Other jobs
+// that a single replica may run at a time use their own row, see WithLockID and TryRun.
+//
+// The owner refreshes the lease while it migrates. If it dies the lease expires and
+// another process can take it over. If it cannot refresh the lease before it expires,
+// the lease is lost: the context of the migrations is cancelled when it carries a cancel
+// function, see withLockLostCancel, and SessionUnlock returns ErrLockLost.
+type TableLocker struct {
+	// db is used instead of the session connection, which is busy running the
+	// migrations while the lease is refreshed
+	db    *sql.DB
+	owner string
+	id    int
+	name  string
+	// ttl is how long the lease lasts without being refreshed
+	ttl time.Duration
+	// timeout is how long to wait for the lease held by another process
+	timeout       time.Duration
+	retryInterval time.Duration
+
+	mu           sync.Mutex
+	tableCreated bool
+	stop         chan struct{}
+	done         chan struct{}
+	lost         error
+}
+
+// LockOption configures a TableLocker.
+type LockOption func(*TableLocker)
+
+// WithLockTTL sets how long the lease lasts without being refreshed.
+func WithLockTTL(ttl time.Duration) LockOption {
+	return func(l *TableLocker) {
+		l.ttl = ttl
+	}
+}
+
+// WithLockTimeout sets how long to wait for a lease held by another process.
+func WithLockTimeout(timeout time.Duration) LockOption {
+	return func(l *TableLocker) {
+		l.timeout = timeout
+	}
+}
+
+// WithLockID sets the row of goose_lock holding the lease, and the name of the lock in
+// the errors and logs.
+func WithLockID(id int, name string) LockOption {
+	return func(l *TableLocker) {
+		l.id = id
+		l.name = name
+	}
+}
+
+// WithLockRetryInterval sets how often a lease held by another process is tried again.
+func WithLockRetryInterval(interval time.Duration) LockOption {
+	return func(l *TableLocker) {
+		l.retryInterval = interval
+	}
+}
+
+func NewTableLocker(db *sql.DB, opts ...LockOption) *TableLocker {
+	hostname, _ := os.Hostname()
+	l := &TableLocker{
+		db:            db,
+		owner:         fmt.Sprintf("%s/%s", hostname, uuid.NewString()),
+		id:            MigrationLockID,
+		name:          "migration",
+		ttl:           time.Minute,
+		timeout:       5 * time.Minute,
+		retryInterval: 2 * time.Second,
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+const (
+	createLockTable = `CREATE TABLE IF NOT EXISTS goose_lock (
+	id         INT4 PRIMARY KEY,
+	owner      STRING NOT NULL,
+	expires_at TIMESTAMPTZ NOT NULL
+)`
+	// The row is only taken over once its lease expired, RETURNING yields no row otherwise
+	acquireLock = `INSERT INTO goose_lock (id, owner, expires_at) VALUES ($3, $1, now() + $2 * INTERVAL '1 millisecond')
+ON CONFLICT (id) DO UPDATE SET owner = excluded.owner, expires_at = excluded.expires_at
+WHERE goose_lock.expires_at < now() OR goose_lock.owner = excluded.owner
+RETURNING owner`
+	refreshLock = `UPDATE goose_lock SET expires_at = now() + $2 * INTERVAL '1 millisecond' WHERE id = $3 AND owner = $1`
+	releaseLock = `DELETE FROM goose_lock WHERE id = $2 AND owner = $1`
+)
+
+// SessionLock waits for the lease, up to the lock timeout, then refreshes it in the
+// background until SessionUnlock.
+func (l *TableLocker) SessionLock(ctx context.Context, _ *sql.Conn) error {
+	if err := l.createTable(ctx); err != nil {
+		return err
+	}
+
+	deadline := time.Now().Add(l.timeout)
+	for {
+		acquired, err := l.tryLock(ctx)
+		if err != nil {
+			return err
+		}
+		if acquired {
+			break
+		}
+		if time.Now().Add(l.retryInterval).After(deadline) {
+			return fmt.Errorf("%s lock: %w", l.name, ErrLockTimeout)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(l.retryInterval):
+		}
+	}
+
+	l.startHeartbeat(ctx)
+	return nil
+}
+
+// SessionUnlock stops refreshing the lease and releases it. It returns ErrLockLost when
+// the lease was lost in the meantime.
+func (l *TableLocker) SessionUnlock(ctx context.Context, _ *sql.Conn) error {
+	l.mu.Lock()
+	if l.stop != nil {
+		close(l.stop)
+		<-l.done
+		l.stop, l.done = nil, nil
+	}
+	lost := l.lost
+	l.mu.Unlock()
+
+	if _, err := l.db.ExecContext(ctx, releaseLock, l.owner, l.id); err != nil {
+		return errors.Join(lost, fmt.Errorf("failed to release the %s lock: %w", l.name, err))
+	}
+	return lost
+}
+
+// TryRun runs fn holding the lease, unless another process holds it, and reports whether
+// fn ran. The context of fn is cancelled when the lease is lost, the returned error then
+// wraps ErrLockLost.
+func (l *TableLocker) TryRun(ctx context.Context, fn func(ctx context.Context) error) (ran bool, err error) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	ctx = withLockLostCancel(ctx, cancel)
+
+	if err := l.createTable(ctx); err != nil {
+		return false, err
+	}
+	acquired, err := l.tryLock(ctx)
+	if err != nil || !acquired {
+		return false, err
+	}
+
+	l.startHeartbeat(ctx)
+	defer func() {
+		err = errors.Join(err, l.SessionUnlock(context.WithoutCancel(ctx), nil))
+	}()
+	return true, fn(ctx)
+}
+
+// createTable creates the goose_lock table the first time the lock is taken
+func (l *TableLocker) createTable(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.tableCreated {
+		return nil
+	}
+	if _, err := l.db.ExecContext(ctx, createLockTable); err != nil {
+		return fmt.Errorf("failed to create the %s lock table: %w", l.name, err)
+	}
+	l.tableCreated = true
+	return nil
+}
+
+func (l *TableLocker) tryLock(ctx context.Context) (bool, error) {
+	var owner string
+	err := l.db.QueryRowContext(ctx, acquireLock, l.owner, l.ttl.Milliseconds(), l.id).Scan(&owner)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to acquire the %s lock: %w", l.name, err)
+	}
+	return true, nil
+}
+
+// startHeartbeat refreshes the lease until SessionUnlock, calling the lock lost cancel
+// function of ctx if it is lost
+func (l *TableLocker) startHeartbeat(ctx context.Context) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.stop, l.done, l.lost = make(chan struct{}), make(chan struct{}), nil
+	go l.heartbeat(l.stop, l.done, lockLostCancel(ctx))
+}
+
+// heartbeat refreshes the lease three times per ttl so that it does not expire while
+// the migrations run. The lease is lost when another process took it over, or when it
+// could not be refreshed for a whole ttl; cancel is then called.
+func (l *TableLocker) heartbeat(stop <-chan struct{}, done chan<- struct{}, cancel context.CancelCauseFunc) {
+	defer close(done)
+
+	ticker := time.NewTicker(l.ttl / 3)
+	defer ticker.Stop()
+	refreshed := time.Now()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			err := l.refresh()
+			if err == nil {
+				refreshed = time.Now()
+				continue
+			}
+			if !errors.Is(err, errLeaseTakenOver) && time.Since(refreshed) < l.ttl {
+				log.Printf("Failed to refresh the %s lock: %s", l.name, err)
+				continue
+			}
+
+			err = fmt.Errorf("%w: %w", ErrLockLost, err)
+			log.Printf("Lost the %s lock, cancelling its holder: %s", l.name, err)
+			l.mu.Lock()
+			l.lost = err
+			l.mu.Unlock()
+			if cancel != nil {
+				cancel(err)
+			}
+			return
+		}
+	}
+}
+
+// errLeaseTakenOver is returned by refresh when another process owns the lease
+var errLeaseTakenOver = errors.New("the lease expired and was taken over")
+
+// refresh extends the lease
+func (l *TableLocker) refresh() error {
+	ctx, cancel := context.WithTimeout(context.Background(), l.ttl/3)
+	defer cancel()
+
+	result, err := l.db.ExecContext(ctx, refreshLock, l.owner, l.ttl.Milliseconds(), l.id)
+	if err != nil {
+		return err
+	}
+	refreshed, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if refreshed == 0 {
+		return errLeaseTakenOver
+	}
+	return nil
+}
+
+type lockLostCancelKey struct{}
+
+// withLockLostCancel returns a context making the TableLocker locking with it call cancel
+// when it loses the lease
+func withLockLostCancel(ctx context.Context, cancel context.CancelCauseFunc) context.Context {
+	return context.WithValue(ctx, lockLostCancelKey{}, cancel)
+}
+
+func lockLostCancel(ctx context.Context) context.CancelCauseFunc {
+	cancel, _ := ctx.Value(lockLostCancelKey{}).(context.CancelCauseFunc)
+	return cancel
+}
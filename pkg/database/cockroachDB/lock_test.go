@@ -0,0 +1,153 @@
+package cockroachdb
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newMockLocker(t *testing.T, opts ...LockOption) (*TableLocker, sqlmock.Sqlmock) {
+	t.Helper()
+
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	return NewTableLocker(sqlDB, opts...), mock
+}
+
+func TestTableLocker_LockAndUnlock(t *testing.T) {
+	locker, mock := newMockLocker(t, WithLockTTL(time.Hour))
+	mock.ExpectExec(regexp.QuoteMeta(createLockTable)).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta(acquireLock)).
+		WithArgs(locker.owner, time.Hour.Milliseconds(), MigrationLockID).
+		WillReturnRows(sqlmock.NewRows([]string{"owner"}).AddRow(locker.owner))
+	mock.ExpectExec(regexp.QuoteMeta(releaseLock)).WithArgs(locker.owner, MigrationLockID).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	require.NoError(t, locker.SessionLock(context.Background(), nil))
+	require.NoError(t, locker.SessionUnlock(context.Background(), nil))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTableLocker_WaitsForTheLease(t *testing.T) {
+	locker, mock := newMockLocker(t, WithLockTTL(time.Hour), WithLockRetryInterval(time.Millisecond))
+	mock.ExpectExec(regexp.QuoteMeta(createLockTable)).WillReturnResult(sqlmock.NewResult(0, 0))
+	// Held by another replica, then expired
+	mock.ExpectQuery(regexp.QuoteMeta(acquireLock)).WillReturnRows(sqlmock.NewRows([]string{"owner"}))
+	mock.ExpectQuery(regexp.QuoteMeta(acquireLock)).WillReturnRows(sqlmock.NewRows([]string{"owner"}).AddRow(locker.owner))
+
+	require.NoError(t, locker.SessionLock(context.Background(), nil))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTableLocker_Timeout(t *testing.T) {
+	locker, mock := newMockLocker(t, WithLockTimeout(0))
+	mock.ExpectExec(regexp.QuoteMeta(createLockTable)).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta(acquireLock)).WillReturnRows(sqlmock.NewRows([]string{"owner"}))
+
+	assert.ErrorIs(t, locker.SessionLock(context.Background(), nil), ErrLockTimeout)
+}
+
+func TestTableLocker_RefreshesTheLease(t *testing.T) {
+	locker, mock := newMockLocker(t, WithLockTTL(30*time.Millisecond))
+	mock.ExpectExec(regexp.QuoteMeta(createLockTable)).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta(acquireLock)).WillReturnRows(sqlmock.NewRows([]string{"owner"}).AddRow(locker.owner))
+	mock.ExpectExec(regexp.QuoteMeta(refreshLock)).WithArgs(locker.owner, int64(30), MigrationLockID).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(releaseLock)).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	require.NoError(t, locker.SessionLock(context.Background(), nil))
+	time.Sleep(25 * time.Millisecond)
+	require.NoError(t, locker.SessionUnlock(context.Background(), nil))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTableLocker_LosesTheLease(t *testing.T) {
+	tests := map[string]func(mock sqlmock.Sqlmock){
+		"taken over": func(mock sqlmock.Sqlmock) {
+			mock.ExpectExec(regexp.QuoteMeta(refreshLock)).WillReturnResult(sqlmock.NewResult(0, 0))
+		},
+		"refresh failing for a whole ttl": func(mock sqlmock.Sqlmock) {
+			for i := 0; i < 3; i++ {
+				mock.ExpectExec(regexp.QuoteMeta(refreshLock)).WillReturnError(assert.AnError)
+			}
+		},
+	}
+
+	for name, expectRefresh := range tests {
+		t.Run(name, func(t *testing.T) {
+			locker, mock := newMockLocker(t, WithLockTTL(30*time.Millisecond))
+			mock.ExpectExec(regexp.QuoteMeta(createLockTable)).WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectQuery(regexp.QuoteMeta(acquireLock)).WillReturnRows(sqlmock.NewRows([]string{"owner"}).AddRow(locker.owner))
+			expectRefresh(mock)
+			mock.ExpectExec(regexp.QuoteMeta(releaseLock)).WillReturnResult(sqlmock.NewResult(0, 0))
+
+			ctx, cancel := migrationContext(context.Background())
+			defer cancel(nil)
+			require.NoError(t, locker.SessionLock(ctx, nil))
+
+			// The migrations are cancelled
+			select {
+			case <-ctx.Done():
+				assert.ErrorIs(t, context.Cause(ctx), ErrLockLost)
+			case <-time.After(time.Second):
+				t.Fatal("the migrations were not cancelled")
+			}
+
+			assert.ErrorIs(t, locker.SessionUnlock(context.Background(), nil), ErrLockLost)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestTableLocker_TryRun(t *testing.T) {
+	locker, mock := newMockLocker(t, WithLockTTL(time.Hour), WithLockID(OutboxLockID, "outbox relay"))
+	mock.ExpectExec(regexp.QuoteMeta(createLockTable)).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta(acquireLock)).
+		WithArgs(locker.owner, time.Hour.Milliseconds(), OutboxLockID).
+		WillReturnRows(sqlmock.NewRows([]string{"owner"}).AddRow(locker.owner))
+	mock.ExpectExec(regexp.QuoteMeta(releaseLock)).WithArgs(locker.owner, OutboxLockID).WillReturnResult(sqlmock.NewResult(0, 1))
+	// Held by another replica, the table is only created once
+	mock.ExpectQuery(regexp.QuoteMeta(acquireLock)).WillReturnRows(sqlmock.NewRows([]string{"owner"}))
+
+	runs := 0
+	run := func(context.Context) error {
+		runs++
+		return nil
+	}
+
+	ran, err := locker.TryRun(context.Background(), run)
+	require.NoError(t, err)
+	assert.True(t, ran)
+
+	ran, err = locker.TryRun(context.Background(), run)
+	require.NoError(t, err)
+	assert.False(t, ran)
+
+	assert.Equal(t, 1, runs)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateMigration(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "00001_init.sql"), nil, 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "00007_add_index.sql"), nil, 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "README.md"), nil, 0o644))
+
+	path, err := CreateMigration(dir, "Add Orders table")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "00008_add_orders_table.sql"), path)
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "-- +goose Up")
+
+	_, err = CreateMigration(dir, "drop; table")
+	assert.ErrorContains(t, err, "invalid migration name")
+}
@@ -1,9 +1,179 @@
 package cockroachdb
 
-
 import (
+	"context"
 	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pressly/goose/v3"
+	"gorm.io/gorm"
 )
 
 //go:embed migrations/*.sql
-var CockroachDBMigrateFS embed.FS
\ No newline at end of file
+var CockroachDBMigrateFS embed.FS
+
+// MigrationsDir is the source directory of CockroachDBMigrateFS, relative to the module root
+const MigrationsDir = "pkg/database/cockroachDB/migrations"
+
+// region: ======= Migrator =======
+
+// Migrator applies, rolls back and reports the goose migrations of the database. The
+// operations changing the schema hold a TableLocker so that replicas starting together
+// do not migrate at the same time.
+type Migrator struct {
+	provider *goose.Provider
+	// unlocked runs the operations made of several steps, which hold locker themselves
+	unlocked *goose.Provider
+	locker   *TableLocker
+}
+
+// NewMigrator returns a Migrator for the migrations of fsys, CockroachDBMigrateFS when nil.
+func NewMigrator(db *gorm.DB, fsys fs.FS, opts ...LockOption) (*Migrator, error) {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sql.DB from GORM: %w", err)
+	}
+	if fsys == nil {
+		if fsys, err = fs.Sub(CockroachDBMigrateFS, "migrations"); err != nil {
+			return nil, err
+		}
+	}
+
+	locker := NewTableLocker(sqlDB, opts...)
+	provider, err := goose.NewProvider(goose.DialectPostgres, sqlDB, fsys, goose.WithSessionLocker(locker))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the migrations: %w", err)
+	}
+	unlocked, err := goose.NewProvider(goose.DialectPostgres, sqlDB, fsys)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the migrations: %w", err)
+	}
+	return &Migrator{provider: provider, unlocked: unlocked, locker: locker}, nil
+}
+
+// Up applies the pending migrations, up to version when it is greater than 0.
+func (m *Migrator) Up(ctx context.Context, version int64) ([]*goose.MigrationResult, error) {
+	ctx, cancel := migrationContext(ctx)
+	defer cancel(nil)
+
+	if version > 0 {
+		return m.provider.UpTo(ctx, version)
+	}
+	return m.provider.Up(ctx)
+}
+
+// Down rolls back the last applied migration or, when version is 0 or more, every
+// migration newer than version. Passing 0 rolls back all of them.
+func (m *Migrator) Down(ctx context.Context, version int64) ([]*goose.MigrationResult, error) {
+	ctx, cancel := migrationContext(ctx)
+	defer cancel(nil)
+
+	if version >= 0 {
+		return m.provider.DownTo(ctx, version)
+	}
+	result, err := m.provider.Down(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return []*goose.MigrationResult{result}, nil
+}
+
+// Redo rolls back the last applied migration and applies it again. The lock is held
+// across both steps so that no other process applies the version in between.
+func (m *Migrator) Redo(ctx context.Context) (results []*goose.MigrationResult, err error) {
+	ctx, cancel := migrationContext(ctx)
+	defer cancel(nil)
+
+	if err := m.locker.SessionLock(ctx, nil); err != nil {
+		return nil, err
+	}
+	defer func() {
+		err = errors.Join(err, m.locker.SessionUnlock(context.WithoutCancel(ctx), nil))
+	}()
+
+	down, err := m.unlocked.Down(ctx)
+	if err != nil {
+		return nil, err
+	}
+	up, err := m.unlocked.ApplyVersion(ctx, down.Source.Version, true)
+	if err != nil {
+		return []*goose.MigrationResult{down}, err
+	}
+	return []*goose.MigrationResult{down, up}, nil
+}
+
+// Status returns every known migration, in version order, with whether it was applied.
+func (m *Migrator) Status(ctx context.Context) ([]*goose.MigrationStatus, error) {
+	return m.provider.Status(ctx)
+}
+
+// Version returns the version of the last applied migration, 0 when none was.
+func (m *Migrator) Version(ctx context.Context) (int64, error) {
+	return m.provider.GetDBVersion(ctx)
+}
+
+// migrationContext returns the context of the migrations, cancelled when the TableLocker
+// loses its lease so that they stop rather than run along another process
+func migrationContext(ctx context.Context) (context.Context, context.CancelCauseFunc) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	return withLockLostCancel(ctx, cancel), cancel
+}
+
+// region: ======= Migration files =======
+
+var (
+	migrationName     = regexp.MustCompile(`^[a-z0-9_]+$`)
+	migrationTemplate = `-- +goose Up
+-- +goose StatementBegin
+SELECT 'up SQL query';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 'down SQL query';
+-- +goose StatementEnd
+`
+)
+
+// CreateMigration writes an empty SQL migration in dir, numbered after the last one like
+// the existing files (00006_name.sql), and returns its path. The name is snake cased.
+func CreateMigration(dir, name string) (string, error) {
+	name = strings.ToLower(strings.Join(strings.Fields(name), "_"))
+	if !migrationName.MatchString(name) {
+		return "", fmt.Errorf("invalid migration name %q: only letters, digits and underscores are allowed", name)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to read the migrations directory: %w", err)
+	}
+	var last int64
+	for _, entry := range entries {
+		prefix, _, found := strings.Cut(entry.Name(), "_")
+		if !found || entry.IsDir() || filepath.Ext(entry.Name()) != ".sql" {
+			continue
+		}
+		if version, err := strconv.ParseInt(prefix, 10, 64); err == nil && version > last {
+			last = version
+		}
+	}
+
+	path := filepath.Join(dir, fmt.Sprintf("%05d_%s.sql", last+1, name))
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return "", fmt.Errorf("failed to create the migration: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.WriteString(migrationTemplate); err != nil {
+		return "", fmt.Errorf("failed to write the migration: %w", err)
+	}
+	return path, nil
+}
@@ -0,0 +1,6 @@
+-- +goose Up
+-- Supports the default keyset pagination order of GenericDAO.List
+CREATE INDEX IF NOT EXISTS users_created_at_id_idx ON users (created_at DESC, id DESC);
+
+-- +goose Down
+DROP INDEX IF EXISTS users@users_created_at_id_idx;
@@ -0,0 +1,6 @@
+-- +goose Up
+-- Row version used for optimistic concurrency control, see model.Versioned
+ALTER TABLE users ADD COLUMN IF NOT EXISTS version INT8 NOT NULL DEFAULT 1;
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN IF EXISTS version;
@@ -0,0 +1,21 @@
+-- +goose NO TRANSACTION
+-- CockroachDB cannot index a column added in the same transaction
+
+-- +goose Up
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+
+-- Soft-deleted users no longer hold their email address
+DROP INDEX IF EXISTS users@users_email_key CASCADE;
+CREATE UNIQUE INDEX IF NOT EXISTS users_email_key ON users (email) WHERE deleted_at IS NULL;
+
+-- Used by the purge job
+CREATE INDEX IF NOT EXISTS users_deleted_at_idx ON users (deleted_at) WHERE deleted_at IS NOT NULL;
+
+-- +goose Down
+-- The soft-deleted users are purged first: their email may be held by a live user again,
+-- which the unique constraint would reject halfway through this non-transactional rollback
+DELETE FROM users WHERE deleted_at IS NOT NULL;
+DROP INDEX IF EXISTS users@users_deleted_at_idx;
+DROP INDEX IF EXISTS users@users_email_key CASCADE;
+ALTER TABLE users ADD CONSTRAINT users_email_key UNIQUE (email);
+ALTER TABLE users DROP COLUMN IF EXISTS deleted_at;
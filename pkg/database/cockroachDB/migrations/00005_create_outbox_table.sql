@@ -0,0 +1,22 @@
+-- +goose Up
+-- Events written in the transaction of the entity they describe, then published to Kafka
+-- by the outbox relay
+CREATE TABLE IF NOT EXISTS outbox (
+    id              INT8 PRIMARY KEY DEFAULT unique_rowid(),
+    aggregate_type  STRING NOT NULL,
+    aggregate_id    STRING NOT NULL,
+    event_type      STRING NOT NULL,
+    payload         JSONB NOT NULL,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+    attempts        INT4 NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_error      STRING,
+    -- Set once the relay published the message, or gave up on it
+    published_at    TIMESTAMPTZ,
+    failed_at       TIMESTAMPTZ,
+    INDEX outbox_pending_idx (created_at, id) WHERE published_at IS NULL AND failed_at IS NULL,
+    INDEX outbox_published_at_idx (published_at) WHERE published_at IS NOT NULL
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS outbox;
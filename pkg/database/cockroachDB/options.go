@@ -1,6 +1,13 @@
 package cockroachdb
 
-import "proposal-template/pkg/logger"
+import (
+	"context"
+	"time"
+
+	"proposal-template/pkg/logger"
+
+	gormlogger "gorm.io/gorm/logger"
+)
 
 // CockroachDBConfig holds database configuration options
 type CockroachDBConfig struct {
@@ -8,7 +15,21 @@ type CockroachDBConfig struct {
 	MaxOpenConns          int
 	MaxIdleConns          int
 	ConnMaxLifetimeInSecs int
-	Logger                logger.ILogger
+	// Idle connections are closed after this many seconds, 0 keeps them
+	ConnMaxIdleTimeInSecs int
+	// StatementTimeout makes the database cancel longer statements, 0 disables it
+	StatementTimeout time.Duration
+	// ConnectAttempts is the number of connection attempts, ConnectRetryInterval the wait
+	// after the first failure, doubled after each one up to ConnectMaxRetryInterval
+	ConnectAttempts         int
+	ConnectRetryInterval    time.Duration
+	ConnectMaxRetryInterval time.Duration
+	// PasswordFunc returns the password of each new connection, overriding the one of URI
+	PasswordFunc PasswordFunc
+	Logger       logger.ILogger
+	// LogLevel of the queries, written to Logger through a GormLogger
+	LogLevel      gormlogger.LogLevel
+	LoggerOptions []GormLoggerOption
 }
 // Option represents a functional option for CockroachDB configuration
 type Option func(*CockroachDBConfig)
@@ -41,8 +62,56 @@ func WithConnMaxLifetime(seconds int) Option {
 	}
 }
 
+// WithConnMaxIdleTime sets how long a connection can stay idle before being closed
+func WithConnMaxIdleTime(seconds int) Option {
+	return func(c *CockroachDBConfig) {
+		c.ConnMaxIdleTimeInSecs = seconds
+	}
+}
+
+// WithStatementTimeout sets the statement_timeout of the connections, 0 disables it
+func WithStatementTimeout(timeout time.Duration) Option {
+	return func(c *CockroachDBConfig) {
+		c.StatementTimeout = timeout
+	}
+}
+
+// WithConnectRetry sets how often connecting is attempted while the database is starting,
+// and the bounds of the exponential wait between the attempts
+func WithConnectRetry(attempts int, interval time.Duration, maxInterval time.Duration) Option {
+	return func(c *CockroachDBConfig) {
+		c.ConnectAttempts = attempts
+		c.ConnectRetryInterval = interval
+		c.ConnectMaxRetryInterval = maxInterval
+	}
+}
+
+// PasswordFunc returns the current password of the database user
+type PasswordFunc func(ctx context.Context) (string, error)
+
+// WithPasswordFunc makes every new connection authenticate with the password returned by
+// fn, so that a rotated password is used without restarting. See ResetIdleConns.
+func WithPasswordFunc(fn PasswordFunc) Option {
+	return func(c *CockroachDBConfig) {
+		c.PasswordFunc = fn
+	}
+}
+
 func WithLogger(customLogger logger.ILogger) Option {
 	return func(c *CockroachDBConfig) {
 		c.Logger = customLogger
 	}
-}
\ No newline at end of file
+}
+// WithLogLevel sets the level of the query logs, gormlogger.Warn by default
+func WithLogLevel(level gormlogger.LogLevel) Option {
+	return func(c *CockroachDBConfig) {
+		c.LogLevel = level
+	}
+}
+
+// WithGormLogger configures the GormLogger writing the query logs, e.g. WithSlowQueryThreshold
+func WithGormLogger(opts ...GormLoggerOption) Option {
+	return func(c *CockroachDBConfig) {
+		c.LoggerOptions = append(c.LoggerOptions, opts...)
+	}
+}
@@ -0,0 +1,158 @@
+package cockroachdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+)
+
+// region: ======= Transaction retry =======
+
+// SerializationFailureCode is the SQLSTATE CockroachDB returns when a transaction must
+// be retried because of contention.
+const SerializationFailureCode = "40001"
+
+// RetryPolicy describes how often and how fast a failed transaction is retried.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, the first one included
+	MaxAttempts int
+	// InitialBackoff is the wait before the first retry, doubled on every retry
+	InitialBackoff time.Duration
+	// MaxBackoff caps the wait between two attempts
+	MaxBackoff time.Duration
+}
+
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    5,
+	InitialBackoff: 50 * time.Millisecond,
+	MaxBackoff:     2 * time.Second,
+}
+
+// backoff returns the wait before the given retry (1 for the first one), a random
+// duration up to the capped exponential delay ("full jitter") so that contending
+// clients do not retry in lockstep.
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	delay := p.InitialBackoff
+	for i := 1; i < retry && delay < p.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > p.MaxBackoff {
+		delay = p.MaxBackoff
+	}
+	if delay <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(delay) + 1))
+}
+
+// IsRetryable reports whether err is a CockroachDB serialization failure, meaning the
+// whole transaction can be run again.
+func IsRetryable(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == SerializationFailureCode
+}
+
+// RetryMetrics counts the transactions run by a TxRunner. It is safe for concurrent use
+// and can be shared between runners.
+type RetryMetrics struct {
+	transactions atomic.Int64
+	retries      atomic.Int64
+	exhausted    atomic.Int64
+}
+
+// RetryStats is a point in time copy of RetryMetrics.
+type RetryStats struct {
+	// Transactions is the number of RunInTx calls
+	Transactions int64
+	// Retries is the number of attempts made after a serialization failure
+	Retries int64
+	// Exhausted is the number of transactions that still failed after the last attempt
+	Exhausted int64
+}
+
+func (m *RetryMetrics) Snapshot() RetryStats {
+	return RetryStats{
+		Transactions: m.transactions.Load(),
+		Retries:      m.retries.Load(),
+		Exhausted:    m.exhausted.Load(),
+	}
+}
+
+// TxRunner runs transactions and retries them on serialization failures. Each attempt
+// runs in a new transaction, fn must therefore only have side effects through tx.
+type TxRunner struct {
+	db      *gorm.DB
+	policy  RetryPolicy
+	metrics *RetryMetrics
+}
+
+// TxRunnerOption represents a functional option for TxRunner configuration
+type TxRunnerOption func(*TxRunner)
+
+func NewTxRunner(db *gorm.DB, opts ...TxRunnerOption) *TxRunner {
+	runner := &TxRunner{
+		db:      db,
+		policy:  DefaultRetryPolicy,
+		metrics: &RetryMetrics{},
+	}
+
+	for _, opt := range opts {
+		opt(runner)
+	}
+	if runner.policy.MaxAttempts < 1 {
+		runner.policy.MaxAttempts = 1
+	}
+	return runner
+}
+
+// WithRetryPolicy sets the retry policy, DefaultRetryPolicy is used otherwise
+func WithRetryPolicy(policy RetryPolicy) TxRunnerOption {
+	return func(r *TxRunner) {
+		r.policy = policy
+	}
+}
+
+// WithRetryMetrics makes the runner record its retries in metrics
+func WithRetryMetrics(metrics *RetryMetrics) TxRunnerOption {
+	return func(r *TxRunner) {
+		r.metrics = metrics
+	}
+}
+
+// Metrics returns the counters of the runner.
+func (r *TxRunner) Metrics() *RetryMetrics {
+	return r.metrics
+}
+
+// RunInTx runs fn in a transaction, committed when fn returns nil. When fn or the commit
+// fails with a serialization failure, the transaction is rolled back and run again after
+// a backoff, until the policy's attempts are used up or ctx is done.
+func (r *TxRunner) RunInTx(ctx context.Context, fn func(tx *gorm.DB) error) error {
+	r.metrics.transactions.Add(1)
+
+	for attempt := 1; ; attempt++ {
+		err := r.db.WithContext(ctx).Transaction(fn)
+		if err == nil || !IsRetryable(err) {
+			return err
+		}
+		if attempt >= r.policy.MaxAttempts {
+			r.metrics.exhausted.Add(1)
+			return fmt.Errorf("transaction failed after %d attempts: %w", attempt, err)
+		}
+
+		timer := time.NewTimer(r.policy.backoff(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("%w: %w", ctx.Err(), err)
+		case <-timer.C:
+		}
+		r.metrics.retries.Add(1)
+	}
+}
@@ -0,0 +1,137 @@
+package cockroachdb
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+var errRestart = &pgconn.PgError{Code: SerializationFailureCode, Message: "restart transaction"}
+
+func newMockRunner(t *testing.T, policy RetryPolicy) (*TxRunner, sqlmock.Sqlmock) {
+	t.Helper()
+
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB}), &gorm.Config{
+		TranslateError: true,
+		Logger:         logger.Default.LogMode(logger.Silent),
+	})
+	require.NoError(t, err)
+	return NewTxRunner(db, WithRetryPolicy(policy)), mock
+}
+
+var fastPolicy = RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond}
+
+func TestIsRetryable(t *testing.T) {
+	assert.True(t, IsRetryable(errRestart))
+	assert.True(t, IsRetryable(errors.Join(errors.New("commit"), errRestart)))
+	assert.False(t, IsRetryable(&pgconn.PgError{Code: "23505"}))
+	assert.False(t, IsRetryable(errors.New("boom")))
+}
+
+func TestRetryPolicy_BackoffIsCapped(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 10, InitialBackoff: 10 * time.Millisecond, MaxBackoff: 50 * time.Millisecond}
+
+	for retry := 1; retry <= 10; retry++ {
+		assert.LessOrEqual(t, policy.backoff(retry), 50*time.Millisecond)
+	}
+}
+
+func TestTxRunner_RetriesSerializationFailures(t *testing.T) {
+	runner, mock := newMockRunner(t, fastPolicy)
+
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+	mock.ExpectBegin()
+	mock.ExpectCommit()
+
+	calls := 0
+	err := runner.RunInTx(context.Background(), func(tx *gorm.DB) error {
+		calls++
+		if calls == 1 {
+			return errRestart
+		}
+		return nil
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, 2, calls)
+	assert.Equal(t, RetryStats{Transactions: 1, Retries: 1}, runner.Metrics().Snapshot())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTxRunner_RetriesFailedCommit(t *testing.T) {
+	runner, mock := newMockRunner(t, fastPolicy)
+
+	mock.ExpectBegin()
+	mock.ExpectCommit().WillReturnError(errRestart)
+	mock.ExpectBegin()
+	mock.ExpectCommit()
+
+	err := runner.RunInTx(context.Background(), func(tx *gorm.DB) error { return nil })
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), runner.Metrics().Snapshot().Retries)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTxRunner_DoesNotRetryOtherErrors(t *testing.T) {
+	runner, mock := newMockRunner(t, fastPolicy)
+	boom := errors.New("boom")
+
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+
+	calls := 0
+	err := runner.RunInTx(context.Background(), func(tx *gorm.DB) error {
+		calls++
+		return boom
+	})
+
+	assert.ErrorIs(t, err, boom)
+	assert.Equal(t, 1, calls)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTxRunner_GivesUpAfterMaxAttempts(t *testing.T) {
+	runner, mock := newMockRunner(t, fastPolicy)
+
+	for i := 0; i < fastPolicy.MaxAttempts; i++ {
+		mock.ExpectBegin()
+		mock.ExpectRollback()
+	}
+
+	err := runner.RunInTx(context.Background(), func(tx *gorm.DB) error { return errRestart })
+
+	assert.True(t, IsRetryable(err))
+	assert.Equal(t, RetryStats{Transactions: 1, Retries: 2, Exhausted: 1}, runner.Metrics().Snapshot())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTxRunner_StopsWhenContextIsDone(t *testing.T) {
+	runner, mock := newMockRunner(t, RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Hour, MaxBackoff: time.Hour})
+	ctx, cancel := context.WithCancel(context.Background())
+
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+
+	err := runner.RunInTx(ctx, func(tx *gorm.DB) error {
+		cancel()
+		return errRestart
+	})
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,48 @@
+// Package features tells whether the optional features of the service are enabled. The
+// flags are set from FEATURE_FLAGS and follow its reloads.
+package features
+
+import (
+	"sort"
+	"strings"
+	"sync/atomic"
+)
+
+// Flags is the set of enabled features, safe for concurrent use.
+type Flags struct {
+	enabled atomic.Pointer[map[string]bool]
+}
+
+// New returns the flags enabled in list, a comma-separated list of feature names.
+func New(list string) *Flags {
+	f := &Flags{}
+	f.Set(list)
+	return f
+}
+
+// Enabled reports whether the feature name is enabled.
+func (f *Flags) Enabled(name string) bool {
+	return (*f.enabled.Load())[name]
+}
+
+// Set replaces the enabled features with those of list, a comma-separated list.
+func (f *Flags) Set(list string) {
+	enabled := map[string]bool{}
+	for _, name := range strings.Split(list, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			enabled[name] = true
+		}
+	}
+	f.enabled.Store(&enabled)
+}
+
+// List returns the enabled features, sorted.
+func (f *Flags) List() []string {
+	enabled := *f.enabled.Load()
+	names := make([]string, 0, len(enabled))
+	for name := range enabled {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,22 @@
+package features
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFlags(t *testing.T) {
+	flags := New(" search, export,,")
+	assert.True(t, flags.Enabled("search"))
+	assert.True(t, flags.Enabled("export"))
+	assert.False(t, flags.Enabled("beta"))
+	assert.Equal(t, []string{"export", "search"}, flags.List())
+
+	flags.Set("beta")
+	assert.False(t, flags.Enabled("search"))
+	assert.True(t, flags.Enabled("beta"))
+
+	flags.Set("")
+	assert.Empty(t, flags.List())
+}
@@ -9,6 +9,13 @@ type Publisher interface {
 	SendMessage(ctx context.Context, value interface{}) error
 }
 
+// KeyedPublisher is implemented by publishers that can set the key of a message. Kafka sends
+// messages with the same key to the same partition, so consumers receive them in order.
+type KeyedPublisher interface {
+	Publisher
+	SendKeyedMessage(ctx context.Context, key string, value interface{}) error
+}
+
 type Subscriber interface {
 	SubscribeToTopic(ctx context.Context) error
 	ConsumeMessages(ctx context.Context, msgTypeConf func() ConsumerMessage) (chMsg <-chan ConsumerMessage, chErr <-chan error, chCommitRequest chan<- bool)
@@ -6,7 +6,6 @@ import (
 	"fmt"
 	"log"
 	"proposal-template/pkg/utils"
-	config "proposal-template/pkg/utils/config"
 	"os"
 	"time"
 
@@ -24,7 +23,7 @@ type kafkaPublisher struct {
 }
 
 
-var _ Publisher = (*kafkaPublisher)(nil)
+var _ KeyedPublisher = (*kafkaPublisher)(nil)
 
 func NewKafkaPublisher(producer *kafka.Producer, sr *SchemaRegistry, schemaID int, topic string) (*kafkaPublisher, error) {
 	serde, err := avrov2.NewSerializer(sr.client, serde.ValueSerde, &avrov2.SerializerConfig{
@@ -42,6 +41,15 @@ func NewKafkaPublisher(producer *kafka.Producer, sr *SchemaRegistry, schemaID in
 }
 
 func (s *kafkaPublisher) SendMessage(ctx context.Context, value interface{}) error {
+	return s.send(ctx, nil, value)
+}
+
+// SendKeyedMessage sends value with the given key, see KeyedPublisher.
+func (s *kafkaPublisher) SendKeyedMessage(ctx context.Context, key string, value interface{}) error {
+	return s.send(ctx, []byte(key), value)
+}
+
+func (s *kafkaPublisher) send(ctx context.Context, key []byte, value interface{}) error {
 	deliveryChan := make(chan kafka.Event)
 
 	payload, err := s.serde.Serialize(s.topic, &value)
@@ -51,6 +59,7 @@ func (s *kafkaPublisher) SendMessage(ctx context.Context, value interface{}) err
 
 	err = s.producer.Produce(&kafka.Message{
 		TopicPartition: kafka.TopicPartition{Topic: &s.topic, Partition: kafka.PartitionAny},
+		Key:            key,
 		Value:          payload,
 	}, deliveryChan)
 	if err != nil {
@@ -282,8 +291,6 @@ func CreateTopicIfNotExist(adminClient *kafka.AdminClient, topicName string, num
 // KAFKA_CONSUMER_GROUP_ID=mygroup
 // KAFKA_SCHEMA_REGISTRY_URL=http://localhost:8081
 
-var _ config.IConfig = (*KafkaProducerConfig)(nil)
-
 
 func NewKafkaProducer(opts ...Option) (*kafka.Producer, error) {
 	producerConfig := DefaultConfig.Producer
@@ -294,13 +301,15 @@ func NewKafkaProducer(opts ...Option) (*kafka.Producer, error) {
 	for _, opt := range opts {
 		opt(&producerConfig, &consumerConfig, &schemaConfig)
 	}
-	return kafka.NewProducer(&kafka.ConfigMap{
+	configMap := kafka.ConfigMap{
 		// "bootstrap.servers": cfg.Brokers,
 		// "client.id":         cfg.ClientID,
 		"bootstrap.servers": producerConfig.Brokers,
 		"client.id":         producerConfig.ClientID,
 
-	})
+	}
+	producerConfig.SASL.configure(configMap)
+	return kafka.NewProducer(&configMap)
 	// return p, nil
 }
 
@@ -309,8 +318,6 @@ func NewAdminClientFromProducer(producer *kafka.Producer) (*kafka.AdminClient, e
 }
 
 
-var _ config.IConfig = (*KafkaConsumerConfig)(nil)
-
 /*
 USAGE EXAMPLE:
 - DEFAULT DECLARATION
@@ -338,8 +345,7 @@ func NewKafkaConsumer(opts ...Option) (*kafka.Consumer, error) {
 		opt(&producerConfig, &consumerConfig, &schemaConfig)
 	}
 
-	c, err := utils.Retry(10, 1*time.Second, func() (*kafka.Consumer, error) {
-		return kafka.NewConsumer(&kafka.ConfigMap{
+	configMap := kafka.ConfigMap{
 		"bootstrap.servers":     consumerConfig.Brokers,
 		"group.id":              consumerConfig.GroupID,
 		"auto.offset.reset":     consumerConfig.AutoOffsetReset,
@@ -350,7 +356,11 @@ func NewKafkaConsumer(opts ...Option) (*kafka.Consumer, error) {
 		"retry.backoff.ms":      consumerConfig.RetryBackoffMs,
 		"fetch.min.bytes":       consumerConfig.FetchMinBytes,
 		"fetch.wait.max.ms":     consumerConfig.FetchWaitMaxMs,
-	})
+	}
+	consumerConfig.SASL.configure(configMap)
+
+	c, err := utils.Retry(10, 1*time.Second, func() (*kafka.Consumer, error) {
+		return kafka.NewConsumer(&configMap)
 	})
 	if err != nil {
 		log.Printf("Failed to create kafka consumer: %s", err)
@@ -0,0 +1,59 @@
+package kafka
+
+import (
+	"context"
+	"sync"
+)
+
+// region:      ======= in-memory publisher =======
+
+// MemoryMessage is a message sent to a MemoryPublisher.
+type MemoryMessage struct {
+	Key   string
+	Value interface{}
+}
+
+// MemoryPublisher keeps the messages it is sent in memory instead of producing them to
+// Kafka. Tests use it in place of the Kafka publisher, SetError makes it fail.
+type MemoryPublisher struct {
+	mu       sync.Mutex
+	messages []MemoryMessage
+	err      error
+}
+
+var _ KeyedPublisher = (*MemoryPublisher)(nil)
+
+func NewMemoryPublisher() *MemoryPublisher {
+	return &MemoryPublisher{}
+}
+
+func (p *MemoryPublisher) SendMessage(ctx context.Context, value interface{}) error {
+	return p.SendKeyedMessage(ctx, "", value)
+}
+
+func (p *MemoryPublisher) SendKeyedMessage(_ context.Context, key string, value interface{}) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.err != nil {
+		return p.err
+	}
+	p.messages = append(p.messages, MemoryMessage{Key: key, Value: value})
+	return nil
+}
+
+// SetError makes the following sends fail with err, until it is called with nil.
+func (p *MemoryPublisher) SetError(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.err = err
+}
+
+// Messages returns a copy of the messages sent so far, in order.
+func (p *MemoryPublisher) Messages() []MemoryMessage {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]MemoryMessage(nil), p.messages...)
+}
+
+// endregion:   ======= in-memory publisher =======
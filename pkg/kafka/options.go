@@ -1,54 +1,60 @@
 package kafka
 
 import (
-	"log"
-	"proposal-template/pkg/utils/config"
+	"strings"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
 )
 
-// KafkaProducerConfig holds Kafka producer settings
+// KafkaProducerConfig holds Kafka producer settings, filled from AppConfig.Kafka
 type KafkaProducerConfig struct {
-	Brokers  string `env:"KAFKA_BROKERS,required"`
-	ClientID string `env:"KAFKA_CLIENT_ID" envDefault:"default-client"`
-}
-
-var _ utils.IConfig = (*KafkaProducerConfig)(nil)
-
-func (c *KafkaProducerConfig) Load() error {
-	log.Printf("Loading KafkaProducerConfig")
-	return utils.ParseConfig(c)
+	Brokers  string
+	ClientID string
+	SASL     SASLConfig
 }
 
-// KafkaConsumerConfig holds Kafka consumer settings
+// KafkaConsumerConfig holds Kafka consumer settings, filled from AppConfig.Kafka
 type KafkaConsumerConfig struct {
-	Brokers            string `env:"KAFKA_BROKERS,required"`
-	GroupID            string `env:"KAFKA_CONSUMER_GROUP_ID" envDefault:"default-group"`
-	AutoOffsetReset    string `env:"KAFKA_CONSUMER_AUTO_OFFSET_RESET" envDefault:"earliest"`
-	EnableAutoCommit   bool   `env:"KAFKA_CONSUMER_ENABLE_AUTO_COMMIT" envDefault:"false"`
-	MaxPollIntervalMs  int    `env:"KAFKA_CONSUMER_MAX_POLL_INTERVAL_MS" envDefault:"300000"`
-	SessionTimeoutMs   int    `env:"KAFKA_CONSUMER_SESSION_TIMEOUT_MS" envDefault:"45000"`
-	HeartbeatIntervalMs int   `env:"KAFKA_CONSUMER_HEARTBEAT_INTERVAL_MS" envDefault:"3000"`
-	RetryBackoffMs     int    `env:"KAFKA_CONSUMER_RETRY_BACKOFF_MS" envDefault:"100"`
-	FetchMinBytes      int    `env:"KAFKA_CONSUMER_FETCH_MIN_BYTES" envDefault:"1"`
-	FetchWaitMaxMs     int    `env:"KAFKA_CONSUMER_FETCH_WAIT_MAX_MS" envDefault:"500"`
-}
-
-var _ utils.IConfig = (*KafkaConsumerConfig)(nil)
-
-func (c *KafkaConsumerConfig) Load() error {
-	log.Printf("Loading KafkaConsumerConfig")
-	return utils.ParseConfig(c)
+	Brokers             string
+	GroupID             string
+	AutoOffsetReset     string
+	EnableAutoCommit    bool
+	MaxPollIntervalMs   int
+	SessionTimeoutMs    int
+	HeartbeatIntervalMs int
+	RetryBackoffMs      int
+	FetchMinBytes       int
+	FetchWaitMaxMs      int
+	SASL                SASLConfig
+}
+
+// SASLConfig holds the security settings of the connections to the brokers
+type SASLConfig struct {
+	// plaintext, ssl, sasl_plaintext or sasl_ssl, the librdkafka default when empty
+	SecurityProtocol string
+	// PLAIN, SCRAM-SHA-256 or SCRAM-SHA-512
+	Mechanism string
+	Username  string
+	Password  string
+}
+
+// configure sets the security settings in m
+func (c SASLConfig) configure(m kafka.ConfigMap) {
+	if c.SecurityProtocol == "" {
+		return
+	}
+	m["security.protocol"] = c.SecurityProtocol
+	if !strings.HasPrefix(c.SecurityProtocol, "sasl_") {
+		return
+	}
+	m["sasl.mechanisms"] = c.Mechanism
+	m["sasl.username"] = c.Username
+	m["sasl.password"] = c.Password
 }
 
-// SchemaRegistryConfig holds Schema Registry settings
+// SchemaRegistryConfig holds Schema Registry settings, filled from AppConfig.Kafka
 type SchemaRegistryConfig struct {
-	URL string `env:"KAFKA_SCHEMA_REGISTRY_URL,required"`
-}
-
-var _ utils.IConfig = (*SchemaRegistryConfig)(nil)
-
-func (c *SchemaRegistryConfig) Load() error {
-	log.Printf("Loading SchemaRegistryConfig")
-	return utils.ParseConfig(c)
+	URL string
 }
 
 // DefaultConfig holds the default Kafka settings
@@ -103,6 +109,15 @@ func WithConsumerGroupID(groupID string) Option {
 	}
 }
 
+// WithSASL sets the security settings of the producer and the consumer. Rotated
+// credentials are applied with SetSaslCredentials, without recreating the client.
+func WithSASL(sasl SASLConfig) Option {
+	return func(p *KafkaProducerConfig, c *KafkaConsumerConfig, _ *SchemaRegistryConfig) {
+		p.SASL = sasl
+		c.SASL = sasl
+	}
+}
+
 // WithSchemaRegistryURL sets the schema registry URL
 func WithSchemaRegistryURL(url string) Option {
 	return func(_ *KafkaProducerConfig, _ *KafkaConsumerConfig, s *SchemaRegistryConfig) {
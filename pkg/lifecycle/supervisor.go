@@ -0,0 +1,190 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"proposal-template/pkg/logger"
+)
+
+// Runnable is a long-running component (HTTP/gRPC server, Kafka consumer, cron worker...)
+// whose lifecycle is managed by a Supervisor.
+type Runnable interface {
+	// Start runs the component and blocks until it stops or fails.
+	// ctx is cancelled when the supervisor begins shutting down.
+	Start(ctx context.Context) error
+	// Stop asks the component to release its resources so that Start returns.
+	// ctx carries the shutdown grace period.
+	Stop(ctx context.Context) error
+}
+
+// ShutdownHook releases a resource shared by the components, such as a database pool.
+type ShutdownHook func(ctx context.Context) error
+
+var DefaultShutdownTimeout = 15 * time.Second
+
+// DefaultHookTimeout is the time given to each shutdown hook, on top of the shutdown
+// timeout of the components.
+var DefaultHookTimeout = 10 * time.Second
+
+type component struct {
+	name     string
+	runnable Runnable
+}
+
+type hook struct {
+	name string
+	fn   ShutdownHook
+}
+
+// Supervisor runs any number of Runnables concurrently. When one of them fails, or the
+// context passed to Run is cancelled, every other component is stopped and the shutdown
+// hooks are executed. All errors are collected and returned joined.
+type Supervisor struct {
+	components      []component
+	hooks           []hook
+	shutdownTimeout time.Duration
+	hookTimeout     time.Duration
+	logger          logger.ILogger
+}
+
+type Option func(*Supervisor)
+
+func NewSupervisor(logger logger.ILogger, opts ...Option) *Supervisor {
+	s := &Supervisor{
+		shutdownTimeout: DefaultShutdownTimeout,
+		hookTimeout:     DefaultHookTimeout,
+		logger:          logger,
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Register adds a component to be started by Run. Components are stopped in reverse
+// registration order.
+func (s *Supervisor) Register(name string, r Runnable) {
+	s.components = append(s.components, component{name: name, runnable: r})
+}
+
+// OnShutdown adds a hook executed once every component has stopped. Hooks run in reverse
+// registration order, so resources should be registered in dependency order. Each hook
+// gets its own hook timeout, whatever time the components took to stop.
+func (s *Supervisor) OnShutdown(name string, fn ShutdownHook) {
+	s.hooks = append(s.hooks, hook{name: name, fn: fn})
+}
+
+// Run starts every registered component and blocks until one of them fails, all of them
+// return, or ctx is cancelled. It then stops the remaining components within the shutdown
+// timeout and runs the shutdown hooks, each within the hook timeout.
+func (s *Supervisor) Run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		index int
+		err   error
+	}
+	// Buffered so that components never block on reporting, whoever is listening
+	results := make(chan result, len(s.components))
+	running := make(map[int]bool, len(s.components))
+
+	for i, c := range s.components {
+		running[i] = true
+		s.logger.Info(fmt.Sprintf("Starting component: %s", c.name))
+		go func(i int, c component) {
+			results <- result{index: i, err: c.runnable.Start(ctx)}
+		}(i, c)
+	}
+
+	var errs []error
+	collect := func(r result) {
+		delete(running, r.index)
+		name := s.components[r.index].name
+		if r.err != nil {
+			s.logger.Error(fmt.Sprintf("Component %s failed: %s", name, r.err))
+			errs = append(errs, fmt.Errorf("%s: %w", name, r.err))
+			return
+		}
+		s.logger.Info(fmt.Sprintf("Component %s stopped", name))
+	}
+
+wait:
+	for len(running) > 0 {
+		select {
+		case <-ctx.Done():
+			s.logger.Info("Shutdown requested")
+			break wait
+		case r := <-results:
+			collect(r)
+			if r.err != nil {
+				break wait
+			}
+		}
+	}
+	cancel()
+
+	stopCtx, stopCancel := context.WithTimeout(context.Background(), s.shutdownTimeout)
+	defer stopCancel()
+
+	for i := len(s.components) - 1; i >= 0; i-- {
+		if !running[i] {
+			continue
+		}
+		c := s.components[i]
+		s.logger.Info(fmt.Sprintf("Stopping component: %s", c.name))
+		if err := c.runnable.Stop(stopCtx); err != nil {
+			errs = append(errs, fmt.Errorf("stop %s: %w", c.name, err))
+		}
+	}
+
+drain:
+	for len(running) > 0 {
+		select {
+		case r := <-results:
+			collect(r)
+		case <-stopCtx.Done():
+			for i := range running {
+				errs = append(errs, fmt.Errorf("%s: did not stop within %s", s.components[i].name, s.shutdownTimeout))
+			}
+			break drain
+		}
+	}
+
+	for i := len(s.hooks) - 1; i >= 0; i-- {
+		h := s.hooks[i]
+		s.logger.Info(fmt.Sprintf("Releasing resource: %s", h.name))
+		if err := s.runHook(h); err != nil {
+			errs = append(errs, fmt.Errorf("shutdown %s: %w", h.name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (s *Supervisor) runHook(h hook) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.hookTimeout)
+	defer cancel()
+	return h.fn(ctx)
+}
+
+// === Optional configuration ===
+func WithShutdownTimeout(timeout time.Duration) Option {
+	return func(s *Supervisor) {
+		if timeout > 0 {
+			s.shutdownTimeout = timeout
+		}
+	}
+}
+
+func WithHookTimeout(timeout time.Duration) Option {
+	return func(s *Supervisor) {
+		if timeout > 0 {
+			s.hookTimeout = timeout
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type nopLogger struct{}
+
+func (nopLogger) Debug(string, ...interface{}) {}
+func (nopLogger) Info(string, ...interface{})  {}
+func (nopLogger) Warn(string, ...interface{})  {}
+func (nopLogger) Error(string, ...interface{}) {}
+func (nopLogger) GetLevel() string             { return "debug" }
+func (nopLogger) SetLevel(string)              {}
+
+// blockingRunnable runs until Stop is called or its context is cancelled.
+type blockingRunnable struct {
+	stopOnce sync.Once
+	stopped  chan struct{}
+	stopErr  error
+	order    *[]string
+	name     string
+	mu       *sync.Mutex
+}
+
+func newBlocking(name string, order *[]string, mu *sync.Mutex) *blockingRunnable {
+	return &blockingRunnable{stopped: make(chan struct{}), order: order, name: name, mu: mu}
+}
+
+func (b *blockingRunnable) Start(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+	case <-b.stopped:
+	}
+	return nil
+}
+
+func (b *blockingRunnable) Stop(ctx context.Context) error {
+	b.mu.Lock()
+	*b.order = append(*b.order, b.name)
+	b.mu.Unlock()
+	b.stopOnce.Do(func() { close(b.stopped) })
+	return b.stopErr
+}
+
+type failingRunnable struct{ err error }
+
+func (f failingRunnable) Start(context.Context) error { return f.err }
+func (f failingRunnable) Stop(context.Context) error  { return nil }
+
+func TestSupervisor_FailureStopsOthersAndJoinsErrors(t *testing.T) {
+	var (
+		order []string
+		mu    sync.Mutex
+	)
+	startErr := errors.New("bind: address already in use")
+	stopErr := errors.New("flush failed")
+
+	http := newBlocking("http", &order, &mu)
+	worker := newBlocking("worker", &order, &mu)
+	worker.stopErr = stopErr
+
+	s := NewSupervisor(nopLogger{}, WithShutdownTimeout(time.Second))
+	s.Register("http", http)
+	s.Register("worker", worker)
+	s.Register("grpc", failingRunnable{err: startErr})
+	s.Register("grpc-2", failingRunnable{err: startErr})
+	s.OnShutdown("database", func(context.Context) error {
+		mu.Lock()
+		order = append(order, "database")
+		mu.Unlock()
+		return nil
+	})
+	s.OnShutdown("schema registry", func(context.Context) error {
+		mu.Lock()
+		order = append(order, "schema registry")
+		mu.Unlock()
+		return nil
+	})
+
+	err := s.Run(context.Background())
+
+	require.Error(t, err)
+	assert.ErrorIs(t, err, startErr)
+	assert.ErrorIs(t, err, stopErr)
+	assert.Equal(t, []string{"worker", "http", "schema registry", "database"}, order)
+}
+
+func TestSupervisor_ContextCancellationIsGraceful(t *testing.T) {
+	var (
+		order []string
+		mu    sync.Mutex
+	)
+	s := NewSupervisor(nopLogger{})
+	s.Register("http", newBlocking("http", &order, &mu))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- s.Run(ctx) }()
+	cancel()
+
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("supervisor did not return after cancellation")
+	}
+	assert.Equal(t, []string{"http"}, order)
+}
+
+type stuckRunnable struct{}
+
+func (stuckRunnable) Start(context.Context) error { select {} }
+func (stuckRunnable) Stop(context.Context) error  { return nil }
+
+func TestSupervisor_ReportsComponentsThatDoNotStop(t *testing.T) {
+	s := NewSupervisor(nopLogger{}, WithShutdownTimeout(50*time.Millisecond))
+	s.Register("stuck", stuckRunnable{})
+	s.Register("broken", failingRunnable{err: errors.New("boom")})
+
+	err := s.Run(context.Background())
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "stuck: did not stop within")
+}
+
+func TestSupervisor_HooksHaveTheirOwnTimeout(t *testing.T) {
+	s := NewSupervisor(nopLogger{}, WithShutdownTimeout(50*time.Millisecond), WithHookTimeout(time.Second))
+	s.Register("stuck", stuckRunnable{})
+	s.Register("broken", failingRunnable{err: errors.New("boom")})
+
+	var remaining time.Duration
+	s.OnShutdown("kafka producer", func(ctx context.Context) error {
+		// A producer flushes its queued messages for the time left
+		deadline, _ := ctx.Deadline()
+		remaining = time.Until(deadline)
+		return ctx.Err()
+	})
+
+	err := s.Run(context.Background())
+
+	require.Error(t, err)
+	assert.NotContains(t, err.Error(), "shutdown kafka producer")
+	assert.Greater(t, remaining, 500*time.Millisecond)
+}
@@ -12,25 +12,12 @@ import (
 // zapLogger implements ILogger using Zap.
 type zapLogger struct {
     logger   *zap.Logger
-    logLevel zapcore.Level
+    logLevel zap.AtomicLevel
 }
 
 // NewZapLogger initializes and returns a new Zap logger with a configurable level.
 func NewZapLogger(level string) *zapLogger {
-    // Map string level to Zap's zapcore.Level
-    var logLevel zapcore.Level
-    switch level {
-    case "debug":
-        logLevel = zapcore.DebugLevel
-    case "info":
-        logLevel = zapcore.InfoLevel
-    case "warn":
-        logLevel = zapcore.WarnLevel
-    case "error":
-        logLevel = zapcore.ErrorLevel
-    default:
-        logLevel = zapcore.InfoLevel // Default to info level
-    }
+    logLevel := zap.NewAtomicLevelAt(parseLevel(level))
     encoderConfig := zapcore.EncoderConfig{
         TimeKey:        "time",
         LevelKey:       "level",
@@ -55,7 +42,7 @@ func NewZapLogger(level string) *zapLogger {
 
     cfg := zap.Config{
         Encoding:         "console",                      // Switch to console encoding
-        Level:            zap.NewAtomicLevelAt(logLevel),
+        Level:            logLevel,
         OutputPaths:      []string{"stdout"},             // Log to console (stdout)
         ErrorOutputPaths: []string{"stderr"},             // Error logs to stderr
         // OutputPaths:      []string{"stdout", logFilePath}, // uncomment this to save log file
@@ -93,7 +80,7 @@ func (z *zapLogger) Warn(msg string, fields ...interface{}) {
 // Debug logs a message at the DEBUG level with the given fields, if the log level is
 // DEBUG or lower.
 func (z *zapLogger) Debug(msg string, fields ...interface{}) {
-    if z.logLevel <= zapcore.DebugLevel {
+    if z.logLevel.Enabled(zapcore.DebugLevel) {
         z.logger.Debug(msg, toZapFields(fields...)...)
     }
 }
@@ -106,7 +93,28 @@ func (z *zapLogger) Error(msg string, fields ...interface{}) {
 // GetLevel returns the string representation of the current log level.
 // This is useful for logging and debugging.
 func (z *zapLogger) GetLevel() string {
-    return z.logLevel.String()
+    return z.logLevel.Level().String()
+}
+
+// SetLevel changes the log level at runtime, the entries being written are not affected.
+func (z *zapLogger) SetLevel(level string) {
+    z.logLevel.SetLevel(parseLevel(level))
+}
+
+// parseLevel maps a string level to Zap's zapcore.Level
+func parseLevel(level string) zapcore.Level {
+    switch level {
+    case "debug":
+        return zapcore.DebugLevel
+    case "info":
+        return zapcore.InfoLevel
+    case "warn":
+        return zapcore.WarnLevel
+    case "error":
+        return zapcore.ErrorLevel
+    default:
+        return zapcore.InfoLevel // Default to info level
+    }
 }
 
 // Capture stack trace as Zap field
@@ -9,6 +9,8 @@ type ILogger interface {
     Warn(msg string, fields ...interface{})
     Error(msg string, fields ...interface{})
     GetLevel() string
+    // SetLevel changes the level at runtime: debug, info, warn or error
+    SetLevel(level string)
 }
 
 func NewLogger(level string) ILogger {
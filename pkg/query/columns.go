@@ -0,0 +1,87 @@
+package query
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// Column describes a model column. Only columns tagged `db:"name"` are known, and only
+// those also tagged `query:"filter"` and/or `query:"sort"` may be used in a Spec:
+//
+//	Email string `db:"email" query:"filter,sort"`
+type Column struct {
+	Name       string
+	Filterable bool
+	Sortable   bool
+	// Type is the Go type of the field, used to convert query string values
+	Type reflect.Type
+	// index is the path of the field in the model, see reflect.Value.FieldByIndex
+	index []int
+}
+
+// Columns is the whitelist of a model's columns keyed by column name.
+type Columns map[string]Column
+
+// Has reports whether name is a column of the model.
+func (c Columns) Has(name string) bool {
+	_, ok := c[name]
+	return ok
+}
+
+// ValueOf returns the value of column name in obj, a pointer to the model.
+func (c Columns) ValueOf(obj interface{}, name string) (interface{}, bool) {
+	col, ok := c[name]
+	if !ok {
+		return nil, false
+	}
+	v := reflect.Indirect(reflect.ValueOf(obj))
+	return v.FieldByIndex(col.index).Interface(), true
+}
+
+var columnsCache sync.Map // reflect.Type -> Columns
+
+// ColumnsOf returns the column whitelist of T, read from its struct tags including those
+// of embedded structs such as model.BaseModel.
+func ColumnsOf[T any]() Columns {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	if cached, ok := columnsCache.Load(t); ok {
+		return cached.(Columns)
+	}
+
+	columns := make(Columns)
+	collectColumns(t, nil, columns)
+	columnsCache.Store(t, columns)
+	return columns
+}
+
+func collectColumns(t reflect.Type, index []int, columns Columns) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		fieldIndex := append(append([]int{}, index...), i)
+
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			collectColumns(field.Type, fieldIndex, columns)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+
+		name, _, _ := strings.Cut(field.Tag.Get("db"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+
+		col := Column{Name: name, Type: field.Type, index: fieldIndex}
+		for _, opt := range strings.Split(field.Tag.Get("query"), ",") {
+			switch strings.TrimSpace(opt) {
+			case "filter":
+				col.Filterable = true
+			case "sort":
+				col.Sortable = true
+			}
+		}
+		columns[name] = col
+	}
+}
@@ -0,0 +1,110 @@
+package query
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// ErrInvalidCursor is returned for cursor tokens that are malformed, were not signed by
+// this service or do not match the requested sort order. It wraps ErrInvalidSpec.
+var ErrInvalidCursor = fmt.Errorf("%w: invalid cursor", ErrInvalidSpec)
+
+// Cursor is the position of the last row of a page in a keyset ordered by Sort and then by
+// primary key. Values holds the row's sort column value followed by its primary key, both
+// formatted with FormatValue.
+type Cursor struct {
+	Sort   Sort     `json:"s"`
+	Values []string `json:"v"`
+}
+
+// CursorCodec turns cursors into opaque tokens signed with HMAC-SHA256, so clients cannot
+// forge a position or tamper with the sort order.
+type CursorCodec struct {
+	secret []byte
+}
+
+// MinCursorSecretSize is the least size of a configured secret, and the size of the random
+// ones
+const MinCursorSecretSize = 32
+
+func NewCursorCodec(secret []byte) *CursorCodec {
+	return &CursorCodec{secret: secret}
+}
+
+// NewRandomCursorCodec returns a codec with a random secret. Its tokens are only valid for
+// the lifetime of the process, so replicas must share a configured secret instead.
+func NewRandomCursorCodec() *CursorCodec {
+	secret := make([]byte, MinCursorSecretSize)
+	if _, err := rand.Read(secret); err != nil {
+		panic(fmt.Sprintf("failed to generate cursor secret: %s", err))
+	}
+	return NewCursorCodec(secret)
+}
+
+func (c *CursorCodec) Encode(cursor Cursor) (string, error) {
+	payload, err := json.Marshal(cursor)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode cursor: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(payload) + "." +
+		base64.RawURLEncoding.EncodeToString(c.sign(payload)), nil
+}
+
+func (c *CursorCodec) Decode(token string) (Cursor, error) {
+	encodedPayload, encodedSig, ok := strings.Cut(token, ".")
+	if !ok {
+		return Cursor{}, ErrInvalidCursor
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encodedPayload)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(encodedSig)
+	if err != nil || !hmac.Equal(sig, c.sign(payload)) {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	var cursor Cursor
+	decoder := json.NewDecoder(bytes.NewReader(payload))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&cursor); err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+	return cursor, nil
+}
+
+func (c *CursorCodec) sign(payload []byte) []byte {
+	mac := hmac.New(sha256.New, c.secret)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+// FormatValue formats a column value so that ParseValue can restore it losslessly.
+func FormatValue(v interface{}) (string, error) {
+	switch value := v.(type) {
+	case time.Time:
+		return value.UTC().Format(time.RFC3339Nano), nil
+	case encoding.TextMarshaler:
+		text, err := value.MarshalText()
+		return string(text), err
+	case nil:
+		return "", errors.New("cannot format a NULL value")
+	}
+	return fmt.Sprint(v), nil
+}
+
+// ParseValue parses raw into a value of type t. Times are RFC 3339, types implementing
+// encoding.TextUnmarshaler (uuid.UUID...) use it, and scalars use strconv.
+func ParseValue(t reflect.Type, raw string) (interface{}, error) {
+	return convert(Column{Type: t}, raw)
+}
@@ -0,0 +1,202 @@
+package query
+
+import (
+	"encoding"
+	"fmt"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	filterParam = "filter"
+	sortParam   = "sort"
+	orGroupKey  = "or"
+)
+
+// ParseFor parses an HTTP query string into a Spec validated against T's column whitelist.
+// See Parse for the supported syntax.
+func ParseFor[T any](values url.Values) (Spec, error) {
+	return Parse(values, ColumnsOf[T]())
+}
+
+// Parse turns HTTP query parameters into a Spec. The supported syntax is
+//
+//	filter[email]=a@b.c                  equality
+//	filter[email][like]=foo              any Operator
+//	filter[id][in]=1,2,3                 comma separated list
+//	filter[deleted_at][is_null]=true
+//	filter[or][name][like]=jo&filter[or][email][like]=jo
+//	                                     conditions under [or] form one OR group
+//	sort=-created_at,name                "-" sorts descending
+//
+// Top-level conditions are AND-ed. Values are converted to the Go type of the column.
+func Parse(values url.Values, columns Columns) (Spec, error) {
+	spec := Spec{Filter: Filter{Logic: And}}
+	orGroup := Filter{Logic: Or}
+
+	// Sort keys so that the resulting spec, and the SQL built from it, are deterministic
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if !strings.HasPrefix(key, filterParam+"[") {
+			continue
+		}
+		path, err := splitBrackets(strings.TrimPrefix(key, filterParam))
+		if err != nil {
+			return Spec{}, err
+		}
+
+		target := &spec.Filter
+		if path[0] == orGroupKey {
+			target = &orGroup
+			path = path[1:]
+		}
+		if len(path) < 1 || len(path) > 2 {
+			return Spec{}, fmt.Errorf("%w: malformed filter %q", ErrInvalidSpec, key)
+		}
+
+		field, op := path[0], OpEq
+		if len(path) == 2 {
+			op = Operator(path[1])
+		}
+		col, ok := columns[field]
+		if !ok || !col.Filterable {
+			return Spec{}, fmt.Errorf("%w: cannot filter on %q", ErrInvalidSpec, field)
+		}
+
+		for _, raw := range values[key] {
+			value, err := parseValue(col, op, raw)
+			if err != nil {
+				return Spec{}, err
+			}
+			target.Conditions = append(target.Conditions, Condition{Field: field, Op: op, Value: value})
+		}
+	}
+	if len(orGroup.Conditions) > 0 {
+		spec.Filter.Groups = append(spec.Filter.Groups, orGroup)
+	}
+
+	if raw := values.Get(sortParam); raw != "" {
+		for _, field := range strings.Split(raw, ",") {
+			field = strings.TrimSpace(field)
+			s := Sort{Field: strings.TrimPrefix(field, "-"), Desc: strings.HasPrefix(field, "-")}
+			spec.Sort = append(spec.Sort, s)
+		}
+	}
+
+	if err := columns.Validate(spec); err != nil {
+		return Spec{}, err
+	}
+	return spec, nil
+}
+
+// splitBrackets splits "[a][b]" into ["a", "b"].
+func splitBrackets(s string) ([]string, error) {
+	if !strings.HasPrefix(s, "[") || !strings.HasSuffix(s, "]") {
+		return nil, fmt.Errorf("%w: malformed filter key %q", ErrInvalidSpec, s)
+	}
+	parts := strings.Split(s[1:len(s)-1], "][")
+	for _, p := range parts {
+		if p == "" || strings.ContainsAny(p, "[]") {
+			return nil, fmt.Errorf("%w: malformed filter key %q", ErrInvalidSpec, s)
+		}
+	}
+	return parts, nil
+}
+
+func parseValue(col Column, op Operator, raw string) (interface{}, error) {
+	switch op {
+	case OpIsNull:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q is_null expects a boolean", ErrInvalidSpec, col.Name)
+		}
+		return b, nil
+	case OpLike:
+		return raw, nil
+	case OpIn:
+		items := strings.Split(raw, ",")
+		list := make([]interface{}, 0, len(items))
+		for _, item := range items {
+			v, err := convert(col, strings.TrimSpace(item))
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+		return list, nil
+	}
+	return convert(col, raw)
+}
+
+var (
+	timeType            = reflect.TypeOf(time.Time{})
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// convert parses raw into the Go type of col so that the database receives typed values.
+func convert(col Column, raw string) (interface{}, error) {
+	t := col.Type
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	invalid := func() error {
+		if col.Name == "" {
+			return fmt.Errorf("%w: invalid %s value %q", ErrInvalidSpec, t, raw)
+		}
+		return fmt.Errorf("%w: invalid value %q for %q", ErrInvalidSpec, raw, col.Name)
+	}
+
+	switch {
+	case t == timeType:
+		v, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return nil, invalid()
+		}
+		return v, nil
+	case reflect.PointerTo(t).Implements(textUnmarshalerType):
+		v := reflect.New(t)
+		if err := v.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(raw)); err != nil {
+			return nil, invalid()
+		}
+		return v.Elem().Interface(), nil
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return raw, nil
+	case reflect.Bool:
+		v, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, invalid()
+		}
+		return v, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return nil, invalid()
+		}
+		return v, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		v, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			return nil, invalid()
+		}
+		return v, nil
+	case reflect.Float32, reflect.Float64:
+		v, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, invalid()
+		}
+		return v, nil
+	}
+	return raw, nil
+}
@@ -0,0 +1,94 @@
+package query
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type account struct {
+	ID        uuid.UUID  `db:"id" query:"filter"`
+	Email     string     `db:"email" query:"filter,sort"`
+	Age       int        `db:"age" query:"filter,sort"`
+	Verified  bool       `db:"verified" query:"filter"`
+	CreatedAt time.Time  `db:"created_at" query:"filter,sort"`
+	DeletedAt *time.Time `db:"deleted_at" query:"filter"`
+	Password  string     `db:"password"`
+	internal  string
+}
+
+func TestColumnsOf(t *testing.T) {
+	columns := ColumnsOf[account]()
+
+	assert.True(t, columns["email"].Filterable)
+	assert.True(t, columns["email"].Sortable)
+	assert.False(t, columns["verified"].Sortable)
+	assert.True(t, columns.Has("password"))
+	assert.False(t, columns["password"].Filterable)
+	assert.Len(t, columns, 7)
+}
+
+func TestParse(t *testing.T) {
+	id := uuid.New()
+	values, err := url.ParseQuery(
+		"filter[email][like]=foo" +
+			"&filter[age][gte]=18&filter[age][lt]=65" +
+			"&filter[verified]=true" +
+			"&filter[id][in]=" + id.String() +
+			"&filter[deleted_at][is_null]=true" +
+			"&filter[or][email][eq]=a@b.c&filter[or][age][eq]=7" +
+			"&sort=-created_at,email" +
+			"&page=2")
+	require.NoError(t, err)
+
+	spec, err := ParseFor[account](values)
+
+	require.NoError(t, err)
+	assert.Equal(t, And, spec.Filter.Logic)
+	assert.ElementsMatch(t, []Condition{
+		{Field: "age", Op: OpGte, Value: int64(18)},
+		{Field: "age", Op: OpLt, Value: int64(65)},
+		{Field: "deleted_at", Op: OpIsNull, Value: true},
+		{Field: "email", Op: OpLike, Value: "foo"},
+		{Field: "id", Op: OpIn, Value: []interface{}{id}},
+		{Field: "verified", Op: OpEq, Value: true},
+	}, spec.Filter.Conditions)
+	require.Len(t, spec.Filter.Groups, 1)
+	assert.Equal(t, Or, spec.Filter.Groups[0].Logic)
+	assert.ElementsMatch(t, []Condition{
+		{Field: "email", Op: OpEq, Value: "a@b.c"},
+		{Field: "age", Op: OpEq, Value: int64(7)},
+	}, spec.Filter.Groups[0].Conditions)
+	assert.Equal(t, []Sort{{Field: "created_at", Desc: true}, {Field: "email"}}, spec.Sort)
+}
+
+func TestParse_Rejects(t *testing.T) {
+	cases := map[string]string{
+		"unknown column":          "filter[name]=x",
+		"column not filterable":   "filter[password]=x",
+		"sql in column name":      "filter[email = email OR 1=1 --]=x",
+		"unknown operator":        "filter[email][regex]=x",
+		"invalid typed value":     "filter[age][gt]=old",
+		"invalid uuid":            "filter[id]=not-a-uuid",
+		"column not sortable":     "sort=verified",
+		"sql in sort":             "sort=email%3BDROP TABLE users",
+		"malformed brackets":      "filter[email=x",
+		"too many path segments":  "filter[email][eq][x]=y",
+		"is_null without boolean": "filter[deleted_at][is_null]=maybe",
+	}
+
+	for name, raw := range cases {
+		t.Run(name, func(t *testing.T) {
+			values, err := url.ParseQuery(raw)
+			require.NoError(t, err)
+
+			_, err = ParseFor[account](values)
+
+			assert.ErrorIs(t, err, ErrInvalidSpec)
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package query
+
+import "context"
+
+// DeletedScope tells which rows of soft-deletable tables reads return.
+type DeletedScope int
+
+const (
+	// ExcludeDeleted is the default scope, soft-deleted rows are hidden
+	ExcludeDeleted DeletedScope = iota
+	// IncludeDeleted returns live and soft-deleted rows
+	IncludeDeleted
+	// DeletedOnly returns soft-deleted rows only
+	DeletedOnly
+)
+
+type deletedScopeKey struct{}
+
+// WithDeleted returns a copy of ctx in which reads also return soft-deleted rows.
+func WithDeleted(ctx context.Context) context.Context {
+	return context.WithValue(ctx, deletedScopeKey{}, IncludeDeleted)
+}
+
+// OnlyDeleted returns a copy of ctx in which reads only return soft-deleted rows.
+func OnlyDeleted(ctx context.Context) context.Context {
+	return context.WithValue(ctx, deletedScopeKey{}, DeletedOnly)
+}
+
+// DeletedScopeFrom returns the scope set on ctx, ExcludeDeleted by default.
+func DeletedScopeFrom(ctx context.Context) DeletedScope {
+	scope, _ := ctx.Value(deletedScopeKey{}).(DeletedScope)
+	return scope
+}
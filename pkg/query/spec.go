@@ -0,0 +1,131 @@
+package query
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidSpec is wrapped by every error caused by a filter or sort that is malformed or
+// references a column outside the model's whitelist.
+var ErrInvalidSpec = errors.New("invalid query spec")
+
+// Operator is a comparison applied to a column by a Condition.
+type Operator string
+
+const (
+	OpEq  Operator = "eq"
+	OpNe  Operator = "ne"
+	OpIn  Operator = "in"
+	OpGt  Operator = "gt"
+	OpGte Operator = "gte"
+	OpLt  Operator = "lt"
+	OpLte Operator = "lte"
+	// OpLike is a case-insensitive substring match, % and _ in the value are matched literally
+	OpLike Operator = "like"
+	// OpIsNull matches NULL columns when its value is true and non-NULL ones when false
+	OpIsNull Operator = "is_null"
+)
+
+var operators = map[Operator]bool{
+	OpEq: true, OpNe: true, OpIn: true, OpGt: true, OpGte: true,
+	OpLt: true, OpLte: true, OpLike: true, OpIsNull: true,
+}
+
+// Logic combines the members of a Filter.
+type Logic string
+
+const (
+	And Logic = "and"
+	Or  Logic = "or"
+)
+
+// Condition compares Field, a column name from the model's `db` tags, to Value.
+// Value is a []interface{} for OpIn and a bool for OpIsNull.
+type Condition struct {
+	Field string
+	Op    Operator
+	Value interface{}
+}
+
+// Filter is a group of conditions and nested groups combined with Logic (And by default).
+type Filter struct {
+	Logic      Logic
+	Conditions []Condition
+	Groups     []Filter
+}
+
+// IsEmpty reports whether the filter matches every row.
+func (f Filter) IsEmpty() bool {
+	if len(f.Conditions) > 0 {
+		return false
+	}
+	for _, g := range f.Groups {
+		if !g.IsEmpty() {
+			return false
+		}
+	}
+	return true
+}
+
+// Sort orders results on Field, ascending unless Desc is set.
+type Sort struct {
+	Field string
+	Desc  bool
+}
+
+// Spec describes which rows a List query returns and in which order.
+type Spec struct {
+	Filter Filter
+	Sort   []Sort
+}
+
+// Where returns a spec AND-ing the given conditions.
+func Where(conditions ...Condition) Spec {
+	return Spec{Filter: Filter{Logic: And, Conditions: conditions}}
+}
+
+// Validate checks that every condition and sort of spec targets a whitelisted column with
+// a supported operator.
+func (c Columns) Validate(spec Spec) error {
+	if err := c.validateFilter(spec.Filter); err != nil {
+		return err
+	}
+	for _, s := range spec.Sort {
+		col, ok := c[s.Field]
+		if !ok || !col.Sortable {
+			return fmt.Errorf("%w: cannot sort on %q", ErrInvalidSpec, s.Field)
+		}
+	}
+	return nil
+}
+
+func (c Columns) validateFilter(f Filter) error {
+	if f.Logic != "" && f.Logic != And && f.Logic != Or {
+		return fmt.Errorf("%w: unknown logic %q", ErrInvalidSpec, f.Logic)
+	}
+	for _, cond := range f.Conditions {
+		col, ok := c[cond.Field]
+		if !ok || !col.Filterable {
+			return fmt.Errorf("%w: cannot filter on %q", ErrInvalidSpec, cond.Field)
+		}
+		if !operators[cond.Op] {
+			return fmt.Errorf("%w: unknown operator %q on %q", ErrInvalidSpec, cond.Op, cond.Field)
+		}
+		switch cond.Op {
+		case OpIn:
+			if _, ok := cond.Value.([]interface{}); !ok {
+				return fmt.Errorf("%w: %q in expects a list", ErrInvalidSpec, cond.Field)
+			}
+		case OpIsNull:
+			if _, ok := cond.Value.(bool); !ok {
+				return fmt.Errorf("%w: %q is_null expects a boolean", ErrInvalidSpec, cond.Field)
+			}
+		}
+	}
+	for _, g := range f.Groups {
+		if err := c.validateFilter(g); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package secrets
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// region: ======= File =======
+
+// FileProvider reads a secret from a file, such as a mounted Kubernetes or Docker secret.
+// The trailing newline is removed.
+type FileProvider struct{}
+
+func (FileProvider) Get(_ context.Context, path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// region: ======= Environment =======
+
+// EnvProvider reads a secret from an environment variable, path is its name.
+type EnvProvider struct{}
+
+func (EnvProvider) Get(_ context.Context, name string) (string, error) {
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return "", fmt.Errorf("environment variable %s is not set", name)
+	}
+	return value, nil
+}
+
+// region: ======= Encrypted file =======
+
+// KeySize is the size of the keys of the encrypted files, AES-256
+const KeySize = 32
+
+// EncryptedFileProvider reads a secret from a file encrypted with a local key, see Encrypt.
+type EncryptedFileProvider struct {
+	aead cipher.AEAD
+}
+
+// NewEncryptedFileProvider returns a provider decrypting the files with key, KeySize bytes.
+func NewEncryptedFileProvider(key []byte) (*EncryptedFileProvider, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	return &EncryptedFileProvider{aead: aead}, nil
+}
+
+func (p *EncryptedFileProvider) Get(_ context.Context, path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil {
+		return "", fmt.Errorf("invalid encrypted file: %w", err)
+	}
+
+	nonceSize := p.aead.NonceSize()
+	if len(sealed) < nonceSize {
+		return "", errors.New("invalid encrypted file: too short")
+	}
+	secret, err := p.aead.Open(nil, sealed[:nonceSize], sealed[nonceSize:], nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt: %w", err)
+	}
+	return string(secret), nil
+}
+
+// Encrypt returns the content of an encrypted file holding secret: the base64 of the
+// nonce followed by the AES-GCM ciphertext.
+func Encrypt(key []byte, secret string) (string, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(aead.Seal(nonce, nonce, []byte(secret), nil)), nil
+}
+
+// LoadKey reads a key file, holding the base64 of KeySize random bytes.
+func LoadKey(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the secrets key: %w", err)
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid secrets key: %w", err)
+	}
+	return key, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("invalid secrets key: %d bytes, expected %d", len(key), KeySize)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
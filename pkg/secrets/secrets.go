@@ -0,0 +1,203 @@
+// Package secrets resolves the configuration values referencing a secret, such as
+// secret://file//run/secrets/db_password, through pluggable SecretProviders. The values
+// are cached and refreshed so that the components can follow their rotation.
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"proposal-template/pkg/lifecycle"
+	"proposal-template/pkg/logger"
+)
+
+// Prefix starts the secret references: secret://<provider>/<path>, e.g.
+// secret://env/DB_PASSWORD or secret://file//run/secrets/db_password for an absolute path.
+const Prefix = "secret://"
+
+// DefaultRefreshInterval is how often the cached secrets are read again
+var DefaultRefreshInterval = time.Minute
+
+// IsReference reports whether value references a secret rather than holding it.
+func IsReference(value string) bool {
+	return strings.HasPrefix(value, Prefix)
+}
+
+// SecretProvider reads secrets from a store.
+type SecretProvider interface {
+	// Get returns the secret at path, the part of the reference after the provider name
+	Get(ctx context.Context, path string) (string, error)
+}
+
+// Manager resolves the secret references with the provider they name. The secrets are
+// cached and read again every refresh interval, the watchers of a secret whose value
+// changed are then called.
+type Manager struct {
+	logger    logger.ILogger
+	providers map[string]SecretProvider
+	interval  time.Duration
+
+	mu       sync.Mutex
+	cache    map[string]string
+	watchers map[string][]func(value string)
+
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+var _ lifecycle.Runnable = (*Manager)(nil)
+
+type Option func(*Manager)
+
+// WithProvider adds a provider, referenced as secret://<name>/<path>.
+func WithProvider(name string, provider SecretProvider) Option {
+	return func(m *Manager) {
+		m.providers[name] = provider
+	}
+}
+
+// WithRefreshInterval sets how often the cached secrets are read again, 0 disables it.
+func WithRefreshInterval(interval time.Duration) Option {
+	return func(m *Manager) {
+		m.interval = interval
+	}
+}
+
+// NewManager returns a Manager with the file and env providers, see FileProvider and
+// EnvProvider.
+func NewManager(logger logger.ILogger, opts ...Option) *Manager {
+	m := &Manager{
+		logger: logger,
+		providers: map[string]SecretProvider{
+			"file": FileProvider{},
+			"env":  EnvProvider{},
+		},
+		interval: DefaultRefreshInterval,
+		cache:    make(map[string]string),
+		watchers: make(map[string][]func(string)),
+		stop:     make(chan struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// Resolve returns the secret referenced by value, or value itself when it is not a
+// reference.
+func (m *Manager) Resolve(ctx context.Context, value string) (string, error) {
+	if !IsReference(value) {
+		return value, nil
+	}
+
+	m.mu.Lock()
+	secret, ok := m.cache[value]
+	m.mu.Unlock()
+	if ok {
+		return secret, nil
+	}
+
+	secret, err := m.get(ctx, value)
+	if err != nil {
+		return "", err
+	}
+	m.mu.Lock()
+	m.cache[value] = secret
+	m.mu.Unlock()
+	return secret, nil
+}
+
+// Watch calls fn with the new value of the secret referenced by ref when it is rotated.
+// Nothing is watched when ref is not a reference.
+func (m *Manager) Watch(ref string, fn func(value string)) {
+	if !IsReference(ref) {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.watchers[ref] = append(m.watchers[ref], fn)
+}
+
+// Refresh reads the cached secrets again and calls the watchers of those that changed. A
+// secret that cannot be read keeps its cached value.
+func (m *Manager) Refresh(ctx context.Context) {
+	m.mu.Lock()
+	refs := make([]string, 0, len(m.cache))
+	for ref := range m.cache {
+		refs = append(refs, ref)
+	}
+	m.mu.Unlock()
+
+	for _, ref := range refs {
+		secret, err := m.get(ctx, ref)
+		if err != nil {
+			m.logger.Error(fmt.Sprintf("Failed to refresh the secret %s: %s", ref, err))
+			continue
+		}
+
+		m.mu.Lock()
+		changed := m.cache[ref] != secret
+		m.cache[ref] = secret
+		watchers := append([]func(string){}, m.watchers[ref]...)
+		m.mu.Unlock()
+
+		if changed {
+			m.logger.Info(fmt.Sprintf("Secret %s rotated", ref))
+			for _, fn := range watchers {
+				fn(secret)
+			}
+		}
+	}
+}
+
+// Start refreshes the secrets every refresh interval until ctx is cancelled or Stop is
+// called.
+func (m *Manager) Start(ctx context.Context) error {
+	if m.interval <= 0 {
+		select {
+		case <-ctx.Done():
+		case <-m.stop:
+		}
+		return nil
+	}
+
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-m.stop:
+			return nil
+		case <-ticker.C:
+			m.Refresh(ctx)
+		}
+	}
+}
+
+func (m *Manager) Stop(_ context.Context) error {
+	m.stopOnce.Do(func() { close(m.stop) })
+	return nil
+}
+
+// get reads the secret referenced by ref from its provider
+func (m *Manager) get(ctx context.Context, ref string) (string, error) {
+	name, path, found := strings.Cut(strings.TrimPrefix(ref, Prefix), "/")
+	if !found || path == "" {
+		return "", fmt.Errorf("invalid secret reference %q, expected %s<provider>/<path>", ref, Prefix)
+	}
+	provider, ok := m.providers[name]
+	if !ok {
+		return "", fmt.Errorf("unknown secret provider %q in %s", name, ref)
+	}
+
+	secret, err := provider.Get(ctx, path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read the secret %s: %w", ref, err)
+	}
+	return secret, nil
+}
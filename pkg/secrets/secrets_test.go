@@ -0,0 +1,122 @@
+package secrets
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type nopLogger struct{}
+
+func (nopLogger) Debug(string, ...interface{}) {}
+func (nopLogger) Info(string, ...interface{})  {}
+func (nopLogger) Warn(string, ...interface{})  {}
+func (nopLogger) Error(string, ...interface{}) {}
+func (nopLogger) GetLevel() string             { return "debug" }
+func (nopLogger) SetLevel(string)              {}
+
+func TestManager_Resolve(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "db_password")
+	require.NoError(t, os.WriteFile(path, []byte("from-file\n"), 0o600))
+	t.Setenv("TEST_SECRET", "from-env")
+
+	key := make([]byte, KeySize)
+	encrypted, err := Encrypt(key, "from-encrypted-file")
+	require.NoError(t, err)
+	encryptedPath := filepath.Join(dir, "kafka_password.enc")
+	require.NoError(t, os.WriteFile(encryptedPath, []byte(encrypted), 0o600))
+	provider, err := NewEncryptedFileProvider(key)
+	require.NoError(t, err)
+
+	m := NewManager(nopLogger{}, WithProvider("encrypted", provider))
+	ctx := context.Background()
+
+	tests := map[string]struct {
+		value string
+		want  string
+		err   string
+	}{
+		"plain value":    {value: "plain", want: "plain"},
+		"file":           {value: "secret://file/" + path, want: "from-file"},
+		"env":            {value: "secret://env/TEST_SECRET", want: "from-env"},
+		"encrypted file": {value: "secret://encrypted/" + encryptedPath, want: "from-encrypted-file"},
+		"missing env":    {value: "secret://env/TEST_MISSING_SECRET", err: "environment variable TEST_MISSING_SECRET is not set"},
+		"unknown":        {value: "secret://vault/db", err: `unknown secret provider "vault"`},
+		"invalid":        {value: "secret://file", err: "invalid secret reference"},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := m.Resolve(ctx, tt.value)
+			if tt.err != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestManager_Refresh(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "password")
+	require.NoError(t, os.WriteFile(path, []byte("v1"), 0o600))
+	ref := "secret://file/" + path
+
+	m := NewManager(nopLogger{})
+	ctx := context.Background()
+
+	var rotated []string
+	m.Watch(ref, func(value string) { rotated = append(rotated, value) })
+
+	got, err := m.Resolve(ctx, ref)
+	require.NoError(t, err)
+	assert.Equal(t, "v1", got)
+
+	// Cached until refreshed
+	require.NoError(t, os.WriteFile(path, []byte("v2"), 0o600))
+	got, _ = m.Resolve(ctx, ref)
+	assert.Equal(t, "v1", got)
+
+	m.Refresh(ctx)
+	got, _ = m.Resolve(ctx, ref)
+	assert.Equal(t, "v2", got)
+	assert.Equal(t, []string{"v2"}, rotated)
+
+	// Unchanged or unreadable secrets are not rotated
+	m.Refresh(ctx)
+	require.NoError(t, os.Remove(path))
+	m.Refresh(ctx)
+	got, _ = m.Resolve(ctx, ref)
+	assert.Equal(t, "v2", got)
+	assert.Equal(t, []string{"v2"}, rotated)
+}
+
+func TestEncryptedFileProvider(t *testing.T) {
+	key := make([]byte, KeySize)
+	key[0] = 1
+	encrypted, err := Encrypt(key, "secret")
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "secret.enc")
+	require.NoError(t, os.WriteFile(path, []byte(encrypted+"\n"), 0o600))
+
+	provider, err := NewEncryptedFileProvider(key)
+	require.NoError(t, err)
+	got, err := provider.Get(context.Background(), path)
+	require.NoError(t, err)
+	assert.Equal(t, "secret", got)
+
+	// Another key cannot decrypt it
+	other, err := NewEncryptedFileProvider(make([]byte, KeySize))
+	require.NoError(t, err)
+	_, err = other.Get(context.Background(), path)
+	assert.ErrorContains(t, err, "failed to decrypt")
+
+	_, err = NewEncryptedFileProvider([]byte("short"))
+	assert.ErrorContains(t, err, "invalid secrets key")
+}
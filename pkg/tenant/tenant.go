@@ -0,0 +1,36 @@
+// Package tenant carries the tenant of a request in its context. The HTTP middleware
+// stores it and the datalayer scopes the rows of tenant models to it.
+package tenant
+
+import "context"
+
+type tenantKey struct{}
+
+type adminBypassKey struct{}
+
+// WithID returns a copy of ctx carrying the tenant id. An empty id leaves ctx unchanged.
+func WithID(ctx context.Context, id string) context.Context {
+	if id == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, tenantKey{}, id)
+}
+
+// FromContext returns the tenant set on ctx with WithID, if any.
+func FromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(tenantKey{}).(string)
+	return id, ok
+}
+
+// WithAdminBypass returns a copy of ctx in which tenant scoping is lifted: reads return the
+// rows of every tenant and writes may target any of them. It is meant for back-office
+// operations and maintenance jobs, never for data coming straight from a request.
+func WithAdminBypass(ctx context.Context) context.Context {
+	return context.WithValue(ctx, adminBypassKey{}, true)
+}
+
+// IsAdminBypass reports whether ctx was returned by WithAdminBypass.
+func IsAdminBypass(ctx context.Context) bool {
+	bypass, _ := ctx.Value(adminBypassKey{}).(bool)
+	return bypass
+}
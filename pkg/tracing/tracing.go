@@ -0,0 +1,70 @@
+// Package tracing carries the request ID and the W3C trace context of a request in its
+// context. The HTTP middleware stores them and the loggers, such as the database one,
+// add them to their entries.
+package tracing
+
+import (
+	"context"
+	"regexp"
+	"strings"
+)
+
+type requestIDKey struct{}
+
+type spanContextKey struct{}
+
+// SpanContext identifies the span of a distributed trace the request belongs to.
+type SpanContext struct {
+	TraceID string
+	SpanID  string
+}
+
+// WithRequestID returns a copy of ctx carrying the request id. An empty id leaves ctx unchanged.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	if id == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFromContext returns the request id set on ctx with WithRequestID, if any.
+func RequestIDFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(requestIDKey{}).(string)
+	return id, ok
+}
+
+// WithSpanContext returns a copy of ctx carrying sc.
+func WithSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, spanContextKey{}, sc)
+}
+
+// SpanContextFromContext returns the span context set on ctx with WithSpanContext, if any.
+func SpanContextFromContext(ctx context.Context) (SpanContext, bool) {
+	sc, ok := ctx.Value(spanContextKey{}).(SpanContext)
+	return sc, ok
+}
+
+var traceparent = regexp.MustCompile(`^[0-9a-f]{2}-([0-9a-f]{32})-([0-9a-f]{16})-[0-9a-f]{2}$`)
+
+// ParseTraceparent parses a W3C traceparent header,
+// e.g. 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01.
+func ParseTraceparent(header string) (SpanContext, bool) {
+	match := traceparent.FindStringSubmatch(strings.TrimSpace(header))
+	if match == nil || match[1] == strings.Repeat("0", 32) || match[2] == strings.Repeat("0", 16) {
+		return SpanContext{}, false
+	}
+	return SpanContext{TraceID: match[1], SpanID: match[2]}, true
+}
+
+// Fields returns the request id and trace context of ctx as key=value pairs, e.g.
+// "request_id=42 trace_id=4bf9... span_id=00f0...", empty when ctx carries none.
+func Fields(ctx context.Context) string {
+	var fields []string
+	if id, ok := RequestIDFromContext(ctx); ok {
+		fields = append(fields, "request_id="+id)
+	}
+	if sc, ok := SpanContextFromContext(ctx); ok {
+		fields = append(fields, "trace_id="+sc.TraceID, "span_id="+sc.SpanID)
+	}
+	return strings.Join(fields, " ")
+}
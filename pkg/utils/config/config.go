@@ -1,60 +1,318 @@
 package utils
 
-
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
 	"log"
 	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
 
 	"github.com/caarlos0/env/v11"
 	"github.com/joho/godotenv"
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
 )
 
-type IConfig interface {
-	Load() error
+// region: ======= Configuration loader =======
+
+// LoadConfig merges the configuration sources into the AppConfig every adapter reads. From
+// the lowest to the highest precedence:
+//
+//  1. the defaults, envDefault tags of AppConfig
+//  2. the YAML or TOML configuration file, see WithFile
+//  3. its environment override, e.g. config.production.yaml next to config.yaml
+//  4. the dotenv files: .env, then the one of the environment (.env.dev, .env.test or
+//     .env.production), then a dotenv file given to WithFile
+//  5. the environment variables
+//  6. the overrides given to WithOverrides, e.g. from the --set flag
+//
+// A source sets the environment variables of the fields, e.g. HTTP_PORT. In the YAML and
+// TOML files the fields are nested under their section in snake case:
+//
+//	httpserver:
+//	  port: 8080
+//
+// The environment (APP_ENV) is taken from WithEnvironment, otherwise from the sources
+// above, and defaults to development. The merged configuration is validated, see
+// AppConfig.Validate.
+func LoadConfig(opts ...LoadOption) (*AppConfig, error) {
+	l := loader{
+		environ: os.Environ,
+	}
+	for _, opt := range opts {
+		opt(&l)
+	}
+
+	vars, err := l.load()
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &AppConfig{}
+	if err := env.ParseWithOptions(cfg, env.Options{Environment: vars}); err != nil {
+		return nil, fmt.Errorf("failed to load configuration: %w", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	sources := append(l.sources, "environment variables")
+	log.Printf("Configuration of the %s environment loaded from %s", cfg.Env, strings.Join(sources, ", "))
+	return cfg, nil
+}
+
+// LoadOption adds a source to LoadConfig.
+type LoadOption func(*loader)
+
+// WithFile loads the configuration file at path: YAML (.yaml, .yml), TOML (.toml) or, for
+// any other extension, dotenv.
+func WithFile(path string) LoadOption {
+	return func(l *loader) {
+		l.file = path
+	}
+}
+
+// WithEnvironment sets the environment, APP_ENV, which selects the environment files.
+func WithEnvironment(name string) LoadOption {
+	return func(l *loader) {
+		l.environment = name
+	}
+}
+
+// WithOverrides sets fields from KEY=VALUE pairs, they take precedence over every other
+// source. KEY is an environment variable (HTTP_PORT) or a file key (httpserver.port).
+func WithOverrides(pairs ...string) LoadOption {
+	return func(l *loader) {
+		l.overrides = append(l.overrides, pairs...)
+	}
+}
+
+// withEnviron replaces the process environment, for tests
+func withEnviron(environ func() []string) LoadOption {
+	return func(l *loader) {
+		l.environ = environ
+	}
+}
+
+type loader struct {
+	file        string
+	environment string
+	overrides   []string
+	environ     func() []string
+	// sources lists the sources that were found, for the logs
+	sources []string
+}
+
+// layer is a source of variables, keyed by environment variable name
+type layer map[string]string
+
+func (l *loader) load() (map[string]string, error) {
+	keys := fileKeys()
+
+	overrides := layer{}
+	for _, pair := range l.overrides {
+		key, value, found := strings.Cut(pair, "=")
+		if !found {
+			return nil, fmt.Errorf("invalid override %q, expected KEY=VALUE", pair)
+		}
+		if name, ok := keys[key]; ok {
+			key = name
+		}
+		overrides[key] = value
+	}
+	environ := layer(env.ToMap(l.environ()))
+
+	configFile, dotenvFile := layer{}, layer{}
+	ext := filepath.Ext(l.file)
+	isConfigFile := false
+	var err error
+	switch strings.ToLower(ext) {
+	case ".yaml", ".yml", ".toml":
+		isConfigFile = true
+		configFile, err = l.readConfigFile(l.file, keys, true)
+	default:
+		if l.file != "" {
+			dotenvFile, err = l.readDotenv(l.file, true)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	dotenv, err := l.readDotenv(".env", false)
+	if err != nil {
+		return nil, err
+	}
+
+	// The environment selects the remaining files, it is read from the sources loaded so far
+	environment := l.environment
+	for _, source := range []layer{overrides, environ, dotenvFile, dotenv, configFile} {
+		if environment == "" {
+			environment = source["APP_ENV"]
+		}
+	}
+	if environment == "" {
+		environment = "development"
+	}
+	overrides["APP_ENV"] = environment
+
+	configOverride := layer{}
+	if isConfigFile {
+		if configOverride, err = l.readConfigFile(overrideFileName(l.file, environment), keys, false); err != nil {
+			return nil, err
+		}
+	}
+	dotenvOverride := layer{}
+	if name := envFileName(environment); name != "" {
+		if dotenvOverride, err = l.readDotenv(name, false); err != nil {
+			return nil, err
+		}
+	}
+
+	vars := map[string]string{}
+	for _, source := range []layer{configFile, configOverride, dotenv, dotenvOverride, dotenvFile, environ, overrides} {
+		for key, value := range source {
+			vars[key] = value
+		}
+	}
+	return vars, nil
 }
 
-type ConfigManager struct {
-	cfgs []IConfig
+// files returns the files read for environment, whether they exist or not
+func (l *loader) files(environment string) []string {
+	files := []string{".env"}
+	if name := envFileName(environment); name != "" {
+		files = append(files, name)
+	}
+	if l.file == "" {
+		return files
+	}
+	files = append(files, l.file)
+	switch strings.ToLower(filepath.Ext(l.file)) {
+	case ".yaml", ".yml", ".toml":
+		files = append(files, overrideFileName(l.file, environment))
+	}
+	return files
 }
 
-func NewConfigManager(cfgs []IConfig) *ConfigManager {
-	return &ConfigManager{
-		cfgs: cfgs,
+// readDotenv reads a dotenv file, a missing file is only an error when required
+func (l *loader) readDotenv(path string, required bool) (layer, error) {
+	vars, err := godotenv.Read(path)
+	if errors.Is(err, fs.ErrNotExist) && !required {
+		return layer{}, nil
 	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	l.sources = append(l.sources, path)
+	return vars, nil
 }
 
-func (c *ConfigManager) LoadAllConfig(envFolderPath string) error {
-	loadEnv(envFolderPath)
-	for _, cfg := range c.cfgs {
-		if err := cfg.Load(); err != nil {
-			return err
+// readConfigFile reads a YAML or TOML file, a missing file is only an error when required
+func (l *loader) readConfigFile(path string, keys map[string]string, required bool) (layer, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) && !required {
+		return layer{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	tree := map[string]interface{}{}
+	if strings.ToLower(filepath.Ext(path)) == ".toml" {
+		err = toml.NewDecoder(bytes.NewReader(data)).Decode(&tree)
+	} else {
+		err = yaml.Unmarshal(data, &tree)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	vars := layer{}
+	if err := flatten(tree, "", keys, vars); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	l.sources = append(l.sources, path)
+	return vars, nil
+}
+
+// flatten sets in vars the environment variable of every leaf of tree
+func flatten(tree map[string]interface{}, prefix string, keys map[string]string, vars layer) error {
+	for key, value := range tree {
+		path := prefix + key
+		if nested, ok := value.(map[string]interface{}); ok {
+			if err := flatten(nested, path+".", keys, vars); err != nil {
+				return err
+			}
+			continue
+		}
+
+		name, ok := keys[path]
+		if !ok {
+			return fmt.Errorf("unknown configuration key %q", path)
+		}
+		if list, ok := value.([]interface{}); ok {
+			items := make([]string, len(list))
+			for i, item := range list {
+				items[i] = fmt.Sprint(item)
+			}
+			vars[name] = strings.Join(items, ",")
+			continue
+		}
+		if value != nil {
+			vars[name] = fmt.Sprint(value)
 		}
 	}
 	return nil
 }
 
-func loadEnv(envFolderPath string) error {
-	env := os.Getenv("APP_ENV")
-	envFileName := ""
+// fileKeys maps the keys of the configuration files to the environment variables of the
+// fields, see Field.Key.
+func fileKeys() map[string]string {
+	keys := map[string]string{}
+	for _, field := range Fields() {
+		keys[field.Key] = field.Env
+	}
+	return keys
+}
 
+// snakeCase turns a Go identifier into snake case, keeping acronyms together:
+// JWTClaimsKey becomes jwt_claims_key.
+func snakeCase(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) {
+			prev := runes[i-1]
+			nextIsLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || unicode.IsUpper(prev) && nextIsLower {
+				b.WriteByte('_')
+			}
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+// overrideFileName returns the override of the configuration file path for environment,
+// config.production.yaml for config.yaml
+func overrideFileName(path, environment string) string {
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "." + environment + ext
+}
+
+// envFileName returns the dotenv file of the environment env, empty when it has none
+func envFileName(env string) string {
 	switch env {
 	case "development":
-		envFileName = ".env.dev"
+		return ".env.dev"
 	case "test":
-		envFileName = ".env.test"
+		return ".env.test"
 	case "production":
-		envFileName = ".env.production"
+		return ".env.production"
 	}
-	if envFileName != "" {
-		envFilePath := envFolderPath + "/" + envFileName
-		log.Printf("Loading config from file:%s\n", envFilePath)
-		_ = godotenv.Load(envFilePath)
-	}
-	log.Printf("Loading config from environment\n")
-	_ = godotenv.Load()
-	return nil
-}
-
-func ParseConfig(c IConfig) error {
-	return env.Parse(c)
+	return ""
 }
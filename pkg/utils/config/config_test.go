@@ -0,0 +1,159 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// inTempDir runs the test in an empty directory holding files, the dotenv files are read
+// from the working directory
+func inTempDir(t *testing.T, files map[string]string) {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+	}
+
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(dir))
+	t.Cleanup(func() { os.Chdir(wd) })
+}
+
+func environ(vars ...string) LoadOption {
+	return withEnviron(func() []string { return vars })
+}
+
+func TestLoadConfig_Precedence(t *testing.T) {
+	inTempDir(t, map[string]string{
+		"config.yaml": `
+httpserver:
+  host: file-host
+  port: 1000
+logger:
+  level: debug
+tenant:
+  jwt_claims_key: file-claims
+  resolvers: [header, jwt]
+`,
+		"config.production.yaml": `
+httpserver:
+  port: 2000
+`,
+		".env":            "HTTP_PORT=3000\nLOG_LEVEL=warn\n",
+		".env.production": "HTTP_PORT=4000\n",
+	})
+
+	cfg, err := LoadConfig(
+		WithFile("config.yaml"),
+		WithEnvironment("production"),
+		WithOverrides("httpserver.host=flag-host"),
+		environ("HTTP_PORT=5000"),
+	)
+	require.NoError(t, err)
+
+	assert.Equal(t, "production", cfg.Env)
+	assert.Equal(t, "flag-host", cfg.Httpserver.Host)
+	assert.Equal(t, 5000, cfg.Httpserver.Port)
+	assert.Equal(t, "warn", cfg.Logger.Level)
+	assert.Equal(t, "file-claims", cfg.Tenant.JWTClaimsKey)
+	assert.Equal(t, "header,jwt", cfg.Tenant.Resolvers)
+	// Untouched fields keep their default
+	assert.Equal(t, "X-Tenant-ID", cfg.Tenant.Header)
+}
+
+func TestLoadConfig_EnvironmentFiles(t *testing.T) {
+	inTempDir(t, map[string]string{
+		"config.toml": `
+[httpserver]
+port = 1000
+`,
+		"config.test.toml": `
+[httpserver]
+port = 2000
+`,
+		".env":      "APP_ENV=test\n",
+		".env.test": "LOG_LEVEL=error\n",
+	})
+
+	// The environment comes from .env, it selects config.test.toml and .env.test
+	cfg, err := LoadConfig(WithFile("config.toml"), environ())
+	require.NoError(t, err)
+	assert.Equal(t, "test", cfg.Env)
+	assert.Equal(t, 2000, cfg.Httpserver.Port)
+	assert.Equal(t, "error", cfg.Logger.Level)
+
+	// The environment of the flag wins
+	cfg, err = LoadConfig(WithFile("config.toml"), WithEnvironment("production"), environ())
+	require.NoError(t, err)
+	assert.Equal(t, "production", cfg.Env)
+	assert.Equal(t, 1000, cfg.Httpserver.Port)
+}
+
+func TestLoadConfig_DotenvFile(t *testing.T) {
+	inTempDir(t, map[string]string{
+		"app.env": "HTTP_PORT=6000\n",
+	})
+
+	cfg, err := LoadConfig(WithFile("app.env"), environ())
+	require.NoError(t, err)
+	assert.Equal(t, "development", cfg.Env)
+	assert.Equal(t, 6000, cfg.Httpserver.Port)
+}
+
+func TestLoadConfig_Errors(t *testing.T) {
+	inTempDir(t, map[string]string{
+		"unknown.yaml": "httpserver:\n  prot: 8080\n",
+		"invalid.yaml": "httpserver: [",
+	})
+
+	tests := map[string]struct {
+		opts []LoadOption
+		err  string
+	}{
+		"missing file": {
+			opts: []LoadOption{WithFile("missing.yaml")},
+			err:  "failed to read missing.yaml",
+		},
+		"unknown key": {
+			opts: []LoadOption{WithFile("unknown.yaml")},
+			err:  `unknown configuration key "httpserver.prot"`,
+		},
+		"invalid file": {
+			opts: []LoadOption{WithFile("invalid.yaml")},
+			err:  "failed to parse invalid.yaml",
+		},
+		"invalid override": {
+			opts: []LoadOption{WithOverrides("HTTP_PORT")},
+			err:  `invalid override "HTTP_PORT"`,
+		},
+		"invalid value": {
+			opts: []LoadOption{WithOverrides("HTTP_PORT=http")},
+			err:  "failed to load configuration",
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := LoadConfig(append(tt.opts, environ())...)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.err)
+		})
+	}
+}
+
+func TestSnakeCase(t *testing.T) {
+	for name, want := range map[string]string{
+		"Httpserver":            "httpserver",
+		"JWTClaimsKey":          "jwt_claims_key",
+		"ConnMaxLifetimeInSecs": "conn_max_lifetime_in_secs",
+		"SSLRootCert":           "ssl_root_cert",
+		"StatementTimeoutInMs":  "statement_timeout_in_ms",
+		"URI":                   "uri",
+	} {
+		assert.Equal(t, want, snakeCase(name), name)
+	}
+}
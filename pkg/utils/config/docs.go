@@ -0,0 +1,160 @@
+package utils
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"proposal-template/pkg/secrets"
+)
+
+// region: ======= Fields =======
+
+// Masked replaces the values of the secret fields
+const Masked = "******"
+
+// Field describes a configuration field, read from the tags of AppConfig.
+type Field struct {
+	// Env is the environment variable of the field
+	Env string
+	// Key is the key of the field in the configuration files, e.g. httpserver.port
+	Key string
+	// Section is the key of the struct holding the field, empty for the top-level fields
+	Section     string
+	Type        string
+	Default     string
+	Description string
+	// Secret fields are masked when printed, see WriteEnv
+	Secret bool
+	// Reloadable fields are applied without a restart, see Reloader
+	Reloadable bool
+
+	index []int
+}
+
+// Fields returns every field of AppConfig, in declaration order.
+func Fields() []Field {
+	return structFields(reflect.TypeOf(AppConfig{}), "", nil)
+}
+
+// Value returns the value of f in cfg.
+func (f Field) Value(cfg AppConfig) string {
+	return fmt.Sprint(reflect.ValueOf(cfg).FieldByIndex(f.index).Interface())
+}
+
+func structFields(t reflect.Type, section string, index []int) []Field {
+	var fields []Field
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		key := snakeCase(field.Name)
+		if section != "" {
+			key = section + "." + key
+		}
+		fieldIndex := append(append([]int{}, index...), i)
+
+		if field.Type.Kind() == reflect.Struct {
+			fields = append(fields, structFields(field.Type, key, fieldIndex)...)
+			continue
+		}
+		env, _, _ := strings.Cut(field.Tag.Get("env"), ",")
+		if env == "" {
+			continue
+		}
+		fields = append(fields, Field{
+			Env:         env,
+			Key:         key,
+			Section:     section,
+			Type:        field.Type.Kind().String(),
+			Default:     field.Tag.Get("envDefault"),
+			Description: field.Tag.Get("description"),
+			Secret:      field.Tag.Get("secret") == "true",
+			Reloadable:  field.Tag.Get("reload") == "true",
+			index:       fieldIndex,
+		})
+	}
+	return fields
+}
+
+// region: ======= Rendering =======
+
+// WriteMarkdown writes a Markdown table of every field.
+func WriteMarkdown(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("| Variable | File key | Type | Default | Reloadable | Description |\n")
+	b.WriteString("|---|---|---|---|---|---|\n")
+	for _, f := range Fields() {
+		reloadable := ""
+		if f.Reloadable {
+			reloadable = "yes"
+		}
+		fmt.Fprintf(&b, "| `%s` | `%s` | %s | %s | %s | %s |\n",
+			f.Env, f.Key, f.Type, markdownCode(f.Default), reloadable, strings.ReplaceAll(f.Description, "|", `\|`))
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteEnvExample writes a dotenv file setting every field to its default, each one
+// preceded by its description.
+func WriteEnvExample(w io.Writer) error {
+	return writeDotenv(w, func(f Field) string {
+		return f.Default
+	}, true)
+}
+
+// WriteEnv writes the values of cfg as a dotenv file, the secret fields masked. The
+// secret references are written as is, they hold no secret.
+func WriteEnv(w io.Writer, cfg AppConfig) error {
+	return writeDotenv(w, func(f Field) string {
+		value := f.Value(cfg)
+		if f.Secret && value != "" && !secrets.IsReference(value) {
+			return Masked
+		}
+		return value
+	}, false)
+}
+
+// writeDotenv writes the fields grouped by section, with their descriptions when
+// describe is set
+func writeDotenv(w io.Writer, value func(Field) string, describe bool) error {
+	var b strings.Builder
+	section := ""
+	for i, f := range Fields() {
+		if i == 0 || f.Section != section {
+			if i > 0 {
+				b.WriteString("\n")
+			}
+			section = f.Section
+			if section != "" {
+				fmt.Fprintf(&b, "# ======= %s =======\n", section)
+			}
+		}
+		if describe {
+			fmt.Fprintf(&b, "# %s (%s", f.Description, f.Type)
+			if f.Reloadable {
+				b.WriteString(", reloadable")
+			}
+			b.WriteString(")\n")
+		}
+		fmt.Fprintf(&b, "%s=%s\n", f.Env, dotenvValue(value(f)))
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// dotenvValue quotes value when a dotenv parser would not read it back as is
+func dotenvValue(value string) string {
+	if strings.ContainsAny(value, " #\"'\\\t") {
+		return strconv.Quote(value)
+	}
+	return value
+}
+
+func markdownCode(value string) string {
+	if value == "" {
+		return ""
+	}
+	return "`" + value + "`"
+}
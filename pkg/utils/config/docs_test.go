@@ -0,0 +1,57 @@
+package utils
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFields(t *testing.T) {
+	fields := Fields()
+	require.NotEmpty(t, fields)
+
+	envs := map[string]bool{}
+	for _, f := range fields {
+		assert.NotEmpty(t, f.Description, "%s has no description", f.Env)
+		assert.False(t, envs[f.Env], "%s is declared twice", f.Env)
+		envs[f.Env] = true
+	}
+
+	assert.Equal(t, Field{
+		Env: "HTTP_REQUEST_TIMEOUT_SECS", Key: "httpserver.request_timeout_in_secs", Section: "httpserver",
+		Type: "int", Default: "30", Description: "Default deadline of a request, 0 disables it", Reloadable: true,
+		index: []int{1, 3},
+	}, fields[4])
+}
+
+func TestWriteEnv(t *testing.T) {
+	cfg := AppConfig{Env: "test"}
+	cfg.Database.Password = "hunter2"
+	cfg.Kafka.SASLPassword = "secret://env/KAFKA_PASSWORD"
+	cfg.Tenant.Header = "X Tenant"
+
+	var out bytes.Buffer
+	require.NoError(t, WriteEnv(&out, cfg))
+
+	assert.Contains(t, out.String(), "APP_ENV=test\n")
+	assert.Contains(t, out.String(), "DB_PASSWORD=******\n")
+	assert.Contains(t, out.String(), "KAFKA_SASL_PASSWORD=secret://env/KAFKA_PASSWORD\n")
+	assert.Contains(t, out.String(), "PAGINATION_CURSOR_SECRET=\n")
+	assert.Contains(t, out.String(), "TENANT_HEADER=\"X Tenant\"\n")
+	assert.NotContains(t, out.String(), "hunter2")
+}
+
+func TestWriteEnvExample(t *testing.T) {
+	var out bytes.Buffer
+	require.NoError(t, WriteEnvExample(&out))
+
+	// The example loads as the default configuration
+	inTempDir(t, map[string]string{".env.example": out.String()})
+	cfg, err := LoadConfig(WithFile(".env.example"), environ())
+	require.NoError(t, err)
+	defaults, err := LoadConfig(environ())
+	require.NoError(t, err)
+	assert.Equal(t, defaults, cfg)
+}
@@ -1,60 +1,176 @@
 package utils
 
 import (
-	"log"
-
-	"github.com/caarlos0/env/v11"
-	"github.com/joho/godotenv"
+	"net"
+	"net/url"
+	"strconv"
 )
 
-// AppConfig holds all system-wide configurations
+// AppConfig holds all system-wide configurations. Every field is documented by its
+// description tag, see Fields, and the secret ones are tagged secret:"true".
 type AppConfig struct {
+	Env        string `env:"APP_ENV" envDefault:"development" validate:"oneof=development test production" description:"Environment the process runs in: development, test or production"`
 	Httpserver HttpServerConfig
-	Kafka  KafkaConfig
-	Logger LoggerConfig
+	Kafka      KafkaConfig
+	Logger     LoggerConfig
+	Pagination PaginationConfig
+	SoftDelete SoftDeleteConfig
+	Tenant     TenantConfig
+	Outbox     OutboxConfig
+	Database   DatabaseConfig
+	Migration  MigrationConfig
+	Features   FeaturesConfig
+	Reload     ReloadConfig
+	Secrets    SecretsConfig
 }
 
 // ServerConfig - HTTP server related configs
 type HttpServerConfig struct {
-	Host string `env:"HTTP_HOST" envDefault:"localhost"`
-	Port int    `env:"HTTP_PORT" envDefault:"8080"`
+	Host                  string `env:"HTTP_HOST" envDefault:"localhost" description:"Address the HTTP server listens on, empty for all interfaces"`
+	Port                  int    `env:"HTTP_PORT" envDefault:"8080" validate:"min=1,max=65535" description:"Port the HTTP server listens on"`
+	ShutdownTimeoutInSecs int    `env:"HTTP_SHUTDOWN_TIMEOUT_SECS" envDefault:"15" validate:"gte=0" description:"Grace period for in-flight requests when the server is asked to stop"`
+	// Routes can override it with httpserver.WithRouteTimeout
+	RequestTimeoutInSecs int `env:"HTTP_REQUEST_TIMEOUT_SECS" envDefault:"30" validate:"gte=0" reload:"true" description:"Default deadline of a request, 0 disables it"`
 }
 
 // KafkaConfig - Holds Kafka settings for producer & consumer
 type KafkaConfig struct {
-	Brokers            string `env:"KAFKA_BROKERS" envDefault:"localhost:9092"`
-	ClientID           string `env:"KAFKA_CLIENT_ID" envDefault:"default-client"`
-	GroupID            string `env:"KAFKA_CONSUMER_GROUP_ID" envDefault:"default-group"`
-	AutoOffsetReset    string `env:"KAFKA_CONSUMER_AUTO_OFFSET_RESET" envDefault:"earliest"`
-	EnableAutoCommit   bool   `env:"KAFKA_CONSUMER_ENABLE_AUTO_COMMIT" envDefault:"false"`
-	MaxPollIntervalMs  int    `env:"KAFKA_CONSUMER_MAX_POLL_INTERVAL_MS" envDefault:"300000"`
-	SessionTimeoutMs   int    `env:"KAFKA_CONSUMER_SESSION_TIMEOUT_MS" envDefault:"45000"`
-	HeartbeatIntervalMs int   `env:"KAFKA_CONSUMER_HEARTBEAT_INTERVAL_MS" envDefault:"3000"`
-	RetryBackoffMs     int    `env:"KAFKA_CONSUMER_RETRY_BACKOFF_MS" envDefault:"100"`
-	FetchMinBytes      int    `env:"KAFKA_CONSUMER_FETCH_MIN_BYTES" envDefault:"1"`
-	FetchWaitMaxMs     int    `env:"KAFKA_CONSUMER_FETCH_WAIT_MAX_MS" envDefault:"500"`
-	SchemaRegistryURL  string `env:"KAFKA_SCHEMA_REGISTRY_URL" envDefault:"http://localhost:8081"`
+	Enabled             bool   `env:"KAFKA_ENABLED" envDefault:"false" description:"Publish the domain events relayed from the outbox, the other settings are ignored when disabled"`
+	Brokers             string `env:"KAFKA_BROKERS" envDefault:"localhost:9092" validate:"required,brokers" description:"Comma-separated host:port of the brokers"`
+	ClientID            string `env:"KAFKA_CLIENT_ID" envDefault:"default-client" validate:"required" description:"Client id sent to the brokers, to trace the requests in their logs"`
+	GroupID             string `env:"KAFKA_CONSUMER_GROUP_ID" envDefault:"default-group" validate:"required" description:"Prefix of the consumer groups, each consumer pipeline appends its name, e.g. default-group.company"`
+	AutoOffsetReset     string `env:"KAFKA_CONSUMER_AUTO_OFFSET_RESET" envDefault:"earliest" validate:"oneof=earliest latest none" description:"Where a consumer group without committed offset starts: earliest, latest or none"`
+	EnableAutoCommit    bool   `env:"KAFKA_CONSUMER_ENABLE_AUTO_COMMIT" envDefault:"false" description:"Commit the offsets periodically rather than once a message is handled"`
+	MaxPollIntervalMs   int    `env:"KAFKA_CONSUMER_MAX_POLL_INTERVAL_MS" envDefault:"300000" validate:"min=1,gtefield=SessionTimeoutMs" description:"Longest time between two polls before the consumer leaves its group"`
+	SessionTimeoutMs    int    `env:"KAFKA_CONSUMER_SESSION_TIMEOUT_MS" envDefault:"45000" validate:"min=1" description:"A consumer sending no heartbeat for this long is removed from its group"`
+	HeartbeatIntervalMs int    `env:"KAFKA_CONSUMER_HEARTBEAT_INTERVAL_MS" envDefault:"3000" validate:"min=1,ltfield=SessionTimeoutMs" description:"Interval of the consumer heartbeats, less than the session timeout"`
+	RetryBackoffMs      int    `env:"KAFKA_CONSUMER_RETRY_BACKOFF_MS" envDefault:"100" validate:"gte=0" description:"Wait before retrying a failed request"`
+	FetchMinBytes       int    `env:"KAFKA_CONSUMER_FETCH_MIN_BYTES" envDefault:"1" validate:"min=1" description:"Least data the brokers return to a fetch"`
+	FetchWaitMaxMs      int    `env:"KAFKA_CONSUMER_FETCH_WAIT_MAX_MS" envDefault:"500" validate:"gte=0" description:"Longest time the brokers wait for KAFKA_CONSUMER_FETCH_MIN_BYTES"`
+	SchemaRegistryURL   string `env:"KAFKA_SCHEMA_REGISTRY_URL" envDefault:"http://localhost:8081" validate:"required,url" description:"URL of the schema registry of the Avro messages"`
+	SecurityProtocol    string `env:"KAFKA_SECURITY_PROTOCOL" envDefault:"plaintext" validate:"oneof=plaintext ssl sasl_plaintext sasl_ssl" description:"Protocol of the connections to the brokers: plaintext, ssl, sasl_plaintext or sasl_ssl"`
+	SASLMechanism       string `env:"KAFKA_SASL_MECHANISM" envDefault:"PLAIN" validate:"oneof=PLAIN SCRAM-SHA-256 SCRAM-SHA-512" description:"SASL mechanism: PLAIN, SCRAM-SHA-256 or SCRAM-SHA-512"`
+	SASLUsername        string `env:"KAFKA_SASL_USERNAME" description:"SASL username, it may reference a secret"`
+	SASLPassword        string `env:"KAFKA_SASL_PASSWORD" secret:"true" description:"SASL password, it may reference a secret"`
 }
 
-// LoggerConfig - Logger settings
-type LoggerConfig struct {
-	Level string `env:"LOG_LEVEL" envDefault:"info"`
+// PaginationConfig - List endpoints settings
+type PaginationConfig struct {
+	CursorSecret string `env:"PAGINATION_CURSOR_SECRET" validate:"omitempty,secretmin=32" secret:"true" description:"Secret signing the cursor tokens, shared by all replicas, it may reference a secret. When empty a random secret is used and cursors do not survive a restart"`
+}
+
+// SoftDeleteConfig - Purge of soft-deleted rows
+type SoftDeleteConfig struct {
+	RetentionInDays     int `env:"SOFT_DELETE_RETENTION_DAYS" envDefault:"30" validate:"gte=0" description:"Soft-deleted rows are permanently removed after this many days, 0 disables the purge"`
+	PurgeIntervalInMins int `env:"SOFT_DELETE_PURGE_INTERVAL_MINS" envDefault:"60" validate:"min=1" description:"Interval of the purge of the soft-deleted rows"`
+}
+
+// TenantConfig - Multi-tenancy, how the tenant of a request is resolved
+type TenantConfig struct {
+	Resolvers    string `env:"TENANT_RESOLVERS" validate:"listof=header jwt subdomain" description:"Comma-separated resolvers tried in order among header, jwt and subdomain, empty disables tenant resolution"`
+	Required     bool   `env:"TENANT_REQUIRED" envDefault:"true" description:"Reject the requests naming no tenant"`
+	Header       string `env:"TENANT_HEADER" envDefault:"X-Tenant-ID" description:"Header naming the tenant, for the header resolver"`
+	JWTClaimsKey string `env:"TENANT_JWT_CLAIMS_KEY" envDefault:"claims" description:"Gin context key the authentication middleware stores the verified token claims under"`
+	JWTClaim     string `env:"TENANT_JWT_CLAIM" envDefault:"tenant_id" description:"Claim naming the tenant, for the jwt resolver"`
+	BaseDomain   string `env:"TENANT_BASE_DOMAIN" description:"Tenants are the subdomains of this domain, e.g. acme.example.com for example.com"`
 }
 
-// LoadConfig loads the full app configuration from environment variables
-func LoadConfig() (*AppConfig, error) {
-	cfg := &AppConfig{}
+// OutboxConfig - Relay of the transactional outbox to Kafka
+type OutboxConfig struct {
+	RelayEnabled     bool `env:"OUTBOX_RELAY_ENABLED" envDefault:"true" description:"Relay the outbox, the replicas relaying it take turns"`
+	PollIntervalInMs int  `env:"OUTBOX_POLL_INTERVAL_MS" envDefault:"1000" validate:"min=1" description:"Interval of the polls of the outbox"`
+	BatchSize        int  `env:"OUTBOX_BATCH_SIZE" envDefault:"100" validate:"min=1" description:"Messages relayed per poll"`
+	MaxAttempts      int  `env:"OUTBOX_MAX_ATTEMPTS" envDefault:"10" validate:"min=1" description:"A message still failing after this many attempts is given up on"`
+	RetentionInHours int  `env:"OUTBOX_RETENTION_HOURS" envDefault:"24" validate:"gte=0" description:"Published messages are deleted after this many hours, 0 keeps them"`
+}
+
+// DatabaseConfig - CockroachDB connection. The URI and the password may reference a
+// secret, see SecretsConfig.
+type DatabaseConfig struct {
+	URI      string `env:"DB_URI" validate:"omitempty,dsn" secret:"true" description:"Connection URI, e.g. postgresql://root@localhost:26257/defaultdb?sslmode=disable. When set, the connection parts DB_HOST to DB_SSL_KEY are ignored"`
+	Host     string `env:"DB_HOST" envDefault:"localhost" validate:"required_without=URI" description:"Host of the database"`
+	Port     int    `env:"DB_PORT" envDefault:"26257" validate:"min=1,max=65535" description:"Port of the database"`
+	User     string `env:"DB_USER" envDefault:"root" description:"User of the database"`
+	Password string `env:"DB_PASSWORD" secret:"true" description:"Password of the user"`
+	Name     string `env:"DB_NAME" envDefault:"defaultdb" description:"Name of the database"`
+	SSLMode  string `env:"DB_SSLMODE" envDefault:"disable" validate:"oneof=disable allow prefer require verify-ca verify-full" description:"TLS mode: disable, allow, prefer, require, verify-ca or verify-full"`
+	// For certificate authentication, the client pair
+	SSLRootCert string `env:"DB_SSL_ROOT_CERT" description:"Path of the CA certificate of the cluster"`
+	SSLCert     string `env:"DB_SSL_CERT" validate:"required_with=SSLKey" description:"Path of the client certificate"`
+	SSLKey      string `env:"DB_SSL_KEY" validate:"required_with=SSLCert" description:"Path of the client key"`
+
+	MaxOpenConns          int `env:"DB_MAX_OPEN_CONNS" envDefault:"25" validate:"gte=0" description:"Most open connections, 0 for no limit"`
+	MaxIdleConns          int `env:"DB_MAX_IDLE_CONNS" envDefault:"25" validate:"gte=0" description:"Most idle connections kept open"`
+	ConnMaxLifetimeInSecs int `env:"DB_CONN_MAX_LIFETIME_SECS" envDefault:"300" validate:"gte=0" description:"Connections are closed after this many seconds, 0 keeps them"`
+	ConnMaxIdleTimeInSecs int `env:"DB_CONN_MAX_IDLE_TIME_SECS" envDefault:"60" validate:"gte=0" description:"Idle connections are closed after this many seconds, 0 keeps them"`
+	StatementTimeoutInMs  int `env:"DB_STATEMENT_TIMEOUT_MS" envDefault:"0" validate:"gte=0" description:"Statements running longer are cancelled by the database, 0 disables the timeout"`
+
+	ConnectAttempts             int `env:"DB_CONNECT_ATTEMPTS" envDefault:"10" validate:"min=1" description:"Connecting on startup is attempted this many times"`
+	ConnectRetryIntervalInMs    int `env:"DB_CONNECT_RETRY_INTERVAL_MS" envDefault:"500" validate:"gte=0" description:"Wait after the first failed connection attempt, doubled after each one"`
+	ConnectMaxRetryIntervalInMs int `env:"DB_CONNECT_MAX_RETRY_INTERVAL_MS" envDefault:"5000" validate:"gtefield=ConnectRetryIntervalInMs" description:"Longest wait between two connection attempts"`
+
+	LogLevel               string `env:"DB_LOG_LEVEL" envDefault:"warn" validate:"oneof=silent error warn info" description:"Level of the query logs: silent, error, warn (failed and slow queries) or info (every query, logged at debug)"`
+	SlowQueryThresholdInMs int    `env:"DB_SLOW_QUERY_THRESHOLD_MS" envDefault:"200" validate:"gte=0" description:"Queries slower than this are logged at warn, 0 disables it"`
+}
+
+// ConnectionURI returns URI when set, otherwise the URI built from the connection parts.
+func (c DatabaseConfig) ConnectionURI() string {
+	if c.URI != "" {
+		return c.URI
+	}
+
+	query := url.Values{}
+	query.Set("sslmode", c.SSLMode)
+	if c.SSLRootCert != "" {
+		query.Set("sslrootcert", c.SSLRootCert)
+	}
+	if c.SSLCert != "" {
+		query.Set("sslcert", c.SSLCert)
+	}
+	if c.SSLKey != "" {
+		query.Set("sslkey", c.SSLKey)
+	}
 
-	err := godotenv.Load()
-	if err != nil {
-		log.Println("No .env file found, using system environment variables.")
+	u := url.URL{
+		Scheme:   "postgresql",
+		User:     url.User(c.User),
+		Host:     net.JoinHostPort(c.Host, strconv.Itoa(c.Port)),
+		Path:     "/" + c.Name,
+		RawQuery: query.Encode(),
 	}
-	// Parse environment variables
-	if err := env.Parse(cfg); err != nil {
-		log.Fatalf("Failed to load configuration: %v", err)
-		return nil, err
+	if c.Password != "" {
+		u.User = url.UserPassword(c.User, c.Password)
 	}
+	return u.String()
+}
+
+// MigrationConfig - Database schema migrations
+type MigrationConfig struct {
+	AutoMigrate       bool `env:"DB_AUTO_MIGRATE" envDefault:"true" description:"Apply the pending migrations on startup, otherwise they are run with the migrate command"`
+	LockTimeoutInSecs int  `env:"DB_MIGRATE_LOCK_TIMEOUT_SECS" envDefault:"300" validate:"min=1" description:"How long to wait for another replica holding the migration lock"`
+}
+
+// LoggerConfig - Logger settings
+type LoggerConfig struct {
+	Level string `env:"LOG_LEVEL" envDefault:"info" validate:"oneof=debug info warn error" reload:"true" description:"Level of the logs: debug, info, warn or error"`
+}
+
+// FeaturesConfig - Feature flags, see features.Flags
+type FeaturesConfig struct {
+	Enabled string `env:"FEATURE_FLAGS" reload:"true" description:"Comma-separated names of the enabled features"`
+}
+
+// ReloadConfig - Reload of the configuration, see Reloader. Only the fields tagged
+// reload:"true" are applied, the others need a restart.
+type ReloadConfig struct {
+	IntervalInSecs int `env:"CONFIG_RELOAD_INTERVAL_SECS" envDefault:"5" validate:"gte=0" description:"The configuration files are checked for changes this often, 0 only reloads on SIGHUP"`
+}
 
-	log.Println("Configuration successfully loaded")
-	return cfg, nil
+// SecretsConfig - Secrets referenced by the other fields as secret://<provider>/<path>:
+// secret://file//run/secrets/db_password, secret://env/NAME or, with a key file,
+// secret://encrypted/path/to/file.enc
+type SecretsConfig struct {
+	// See secrets.Encrypt
+	KeyFile               string `env:"SECRETS_KEY_FILE" description:"File holding the base64 key of the encrypted secrets"`
+	RefreshIntervalInSecs int    `env:"SECRETS_REFRESH_INTERVAL_SECS" envDefault:"60" validate:"gte=0" description:"The secrets are read again this often and the rotated ones applied, 0 disables it"`
 }
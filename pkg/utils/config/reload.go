@@ -0,0 +1,239 @@
+package utils
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"reflect"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// region: ======= Reloader =======
+
+// DefaultReloadInterval is how often the Reloader checks the configuration files
+var DefaultReloadInterval = 5 * time.Second
+
+// Change is a reload of the configuration. Old and New only differ by the Fields that
+// changed, which are all reloadable.
+type Change struct {
+	Old    AppConfig
+	New    AppConfig
+	Fields []FieldChange
+}
+
+// Changed reports whether one of the fields named by their environment variable changed.
+func (c Change) Changed(envs ...string) bool {
+	for _, field := range c.Fields {
+		for _, env := range envs {
+			if field.Env == env {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// FieldChange is a field whose value changed, named by its environment variable.
+type FieldChange struct {
+	Env        string
+	Old        interface{}
+	New        interface{}
+	Reloadable bool
+}
+
+// Reloader reloads the configuration when its files change or the process receives
+// SIGHUP. The new configuration is validated, then its reloadable fields, tagged
+// reload:"true", are applied and the subscribers notified. The other fields keep their
+// value until a restart, a warning names them.
+type Reloader struct {
+	loadOpts []LoadOption
+	interval time.Duration
+
+	mu          sync.Mutex
+	current     AppConfig
+	subscribers []func(Change)
+
+	// stamps are the modification times and sizes of the files on the last check
+	stamps   map[string]fileStamp
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+type ReloaderOption func(*Reloader)
+
+// WithLoadOptions sets the sources of the configuration, as given to LoadConfig.
+func WithLoadOptions(opts ...LoadOption) ReloaderOption {
+	return func(r *Reloader) {
+		r.loadOpts = opts
+	}
+}
+
+// WithReloadInterval sets how often the files are checked, 0 only reloads on SIGHUP.
+func WithReloadInterval(interval time.Duration) ReloaderOption {
+	return func(r *Reloader) {
+		r.interval = interval
+	}
+}
+
+// NewReloader returns a Reloader of cfg, the configuration loaded on startup.
+func NewReloader(cfg AppConfig, opts ...ReloaderOption) *Reloader {
+	r := &Reloader{
+		interval: DefaultReloadInterval,
+		current:  cfg,
+		stop:     make(chan struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+	r.stamps = r.stat()
+	return r
+}
+
+// Current returns the configuration in use, with the reloaded fields.
+func (r *Reloader) Current() AppConfig {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.current
+}
+
+// Subscribe calls fn after every reload changing a reloadable field.
+func (r *Reloader) Subscribe(fn func(Change)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.subscribers = append(r.subscribers, fn)
+}
+
+// Reload loads the configuration again and applies its reloadable fields. When it is
+// invalid the configuration in use is kept and the error returned.
+func (r *Reloader) Reload() error {
+	next, err := LoadConfig(r.loadOpts...)
+	if err != nil {
+		log.Printf("Configuration not reloaded: %s", err)
+		return err
+	}
+
+	r.mu.Lock()
+	old := r.current
+	applied := old
+	var changes []FieldChange
+	for _, field := range diffConfig(reflect.ValueOf(&applied).Elem(), reflect.ValueOf(next).Elem()) {
+		if !field.Reloadable {
+			log.Printf("Configuration field %s changed, restart to apply it", field.Env)
+			continue
+		}
+		changes = append(changes, field)
+	}
+	r.current = applied
+	subscribers := append([]func(Change){}, r.subscribers...)
+	r.mu.Unlock()
+
+	if len(changes) == 0 {
+		return nil
+	}
+	change := Change{Old: old, New: applied, Fields: changes}
+	names := make([]string, len(changes))
+	for i, field := range changes {
+		names[i] = field.Env
+	}
+	log.Printf("Configuration reloaded: %s", strings.Join(names, ", "))
+
+	for _, fn := range subscribers {
+		fn(change)
+	}
+	return nil
+}
+
+// Start reloads the configuration on SIGHUP and when its files change, until ctx is
+// cancelled or Stop is called.
+func (r *Reloader) Start(ctx context.Context) error {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	defer signal.Stop(hangup)
+
+	var tick <-chan time.Time
+	if r.interval > 0 {
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-r.stop:
+			return nil
+		case <-hangup:
+			r.stamps = r.stat()
+			_ = r.Reload()
+		case <-tick:
+			stamps := r.stat()
+			if reflect.DeepEqual(stamps, r.stamps) {
+				continue
+			}
+			r.stamps = stamps
+			_ = r.Reload()
+		}
+	}
+}
+
+func (r *Reloader) Stop(_ context.Context) error {
+	r.stopOnce.Do(func() { close(r.stop) })
+	return nil
+}
+
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// stat returns the stamps of the configuration files, a missing file has none so that
+// creating it is a change
+func (r *Reloader) stat() map[string]fileStamp {
+	l := loader{}
+	for _, opt := range r.loadOpts {
+		opt(&l)
+	}
+
+	stamps := map[string]fileStamp{}
+	for _, file := range l.files(r.Current().Env) {
+		if info, err := os.Stat(file); err == nil {
+			stamps[file] = fileStamp{modTime: info.ModTime(), size: info.Size()}
+		}
+	}
+	return stamps
+}
+
+// diffConfig returns the fields of next differing from cur, and sets the reloadable ones
+// in cur
+func diffConfig(cur, next reflect.Value) []FieldChange {
+	var changes []FieldChange
+	for i := 0; i < cur.NumField(); i++ {
+		field := cur.Type().Field(i)
+		if field.Type.Kind() == reflect.Struct {
+			changes = append(changes, diffConfig(cur.Field(i), next.Field(i))...)
+			continue
+		}
+
+		env, _, _ := strings.Cut(field.Tag.Get("env"), ",")
+		if env == "" || reflect.DeepEqual(cur.Field(i).Interface(), next.Field(i).Interface()) {
+			continue
+		}
+		change := FieldChange{
+			Env:        env,
+			Old:        cur.Field(i).Interface(),
+			New:        next.Field(i).Interface(),
+			Reloadable: field.Tag.Get("reload") == "true",
+		}
+		if change.Reloadable {
+			cur.Field(i).Set(next.Field(i))
+		}
+		changes = append(changes, change)
+	}
+	return changes
+}
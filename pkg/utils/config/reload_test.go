@@ -0,0 +1,77 @@
+package utils
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestReloader(t *testing.T, opts ...ReloaderOption) *Reloader {
+	t.Helper()
+	loadOpts := []LoadOption{WithFile("config.yaml"), environ()}
+	cfg, err := LoadConfig(loadOpts...)
+	require.NoError(t, err)
+	return NewReloader(*cfg, append([]ReloaderOption{WithLoadOptions(loadOpts...)}, opts...)...)
+}
+
+func TestReloader_Reload(t *testing.T) {
+	inTempDir(t, map[string]string{
+		"config.yaml": "logger:\n  level: info\nhttpserver:\n  port: 8080\n",
+	})
+	reloader := newTestReloader(t)
+
+	var changes []Change
+	reloader.Subscribe(func(change Change) { changes = append(changes, change) })
+
+	// Only the reloadable fields are applied
+	require.NoError(t, os.WriteFile("config.yaml", []byte("logger:\n  level: debug\nhttpserver:\n  port: 9090\n"), 0o644))
+	require.NoError(t, reloader.Reload())
+
+	require.Len(t, changes, 1)
+	assert.Equal(t, []FieldChange{{Env: "LOG_LEVEL", Old: "info", New: "debug", Reloadable: true}}, changes[0].Fields)
+	assert.True(t, changes[0].Changed("LOG_LEVEL"))
+	assert.False(t, changes[0].Changed("HTTP_PORT"))
+	assert.Equal(t, "info", changes[0].Old.Logger.Level)
+	assert.Equal(t, "debug", changes[0].New.Logger.Level)
+	assert.Equal(t, 8080, changes[0].New.Httpserver.Port)
+	assert.Equal(t, changes[0].New, reloader.Current())
+
+	// Nothing reloadable changed
+	require.NoError(t, reloader.Reload())
+	assert.Len(t, changes, 1)
+
+	// An invalid configuration is not applied
+	require.NoError(t, os.WriteFile("config.yaml", []byte("logger:\n  level: trace\n"), 0o644))
+	var validationErr *ValidationError
+	require.ErrorAs(t, reloader.Reload(), &validationErr)
+	assert.Len(t, changes, 1)
+	assert.Equal(t, "debug", reloader.Current().Logger.Level)
+}
+
+func TestReloader_Start(t *testing.T) {
+	inTempDir(t, map[string]string{
+		"config.yaml": "features:\n  enabled: search\n",
+	})
+	reloader := newTestReloader(t, WithReloadInterval(10*time.Millisecond))
+
+	reloaded := make(chan Change, 1)
+	reloader.Subscribe(func(change Change) { reloaded <- change })
+
+	done := make(chan error, 1)
+	go func() { done <- reloader.Start(context.Background()) }()
+
+	require.NoError(t, os.WriteFile("config.yaml", []byte("features:\n  enabled: search,export\n"), 0o644))
+	select {
+	case change := <-reloaded:
+		assert.Equal(t, "search,export", change.New.Features.Enabled)
+	case <-time.After(time.Second):
+		t.Fatal("the configuration was not reloaded")
+	}
+
+	require.NoError(t, reloader.Stop(context.Background()))
+	assert.NoError(t, <-done)
+}
@@ -0,0 +1,210 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"proposal-template/pkg/secrets"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// region: ======= Validation =======
+
+// ValidationError lists every invalid field of a configuration.
+type ValidationError struct {
+	Fields []FieldError
+}
+
+// FieldError is an invalid field, named by its environment variable.
+type FieldError struct {
+	Env     string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "invalid configuration, %d error(s):", len(e.Fields))
+	for _, field := range e.Fields {
+		fmt.Fprintf(&b, "\n  %s: %s", field.Env, field.Message)
+	}
+	return b.String()
+}
+
+// Validate checks the validate tags of the configuration and the rules spanning several
+// fields. It returns a *ValidationError listing every violation.
+func (c *AppConfig) Validate() error {
+	return validateConfig(c)
+}
+
+var configValidator = newConfigValidator()
+
+// newConfigValidator returns a validator naming the fields by their environment variable,
+// with the rules of the configuration:
+//
+//   - brokers: comma-separated host:port list
+//   - dsn: postgresql:// connection URI or secret reference
+//   - listof: comma-separated list of the space-separated values of the param
+//   - secretmin: at least param characters long or a secret reference, whose secret is
+//     checked once resolved
+func newConfigValidator() *validator.Validate {
+	v := validator.New(validator.WithRequiredStructEnabled())
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		if name, _, _ := strings.Cut(field.Tag.Get("env"), ","); name != "" {
+			return name
+		}
+		return field.Name
+	})
+	v.RegisterValidation("brokers", func(fl validator.FieldLevel) bool {
+		return isBrokerList(fl.Field().String())
+	})
+	v.RegisterValidation("dsn", func(fl validator.FieldLevel) bool {
+		if secrets.IsReference(fl.Field().String()) {
+			return true
+		}
+		u, err := url.Parse(fl.Field().String())
+		return err == nil && (u.Scheme == "postgresql" || u.Scheme == "postgres") && u.Host != ""
+	})
+	v.RegisterValidation("listof", func(fl validator.FieldLevel) bool {
+		allowed := strings.Fields(fl.Param())
+		for _, item := range strings.Split(fl.Field().String(), ",") {
+			if item = strings.TrimSpace(item); item != "" && !slices.Contains(allowed, item) {
+				return false
+			}
+		}
+		return true
+	})
+	v.RegisterValidation("secretmin", func(fl validator.FieldLevel) bool {
+		if secrets.IsReference(fl.Field().String()) {
+			return true
+		}
+		n, err := strconv.Atoi(fl.Param())
+		return err == nil && len(fl.Field().String()) >= n
+	})
+	v.RegisterStructValidation(validateTenant, TenantConfig{})
+	return v
+}
+
+// validateConfig validates cfg, a configuration struct with env and validate tags
+func validateConfig(cfg interface{}) error {
+	err := configValidator.Struct(cfg)
+	var fieldErrors validator.ValidationErrors
+	if !errors.As(err, &fieldErrors) {
+		return err
+	}
+
+	root := reflect.Indirect(reflect.ValueOf(cfg)).Type()
+	fields := make([]FieldError, len(fieldErrors))
+	for i, fe := range fieldErrors {
+		fields[i] = FieldError{Env: fe.Field(), Message: fieldMessage(root, fe)}
+	}
+	return &ValidationError{Fields: fields}
+}
+
+// validateTenant requires the settings of the tenant resolvers in use
+func validateTenant(sl validator.StructLevel) {
+	cfg := sl.Current().Interface().(TenantConfig)
+	for _, resolver := range strings.Split(cfg.Resolvers, ",") {
+		switch strings.TrimSpace(resolver) {
+		case "header":
+			if cfg.Header == "" {
+				sl.ReportError(cfg.Header, "TENANT_HEADER", "Header", "resolver", "header")
+			}
+		case "jwt":
+			if cfg.JWTClaimsKey == "" {
+				sl.ReportError(cfg.JWTClaimsKey, "TENANT_JWT_CLAIMS_KEY", "JWTClaimsKey", "resolver", "jwt")
+			}
+			if cfg.JWTClaim == "" {
+				sl.ReportError(cfg.JWTClaim, "TENANT_JWT_CLAIM", "JWTClaim", "resolver", "jwt")
+			}
+		case "subdomain":
+			if cfg.BaseDomain == "" {
+				sl.ReportError(cfg.BaseDomain, "TENANT_BASE_DOMAIN", "BaseDomain", "resolver", "subdomain")
+			}
+		}
+	}
+}
+
+// fieldMessage explains why the field of fe is invalid. The rules comparing fields name
+// the other field by its environment variable.
+func fieldMessage(root reflect.Type, fe validator.FieldError) string {
+	param := fe.Param()
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "required_with", "required_without":
+		other := siblingEnv(root, fe, param)
+		if fe.Tag() == "required_with" {
+			return fmt.Sprintf("is required when %s is set", other)
+		}
+		return fmt.Sprintf("is required when %s is not set", other)
+	case "resolver":
+		return fmt.Sprintf("is required by the %s tenant resolver", param)
+	case "min", "gte":
+		if fe.Kind() == reflect.String {
+			return fmt.Sprintf("must be at least %s characters long", param)
+		}
+		return fmt.Sprintf("must be at least %s, got %v", param, fe.Value())
+	case "max", "lte":
+		return fmt.Sprintf("must be at most %s, got %v", param, fe.Value())
+	case "oneof":
+		return fmt.Sprintf("must be one of %s, got %q", strings.Join(strings.Fields(param), ", "), fe.Value())
+	case "listof":
+		return fmt.Sprintf("must be a comma-separated list of %s, got %q", strings.Join(strings.Fields(param), ", "), fe.Value())
+	case "ltfield":
+		return fmt.Sprintf("must be less than %s, got %v", siblingEnv(root, fe, param), fe.Value())
+	case "gtefield":
+		return fmt.Sprintf("must be greater than or equal to %s, got %v", siblingEnv(root, fe, param), fe.Value())
+	case "url":
+		return fmt.Sprintf("must be a URL, got %q", fe.Value())
+	case "dsn":
+		// The URI may hold a password, it is not echoed
+		return "must be a postgresql:// connection URI or a secret reference"
+	case "secretmin":
+		// The value is a secret, it is not echoed
+		return fmt.Sprintf("must be at least %s characters long or a secret reference", param)
+	case "brokers":
+		return fmt.Sprintf("must be a comma-separated list of host:port, got %q", fe.Value())
+	}
+	return fmt.Sprintf("fails the %s rule", fe.Tag())
+}
+
+// siblingEnv returns the environment variable of the field name, in the struct of the
+// field of fe
+func siblingEnv(root reflect.Type, fe validator.FieldError, name string) string {
+	path := strings.Split(fe.StructNamespace(), ".")
+	t := root
+	// The namespace starts with the root struct and ends with the field itself
+	for _, part := range path[1 : len(path)-1] {
+		field, ok := t.FieldByName(part)
+		if !ok {
+			return name
+		}
+		t = field.Type
+	}
+	if field, ok := t.FieldByName(name); ok {
+		if env, _, _ := strings.Cut(field.Tag.Get("env"), ","); env != "" {
+			return env
+		}
+	}
+	return name
+}
+
+func isBrokerList(brokers string) bool {
+	for _, broker := range strings.Split(brokers, ",") {
+		host, port, err := net.SplitHostPort(strings.TrimSpace(broker))
+		if err != nil || host == "" {
+			return false
+		}
+		if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+			return false
+		}
+	}
+	return true
+}
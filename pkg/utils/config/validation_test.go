@@ -0,0 +1,95 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAppConfig_ValidateDefaults(t *testing.T) {
+	inTempDir(t, nil)
+
+	_, err := LoadConfig(environ())
+	assert.NoError(t, err)
+}
+
+func TestAppConfig_ValidateSecretReferences(t *testing.T) {
+	inTempDir(t, nil)
+
+	// The secrets are checked once resolved
+	_, err := LoadConfig(WithOverrides("DB_URI=secret://env/DB_URI", "PAGINATION_CURSOR_SECRET=secret://file//run/secrets/cursor"), environ())
+	assert.NoError(t, err)
+}
+
+func TestAppConfig_Validate(t *testing.T) {
+	inTempDir(t, nil)
+
+	tests := map[string]struct {
+		overrides []string
+		want      []FieldError
+	}{
+		"range": {
+			overrides: []string{"HTTP_PORT=0", "DB_CONNECT_ATTEMPTS=0"},
+			want: []FieldError{
+				{Env: "HTTP_PORT", Message: "must be at least 1, got 0"},
+				{Env: "DB_CONNECT_ATTEMPTS", Message: "must be at least 1, got 0"},
+			},
+		},
+		"enum": {
+			overrides: []string{"KAFKA_CONSUMER_AUTO_OFFSET_RESET=earlist", "TENANT_RESOLVERS=header,cookie"},
+			want: []FieldError{
+				{Env: "KAFKA_CONSUMER_AUTO_OFFSET_RESET", Message: `must be one of earliest, latest, none, got "earlist"`},
+				{Env: "TENANT_RESOLVERS", Message: `must be a comma-separated list of header, jwt, subdomain, got "header,cookie"`},
+			},
+		},
+		"format": {
+			overrides: []string{
+				"KAFKA_BROKERS=localhost",
+				"KAFKA_SCHEMA_REGISTRY_URL=registry",
+				"DB_URI=mysql://root:secret@db/app",
+				"PAGINATION_CURSOR_SECRET=short",
+			},
+			want: []FieldError{
+				{Env: "KAFKA_BROKERS", Message: `must be a comma-separated list of host:port, got "localhost"`},
+				{Env: "KAFKA_SCHEMA_REGISTRY_URL", Message: `must be a URL, got "registry"`},
+				{Env: "DB_URI", Message: "must be a postgresql:// connection URI or a secret reference"},
+				{Env: "PAGINATION_CURSOR_SECRET", Message: "must be at least 32 characters long or a secret reference"},
+			},
+		},
+		"cross field": {
+			overrides: []string{
+				"KAFKA_CONSUMER_HEARTBEAT_INTERVAL_MS=45000",
+				"KAFKA_CONSUMER_MAX_POLL_INTERVAL_MS=1000",
+				"DB_SSL_KEY=/certs/client.key",
+				"TENANT_RESOLVERS=subdomain",
+			},
+			want: []FieldError{
+				{Env: "KAFKA_CONSUMER_MAX_POLL_INTERVAL_MS", Message: "must be greater than or equal to KAFKA_CONSUMER_SESSION_TIMEOUT_MS, got 1000"},
+				{Env: "KAFKA_CONSUMER_HEARTBEAT_INTERVAL_MS", Message: "must be less than KAFKA_CONSUMER_SESSION_TIMEOUT_MS, got 45000"},
+				{Env: "TENANT_BASE_DOMAIN", Message: "is required by the subdomain tenant resolver"},
+				{Env: "DB_SSL_CERT", Message: "is required when DB_SSL_KEY is set"},
+			},
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := LoadConfig(WithOverrides(tt.overrides...), environ())
+
+			var validationErr *ValidationError
+			require.ErrorAs(t, err, &validationErr)
+			assert.ElementsMatch(t, tt.want, validationErr.Fields)
+		})
+	}
+}
+
+func TestValidationError_Error(t *testing.T) {
+	err := &ValidationError{Fields: []FieldError{
+		{Env: "HTTP_PORT", Message: "must be at least 1, got 0"},
+		{Env: "LOG_LEVEL", Message: `must be one of debug, info, warn, error, got "trace"`},
+	}}
+
+	assert.Equal(t, `invalid configuration, 2 error(s):
+  HTTP_PORT: must be at least 1, got 0
+  LOG_LEVEL: must be one of debug, info, warn, error, got "trace"`, err.Error())
+}
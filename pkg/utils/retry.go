@@ -1,18 +1,67 @@
 package utils
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"time"
+
+	"proposal-template/pkg/logger"
 )
 
-// retry with return type generic
-func Retry[T any](attempts int, sleep time.Duration, f func() (T, error)) (result T, err error) {
+// RetryOption configures Retry.
+type RetryOption func(*retryOptions)
+
+type retryOptions struct {
+	ctx         context.Context
+	maxInterval time.Duration
+	logger      logger.ILogger
+}
+
+// WithRetryContext stops waiting between attempts once ctx is done, Retry then returns
+// the context error.
+func WithRetryContext(ctx context.Context) RetryOption {
+	return func(o *retryOptions) {
+		o.ctx = ctx
+	}
+}
+
+// WithMaxInterval caps the wait between two attempts, 0 leaves it unbounded.
+func WithMaxInterval(interval time.Duration) RetryOption {
+	return func(o *retryOptions) {
+		o.maxInterval = interval
+	}
+}
+
+// WithRetryLogger logs the failed attempts to logger rather than the standard logger.
+func WithRetryLogger(logger logger.ILogger) RetryOption {
+	return func(o *retryOptions) {
+		o.logger = logger
+	}
+}
+
+// Retry calls f until it succeeds, at most attempts times. The wait after the first
+// failure is sleep, doubled after each one up to the maximum interval.
+func Retry[T any](attempts int, sleep time.Duration, f func() (T, error), opts ...RetryOption) (result T, err error) {
+	o := retryOptions{ctx: context.Background()}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
 	for i := 0; i < attempts; i++ {
 		if i > 0 {
-			log.Printf("Failed to execute function, attempt %d/%d: %s\n", i, attempts, err)
-			log.Printf("Sleeping for %s\n", sleep)
-			time.Sleep(sleep)
+			if o.maxInterval > 0 {
+				sleep = min(sleep, o.maxInterval)
+			}
+			o.log(fmt.Sprintf("Failed to execute function, attempt %d/%d, retrying in %s: %s", i, attempts, sleep, err))
+
+			timer := time.NewTimer(sleep)
+			select {
+			case <-o.ctx.Done():
+				timer.Stop()
+				return result, fmt.Errorf("%w, after %d attempts, last error: %w", o.ctx.Err(), i, err)
+			case <-timer.C:
+			}
 			sleep *= 2
 		}
 		result, err = f()
@@ -20,5 +69,13 @@ func Retry[T any](attempts int, sleep time.Duration, f func() (T, error)) (resul
 			return result, nil
 		}
 	}
-	return result, fmt.Errorf("after %d attempts, last error: %s", attempts, err)
+	return result, fmt.Errorf("after %d attempts, last error: %w", attempts, err)
+}
+
+func (o retryOptions) log(msg string) {
+	if o.logger != nil {
+		o.logger.Warn(msg)
+		return
+	}
+	log.Println(msg)
 }
@@ -0,0 +1,51 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetry_CapsTheInterval(t *testing.T) {
+	calls := 0
+	start := time.Now()
+	_, err := Retry(4, 20*time.Millisecond, func() (int, error) {
+		calls++
+		return 0, errors.New("unavailable")
+	}, WithMaxInterval(25*time.Millisecond))
+
+	assert.Error(t, err)
+	assert.Equal(t, 4, calls)
+	// 20 + 25 + 25 rather than 20 + 40 + 80
+	assert.Less(t, time.Since(start), 130*time.Millisecond)
+}
+
+func TestRetry_StopsWhenTheContextIsDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	calls := 0
+	_, err := Retry(3, time.Hour, func() (int, error) {
+		calls++
+		cancel()
+		return 0, errors.New("unavailable")
+	}, WithRetryContext(ctx))
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 1, calls)
+}
+
+func TestRetry_ReturnsTheResult(t *testing.T) {
+	calls := 0
+	result, err := Retry(3, time.Millisecond, func() (int, error) {
+		calls++
+		if calls < 2 {
+			return 0, errors.New("unavailable")
+		}
+		return 42, nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 42, result)
+}
@@ -1,20 +1,26 @@
 package presentation
 
 import (
-	// "proposal-template/pkg/logger"
+	"context"
+	"time"
+
+	"proposal-template/datalayers/datasources/repositories"
+	"proposal-template/pkg/lifecycle"
+	"proposal-template/pkg/logger"
+	"proposal-template/pkg/secrets"
+	utils "proposal-template/pkg/utils/config"
 	httpserver "proposal-template/presentation/http"
-	"sync"
 
 	"github.com/golobby/container/v3"
 )
 
 type server struct {
-	httpServer httpserver.HTTPServer
-	//grpcServer...
-	//...
+	supervisor *lifecycle.Supervisor
 }
 
-func NewServer() *server {
+// NewServer returns the process serving the HTTP API. With workers it also runs the
+// background jobs, otherwise they are left to a process started with NewWorker.
+func NewServer(workers bool) *server {
 
 	var hs *httpserver.HTTPServer
 	err := container.Resolve(&hs)
@@ -22,42 +28,84 @@ func NewServer() *server {
 		panic(err)
 	}
 
+	supervisor := newSupervisor(hs.ShutdownTimeout())
+
+	// Every server is a lifecycle.Runnable, adding one is a single registration, for example
+	// supervisor.Register("grpc", grpcServer)
+	supervisor.Register("http", hs)
+
+	if workers {
+		registerWorkers(supervisor)
+	}
+	registerResourceClosers(supervisor)
+
+	return &server{
+		supervisor: supervisor,
+	}
+}
+
+// NewWorker returns the process running the background jobs only.
+func NewWorker() *server {
+	supervisor := newSupervisor(lifecycle.DefaultShutdownTimeout)
+	registerWorkers(supervisor)
+	registerResourceClosers(supervisor)
+
+	return &server{
+		supervisor: supervisor,
+	}
+}
+
+// NewConsumer returns the process running the Kafka consumer pipeline named name.
+func NewConsumer(name string, consumer lifecycle.Runnable) *server {
+	supervisor := newSupervisor(lifecycle.DefaultShutdownTimeout)
+	supervisor.Register(name+" consumer", consumer)
+	registerResourceClosers(supervisor)
+
 	return &server{
-		httpServer: *hs,
+		supervisor: supervisor,
 	}
 }
 
-// Run starts all the servers in a separate goroutine and waits for any one of them to return an error.
-func (s *server) Run() error {
-	var wg sync.WaitGroup
-
-
-	errChan := make(chan error, 1)
-
-	wg.Add(1) 
-	//If there are more than http server, this "1" value should be increased, for example
-	// if we added a grpc server, this value should be "2"
-	go func ()  {
-		defer wg.Done()
-		if err := s.httpServer.Start(); err != nil {
-			errChan <- err
-		} else {
-			errChan <- nil
-		}
-	}()
-	
-	//== if there are more than a server running, we add a goroutine like above
-	//== example
-	// go func ()  {
-	// 	defer wg.Done()
-	// 	if err := s.grpcServer.Start(); err != nil {
-	// 		errChan <- err
-	// 	} else {
-	// 		errChan <- nil
-	// 	}
-	// }
-
-	wg.Wait()
-	err := <-errChan
-	return err
-}
\ No newline at end of file
+func newSupervisor(shutdownTimeout time.Duration) *lifecycle.Supervisor {
+	var log logger.ILogger
+	err := container.Resolve(&log)
+	if err != nil {
+		panic(err)
+	}
+
+	supervisor := lifecycle.NewSupervisor(
+		log,
+		lifecycle.WithShutdownTimeout(shutdownTimeout),
+	)
+
+	// Every process applies the reloadable fields of the configuration and the rotated secrets
+	var reloader *utils.Reloader
+	if err := container.Resolve(&reloader); err == nil {
+		supervisor.Register("config reload", reloader)
+	}
+	var secretManager *secrets.Manager
+	if err := container.Resolve(&secretManager); err == nil {
+		supervisor.Register("secrets refresh", secretManager)
+	}
+	return supervisor
+}
+
+// registerWorkers registers the background jobs, they are only registered in the
+// container when enabled
+func registerWorkers(supervisor *lifecycle.Supervisor) {
+	var purgeJob *repositories.PurgeJob
+	if err := container.Resolve(&purgeJob); err == nil {
+		supervisor.Register("purge", purgeJob)
+	}
+	var outboxRelay *repositories.OutboxRelay
+	if err := container.Resolve(&outboxRelay); err == nil {
+		supervisor.Register("outbox relay", outboxRelay)
+	}
+}
+
+// Run starts all the components and blocks until one of them fails or ctx is cancelled,
+// on SIGINT/SIGTERM. The components are then drained within the shutdown grace period and
+// the infrastructure registered in the container is closed.
+func (s *server) Run(ctx context.Context) error {
+	return s.supervisor.Run(ctx)
+}
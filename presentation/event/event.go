@@ -0,0 +1,47 @@
+// Package event lists the domain events published to Kafka with their topic and Avro schema.
+package event
+
+import (
+	"embed"
+
+	model "proposal-template/models"
+)
+
+// SchemasFS holds the Avro schemas of the events, registered at startup with
+// kafka.SchemaRegistry.FindOrCreateArvoSchema
+//
+//go:embed schemas/*.avsc
+var SchemasFS embed.FS
+
+// Event describes how a domain event is published.
+type Event struct {
+	// Name is the name of the model.DomainEvent
+	Name  string
+	Topic string
+	// SchemaFile is the path of the Avro schema in SchemasFS
+	SchemaFile string
+	// New returns a pointer to the record the event is decoded into from the outbox
+	New func() interface{}
+}
+
+// UserEvents are the events emitted by biz.UserService
+var UserEvents = []Event{
+	{
+		Name:       model.EventUserCreated,
+		Topic:      "user.created",
+		SchemaFile: "schemas/user_created.avsc",
+		New:        func() interface{} { return new(model.UserCreated) },
+	},
+	{
+		Name:       model.EventUserUpdated,
+		Topic:      "user.updated",
+		SchemaFile: "schemas/user_updated.avsc",
+		New:        func() interface{} { return new(model.UserUpdated) },
+	},
+	{
+		Name:       model.EventEmailVerified,
+		Topic:      "user.email-verified",
+		SchemaFile: "schemas/email_verified.avsc",
+		New:        func() interface{} { return new(model.EmailVerified) },
+	},
+}
@@ -0,0 +1,52 @@
+package event
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	model "proposal-template/models"
+
+	"github.com/google/uuid"
+	"github.com/hamba/avro/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestUserEvents_MatchSchemas encodes each event with its schema and decodes it back, as
+// the Kafka serializer and a consumer would.
+func TestUserEvents_MatchSchemas(t *testing.T) {
+	user := model.User{
+		BaseModel: model.BaseModel{
+			Id:        uuid.New(),
+			CreatedAt: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+			UpdatedAt: time.Date(2024, 5, 2, 12, 0, 0, 0, time.UTC),
+		},
+		Versioned:     model.Versioned{Version: 2},
+		Name:          "Jane",
+		Email:         "jane@example.com",
+		EmailVerified: true,
+	}
+	events := map[string]model.DomainEvent{
+		model.EventUserCreated:   model.NewUserCreated(user),
+		model.EventUserUpdated:   model.NewUserUpdated(user),
+		model.EventEmailVerified: model.NewEmailVerified(user),
+	}
+
+	require.Len(t, UserEvents, len(events))
+	for _, e := range UserEvents {
+		t.Run(e.Name, func(t *testing.T) {
+			data, err := SchemasFS.ReadFile(e.SchemaFile)
+			require.NoError(t, err)
+			schema, err := avro.Parse(string(data))
+			require.NoError(t, err)
+
+			encoded, err := avro.Marshal(schema, events[e.Name])
+			require.NoError(t, err)
+
+			decoded := e.New()
+			require.NoError(t, avro.Unmarshal(schema, encoded, decoded))
+			assert.Equal(t, events[e.Name], reflect.ValueOf(decoded).Elem().Interface())
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	model "proposal-template/models"
+	"proposal-template/pkg/logger"
+	"proposal-template/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// StatusClientClosedRequest is the non-standard status (introduced by nginx) returned when
+// the client went away before the response was ready.
+const StatusClientClosedRequest = 499
+
+// errorStatuses maps the biz errors that are safe to expose to their HTTP status code.
+// Any other error is reported as ErrUnknown with a 500.
+var errorStatuses = map[*utils.CustomError]int{
+	model.ErrMalformedJSON:   http.StatusBadRequest,
+	model.ErrInvalidInput:    http.StatusBadRequest,
+	model.ErrInvalidID:       http.StatusBadRequest,
+	model.ErrInvalidFilter:   http.StatusBadRequest,
+	model.ErrUnimplemented:   http.StatusNotImplemented,
+	model.ErrRequestCanceled: StatusClientClosedRequest,
+	model.ErrRequestTimeout:  http.StatusGatewayTimeout,
+	model.ErrConflict:        http.StatusConflict,
+	model.ErrTenantRequired:  http.StatusBadRequest,
+	model.ErrCrossTenant:     http.StatusForbidden,
+
+	model.ErrPreconditionFailed: http.StatusPreconditionFailed,
+
+	model.ErrUserNotFound:      http.StatusNotFound,
+	model.ErrEmailNotAvailable: http.StatusConflict,
+}
+
+// toHTTPError converts an error returned by the biz layer into a status code and the error
+// sent to the client. Context cancellation and deadlines take precedence over whatever
+// error the aborted query produced.
+func toHTTPError(err error) (int, *utils.CustomError) {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout, model.ErrRequestTimeout
+	case errors.Is(err, context.Canceled):
+		return StatusClientClosedRequest, model.ErrRequestCanceled
+	}
+
+	var customErr *utils.CustomError
+	if errors.As(err, &customErr) {
+		if status, ok := errorStatuses[customErr]; ok {
+			return status, customErr
+		}
+	}
+	return http.StatusInternalServerError, model.ErrUnknown
+}
+
+// abortWithError writes the JSON error response for err and logs it. Server errors are
+// logged at error level, client-side ones at warn level.
+func abortWithError(ctx *gin.Context, log logger.ILogger, msg string, err error) {
+	status, customErr := toHTTPError(err)
+	if status >= http.StatusInternalServerError && status != http.StatusGatewayTimeout {
+		log.Error(msg + ": " + err.Error())
+	} else {
+		log.Warn(msg + ": " + err.Error())
+	}
+
+	ctx.AbortWithStatusJSON(status, gin.H{"error": customErr})
+}
@@ -0,0 +1,53 @@
+package handler
+
+import (
+	"strconv"
+	"strings"
+
+	model "proposal-template/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// etag returns the entity tag of a resource at version.
+func etag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// ifMatchVersions returns the versions named by the If-Match header, or nil when the
+// header is absent or "*". If-Match uses the strong comparison: weak tags and tags naming
+// no valid version are left out, so a header without any strong tag matches no version.
+func ifMatchVersions(ctx *gin.Context) model.VersionMatch {
+	header := strings.TrimSpace(ctx.GetHeader("If-Match"))
+	if header == "" || header == "*" {
+		return nil
+	}
+
+	versions := model.VersionMatch{}
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if strings.HasPrefix(tag, "W/") {
+			continue
+		}
+		if version, err := strconv.ParseInt(strings.Trim(tag, `"`), 10, 64); err == nil {
+			versions = append(versions, version)
+		}
+	}
+	return versions
+}
+
+// noneMatch reports whether the If-None-Match header of the request excludes the
+// resource tagged with tag, using the weak comparison.
+func noneMatch(ctx *gin.Context, tag string) bool {
+	header := ctx.GetHeader("If-None-Match")
+	if header == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == tag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,41 @@
+package handler
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	model "proposal-template/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func newContext(header, value string) *gin.Context {
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ctx.Request = httptest.NewRequest("GET", "/users/1", nil)
+	if value != "" {
+		ctx.Request.Header.Set(header, value)
+	}
+	return ctx
+}
+
+func TestIfMatchVersions(t *testing.T) {
+	assert.Nil(t, ifMatchVersions(newContext("If-Match", "")))
+	assert.Nil(t, ifMatchVersions(newContext("If-Match", "*")))
+	assert.Equal(t, model.VersionMatch{3}, ifMatchVersions(newContext("If-Match", `"3"`)))
+	assert.Equal(t, model.VersionMatch{3, 4}, ifMatchVersions(newContext("If-Match", `"3", "4"`)))
+	assert.Equal(t, model.VersionMatch{4}, ifMatchVersions(newContext("If-Match", `W/"3", "abc", "4"`)))
+
+	// A list naming no strong version matches none
+	versions := ifMatchVersions(newContext("If-Match", `W/"3"`))
+	assert.NotNil(t, versions)
+	assert.False(t, versions.Matches(3))
+	assert.True(t, ifMatchVersions(newContext("If-Match", `"3", "4"`)).Matches(4))
+}
+
+func TestNoneMatch(t *testing.T) {
+	assert.False(t, noneMatch(newContext("If-None-Match", ""), etag(3)))
+	assert.True(t, noneMatch(newContext("If-None-Match", `"1", W/"3"`), etag(3)))
+	assert.True(t, noneMatch(newContext("If-None-Match", "*"), etag(3)))
+	assert.False(t, noneMatch(newContext("If-None-Match", `"2"`), etag(3)))
+}
@@ -1,15 +1,26 @@
 package handler
 
 import (
+	"context"
+	"net/http"
+
 	"proposal-template/models"
 	"proposal-template/pkg/logger"
+	"proposal-template/pkg/query"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golobby/container/v3"
 )
 
 type IUserService interface {
-	GetById(id string) (*model.User, error)
+	GetById(ctx context.Context, id string) (*model.User, error)
+	List(ctx context.Context, paging model.Paging, spec query.Spec) (model.Page[model.User], error)
+	Create(ctx context.Context, input model.UserCreate) (*model.User, error)
+	Update(ctx context.Context, id string, input model.UserUpdate, expectedVersions model.VersionMatch) (*model.User, error)
+	Patch(ctx context.Context, id string, input model.UserPatch, expectedVersions model.VersionMatch) (*model.User, error)
+	Delete(ctx context.Context, id string, expectedVersions model.VersionMatch) error
+	Restore(ctx context.Context, id string) (*model.User, error)
+	VerifyEmail(ctx context.Context, id string, expectedVersions model.VersionMatch) (*model.User, error)
 }
 
 type UserHandler struct {
@@ -34,16 +45,131 @@ func NewUserHandler(opts ...Option) *UserHandler {
 	return userHandler
 }
 
+// GetUserById returns the user with its version as ETag, or 304 Not Modified when the
+// version matches If-None-Match.
 func (u *UserHandler) GetUserById(ctx *gin.Context) {
 	id := ctx.Param("id")
-	data, err := u.UserService.GetById(id)
+	data, err := u.UserService.GetById(ctx.Request.Context(), id)
+	if err != nil {
+		abortWithError(ctx, u.logger, "Error getting user by id", err)
+		return
+	}
+
+	tag := etag(data.Version)
+	ctx.Header("ETag", tag)
+	if noneMatch(ctx, tag) {
+		ctx.Status(http.StatusNotModified)
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"data": data})
+}
+
+// ListUsers returns a page of users, filtered and sorted as described by query.Parse,
+// e.g. GET /users?filter[email][like]=foo&sort=-created_at&limit=20. The next page is
+// read by passing back paging.next_cursor as ?cursor=, add ?with_total=true for a count.
+func (u *UserHandler) ListUsers(ctx *gin.Context) {
+	var paging model.Paging
+	if err := ctx.ShouldBindQuery(&paging); err != nil {
+		abortWithError(ctx, u.logger, "Invalid paging parameters", model.ErrInvalidInput)
+		return
+	}
+	spec, err := query.ParseFor[model.User](ctx.Request.URL.Query())
+	if err != nil {
+		abortWithError(ctx, u.logger, "Invalid filter: "+err.Error(), model.ErrInvalidFilter)
+		return
+	}
+
+	page, err := u.UserService.List(ctx.Request.Context(), paging, spec)
+	if err != nil {
+		abortWithError(ctx, u.logger, "Error listing users", err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, page)
+}
+
+func (u *UserHandler) CreateUser(ctx *gin.Context) {
+	var input model.UserCreate
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		abortWithError(ctx, u.logger, "Invalid create user payload", model.ErrInvalidInput)
+		return
+	}
+
+	data, err := u.UserService.Create(ctx.Request.Context(), input)
+	if err != nil {
+		abortWithError(ctx, u.logger, "Error creating user", err)
+		return
+	}
+
+	ctx.Header("ETag", etag(data.Version))
+	ctx.JSON(http.StatusCreated, gin.H{"data": data})
+}
+
+// UpdateUser replaces the user, only if its current ETag matches If-Match when given.
+func (u *UserHandler) UpdateUser(ctx *gin.Context) {
+	var input model.UserUpdate
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		abortWithError(ctx, u.logger, "Invalid update user payload", model.ErrInvalidInput)
+		return
+	}
+
+	data, err := u.UserService.Update(ctx.Request.Context(), ctx.Param("id"), input, ifMatchVersions(ctx))
+	if err != nil {
+		abortWithError(ctx, u.logger, "Error updating user", err)
+		return
+	}
+
+	ctx.Header("ETag", etag(data.Version))
+	ctx.JSON(http.StatusOK, gin.H{"data": data})
+}
+
+func (u *UserHandler) PatchUser(ctx *gin.Context) {
+	var input model.UserPatch
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		abortWithError(ctx, u.logger, "Invalid patch user payload", model.ErrInvalidInput)
+		return
+	}
+
+	data, err := u.UserService.Patch(ctx.Request.Context(), ctx.Param("id"), input, ifMatchVersions(ctx))
+	if err != nil {
+		abortWithError(ctx, u.logger, "Error patching user", err)
+		return
+	}
+
+	ctx.Header("ETag", etag(data.Version))
+	ctx.JSON(http.StatusOK, gin.H{"data": data})
+}
+
+func (u *UserHandler) DeleteUser(ctx *gin.Context) {
+	if err := u.UserService.Delete(ctx.Request.Context(), ctx.Param("id"), ifMatchVersions(ctx)); err != nil {
+		abortWithError(ctx, u.logger, "Error deleting user", err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+func (u *UserHandler) RestoreUser(ctx *gin.Context) {
+	data, err := u.UserService.Restore(ctx.Request.Context(), ctx.Param("id"))
 	if err != nil {
-		u.logger.Error("Error getting user by id: " + err.Error())
-		ctx.JSON(500, gin.H{"error": err.Error()})
+		abortWithError(ctx, u.logger, "Error restoring user", err)
 		return
 	}
-	
-	ctx.JSON(200, gin.H{"data": data})
+
+	ctx.Header("ETag", etag(data.Version))
+	ctx.JSON(http.StatusOK, gin.H{"data": data})
+}
+
+// VerifyEmail marks the email address of the user as verified, honouring If-Match like PatchUser.
+func (u *UserHandler) VerifyEmail(ctx *gin.Context) {
+	data, err := u.UserService.VerifyEmail(ctx.Request.Context(), ctx.Param("id"), ifMatchVersions(ctx))
+	if err != nil {
+		abortWithError(ctx, u.logger, "Error verifying user email", err)
+		return
+	}
+
+	ctx.Header("ETag", etag(data.Version))
+	ctx.JSON(http.StatusOK, gin.H{"data": data})
 }
 
 // === optional dependencies ===
@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"regexp"
+
+	"proposal-template/pkg/tracing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// RequestIDHeader is the header carrying the request id, in requests and responses
+const RequestIDHeader = "X-Request-ID"
+
+// TraceparentHeader is the W3C trace context header
+const TraceparentHeader = "traceparent"
+
+// requestID restricts the ids accepted from clients, they end up in the logs
+var requestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// RequestID stores the request id and the trace context of the request in its context, see
+// tracing.WithRequestID. The id sent by the client in RequestIDHeader is kept when valid,
+// otherwise a new one is generated. It is echoed in the response.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !requestID.MatchString(id) {
+			id = uuid.NewString()
+		}
+		c.Header(RequestIDHeader, id)
+
+		ctx := tracing.WithRequestID(c.Request.Context(), id)
+		if sc, ok := tracing.ParseTraceparent(c.GetHeader(TraceparentHeader)); ok {
+			ctx = tracing.WithSpanContext(ctx, sc)
+		}
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
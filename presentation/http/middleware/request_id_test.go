@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"proposal-template/pkg/tracing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestID(t *testing.T) {
+	tests := map[string]struct {
+		requestID   string
+		traceparent string
+		keepID      bool
+		wantSpan    tracing.SpanContext
+	}{
+		"client id and trace": {
+			requestID:   "abc-123",
+			traceparent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			keepID:      true,
+			wantSpan:    tracing.SpanContext{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", SpanID: "00f067aa0ba902b7"},
+		},
+		"generated id": {},
+		"invalid id and trace": {
+			requestID:   "bad id\n",
+			traceparent: "00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			router := gin.New()
+			var (
+				seenID   string
+				seenSpan tracing.SpanContext
+			)
+			router.GET("/", RequestID(), func(c *gin.Context) {
+				seenID, _ = tracing.RequestIDFromContext(c.Request.Context())
+				seenSpan, _ = tracing.SpanContextFromContext(c.Request.Context())
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set(RequestIDHeader, tt.requestID)
+			req.Header.Set(TraceparentHeader, tt.traceparent)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.NotEmpty(t, seenID)
+			assert.Equal(t, seenID, w.Header().Get(RequestIDHeader))
+			assert.Equal(t, tt.keepID, seenID == tt.requestID)
+			assert.Equal(t, tt.wantSpan, seenSpan)
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package middleware
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+
+	model "proposal-template/models"
+	"proposal-template/pkg/tenant"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TenantResolver returns the tenant named by a request, or "" when it names none.
+type TenantResolver func(c *gin.Context) string
+
+// DefaultTenantHeader is the header read by HeaderTenant when none is given
+const DefaultTenantHeader = "X-Tenant-ID"
+
+// DefaultClaimsKey is the gin context key ClaimTenant reads the token claims from
+const DefaultClaimsKey = "claims"
+
+// Tenant stores the tenant of the request in its context, see tenant.WithID, where the
+// datalayer scopes the rows of tenant models to it. The resolvers are tried in order and the
+// first tenant found wins. When required is set, requests naming no tenant are rejected
+// with a 400 model.ErrTenantRequired, otherwise they go on without a tenant.
+func Tenant(required bool, resolvers ...TenantResolver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		for _, resolve := range resolvers {
+			if id := strings.TrimSpace(resolve(c)); id != "" {
+				c.Request = c.Request.WithContext(tenant.WithID(c.Request.Context(), id))
+				c.Next()
+				return
+			}
+		}
+
+		if required {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": model.ErrTenantRequired})
+			return
+		}
+		c.Next()
+	}
+}
+
+// HeaderTenant reads the tenant from a request header, DefaultTenantHeader when header is
+// empty. Clients can name any tenant, so it is only safe behind a gateway setting the header.
+func HeaderTenant(header string) TenantResolver {
+	if header == "" {
+		header = DefaultTenantHeader
+	}
+	return func(c *gin.Context) string {
+		return c.GetHeader(header)
+	}
+}
+
+// ClaimTenant reads the tenant from the string claim of a verified token. The authentication
+// middleware must run first and store the claims, e.g. jwt.MapClaims, under claimsKey
+// (DefaultClaimsKey when empty) of the gin context.
+func ClaimTenant(claimsKey string, claim string) TenantResolver {
+	if claimsKey == "" {
+		claimsKey = DefaultClaimsKey
+	}
+	return func(c *gin.Context) string {
+		claims, ok := c.Get(claimsKey)
+		if !ok {
+			return ""
+		}
+
+		// Claims are map[string]interface{} or a named type of it such as jwt.MapClaims
+		v := reflect.ValueOf(claims)
+		if v.Kind() != reflect.Map || v.Type().Key().Kind() != reflect.String {
+			return ""
+		}
+		value := v.MapIndex(reflect.ValueOf(claim).Convert(v.Type().Key()))
+		if !value.IsValid() {
+			return ""
+		}
+		id, _ := value.Interface().(string)
+		return id
+	}
+}
+
+// SubdomainTenant reads the tenant from the leftmost label of the request host below
+// baseDomain, e.g. "acme" for acme.example.com with baseDomain example.com. Hosts outside
+// baseDomain, the base domain itself and nested subdomains name no tenant.
+func SubdomainTenant(baseDomain string) TenantResolver {
+	suffix := "." + strings.ToLower(strings.Trim(baseDomain, "."))
+	return func(c *gin.Context) string {
+		host := strings.ToLower(c.Request.Host)
+		if i := strings.LastIndexByte(host, ':'); i >= 0 && !strings.HasSuffix(host, "]") {
+			host = host[:i]
+		}
+
+		label, ok := strings.CutSuffix(host, suffix)
+		if !ok || strings.Contains(label, ".") {
+			return ""
+		}
+		return label
+	}
+}
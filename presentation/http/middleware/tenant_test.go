@@ -0,0 +1,85 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"proposal-template/pkg/tenant"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// serveTenant runs req through the Tenant middleware and returns the response and the
+// tenant the handler saw.
+func serveTenant(req *http.Request, required bool, resolvers ...TenantResolver) (*httptest.ResponseRecorder, string) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		// Stands for an authentication middleware
+		c.Set(DefaultClaimsKey, map[string]interface{}{"tenant_id": "from-token"})
+	})
+
+	var seen string
+	router.GET("/", Tenant(required, resolvers...), func(c *gin.Context) {
+		seen, _ = tenant.FromContext(c.Request.Context())
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w, seen
+}
+
+func TestTenant_Resolvers(t *testing.T) {
+	tests := map[string]struct {
+		host     string
+		header   string
+		resolver TenantResolver
+		want     string
+	}{
+		"header":             {header: "acme", resolver: HeaderTenant(""), want: "acme"},
+		"claim":              {resolver: ClaimTenant("", "tenant_id"), want: "from-token"},
+		"missing claim":      {resolver: ClaimTenant("", "org"), want: ""},
+		"subdomain":          {host: "Acme.example.com:8080", resolver: SubdomainTenant("example.com"), want: "acme"},
+		"base domain":        {host: "example.com", resolver: SubdomainTenant("example.com"), want: ""},
+		"nested subdomain":   {host: "a.b.example.com", resolver: SubdomainTenant("example.com"), want: ""},
+		"other domain":       {host: "acme.example.org", resolver: SubdomainTenant("example.com"), want: ""},
+		"suffix not a label": {host: "acmeexample.com", resolver: SubdomainTenant("example.com"), want: ""},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.host != "" {
+				req.Host = tt.host
+			}
+			if tt.header != "" {
+				req.Header.Set(DefaultTenantHeader, tt.header)
+			}
+
+			w, seen := serveTenant(req, false, tt.resolver)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, tt.want, seen)
+		})
+	}
+}
+
+func TestTenant_FirstResolverWins(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(DefaultTenantHeader, "acme")
+
+	_, seen := serveTenant(req, true, ClaimTenant("", "tenant_id"), HeaderTenant(""))
+
+	assert.Equal(t, "from-token", seen)
+}
+
+func TestTenant_Required(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	w, _ := serveTenant(req, true, HeaderTenant(""))
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.JSONEq(t, `{"error":{"code":"tenant_required"}}`, w.Body.String())
+}
@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"context"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Timeout bounds the request context with the given duration. Handlers pass
+// c.Request.Context() down to biz and the datalayer, so queries still running when the
+// deadline expires are aborted. A non-positive timeout leaves the request unbounded.
+func Timeout(timeout time.Duration) gin.HandlerFunc {
+	return TimeoutFunc(func() time.Duration { return timeout })
+}
+
+// TimeoutFunc is Timeout with the duration returned by timeout for each request, so that
+// it can change at runtime.
+func TimeoutFunc(timeout func() time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		timeout := timeout()
+		if timeout <= 0 {
+			c.Next()
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
@@ -1,10 +1,16 @@
 package httpserver
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"proposal-template/pkg/lifecycle"
 	"proposal-template/pkg/logger"
+	"sync/atomic"
+	"time"
 	utils "proposal-template/pkg/utils/config"
+	"proposal-template/presentation/http/middleware"
 
 	"github.com/gin-gonic/gin"
 	// ginSwagger "github.com/swaggo/gin-swagger"
@@ -13,14 +19,23 @@ import (
 
 
 var DefaultConfig = utils.HttpServerConfig{
-	Host: "localhost",
-	Port: 8080,
+	Host:                  "localhost",
+	Port:                  8080,
+	ShutdownTimeoutInSecs: 15,
+	RequestTimeoutInSecs:  30,
 }
 
 type HTTPServer struct {
 	config utils.HttpServerConfig
 	logger logger.ILogger
 	router *gin.Engine
+	server *http.Server
+	// Per-route request deadlines keyed by "METHOD /full/path", see WithRouteTimeout
+	routeTimeouts map[string]time.Duration
+	// Deadline of the other routes, see SetRequestTimeout
+	requestTimeout atomic.Int64
+	// Resolves the tenant of /api requests when set, see WithTenantResolution
+	tenantMiddleware gin.HandlerFunc
 }
 
 type Option func(*HTTPServer)
@@ -28,8 +43,9 @@ func NewHTTPServer(opts ...Option) *HTTPServer {
 	gin.SetMode(gin.ReleaseMode)
 
 	hs := &HTTPServer{
-		config: DefaultConfig,
-		router: gin.Default(),
+		config:        DefaultConfig,
+		router:        gin.Default(),
+		routeTimeouts: make(map[string]time.Duration),
 	}
 
 	// Apply functional options
@@ -38,7 +54,12 @@ func NewHTTPServer(opts ...Option) *HTTPServer {
 	}
 
 	// Final setup
+	hs.SetRequestTimeout(time.Duration(hs.config.RequestTimeoutInSecs) * time.Second)
 	hs.SetupRouter()
+	hs.server = &http.Server{
+		Addr:    fmt.Sprintf("%s:%d", hs.config.Host, hs.config.Port),
+		Handler: hs.router,
+	}
 
 	return hs
 }
@@ -52,14 +73,18 @@ func (s *HTTPServer) SetupRouter() {
 	s.logger.Info("Initializing routes...")
 
 	// s.router.Use(middleware.RequestInfoMiddleware(*s.svcCtx))
+	s.router.Use(middleware.RequestID())
 	// s.ServeSwagger()
 	s.addRoute(nil, "GET", "/ping", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"message": "pong"})
 	})
 
 	v1 := s.router.Group("/api/v1")
+	if s.tenantMiddleware != nil {
+		v1.Use(s.tenantMiddleware)
+	}
 	{
-		
+
 		s.SetupUserRouter(v1)
 	}
 }
@@ -70,6 +95,7 @@ func (s *HTTPServer) SetupRouter() {
 
 // addRoute adds a route to the HTTP server. If the group parameter is nil, the route is added to the root router.
 // Otherwise, the route is added to the given group. The description parameter is optional and is used to provide a description for the route.
+// Every route runs behind a timeout middleware, see routeTimeout.
 func (s *HTTPServer) addRoute(group *gin.RouterGroup, method string, path string, handler gin.HandlerFunc, description ...string) {
 	desc := "No description provided" // Default if empty
 
@@ -77,19 +103,52 @@ func (s *HTTPServer) addRoute(group *gin.RouterGroup, method string, path string
 		desc = description[0] // Use first argument if provided
 	}
 
+	fullPath := path
+	if group != nil {
+		fullPath = group.BasePath() + path
+	}
+	timeout := s.routeTimeout(method, fullPath)
+	timeoutMiddleware := middleware.TimeoutFunc(s.RequestTimeout)
+	if _, ok := s.routeTimeouts[method+" "+fullPath]; ok {
+		timeoutMiddleware = middleware.Timeout(timeout)
+	}
+
 	if group == nil {
-		s.router.Handle(method, path, handler)
-		s.logger.Info(fmt.Sprintf("Route initialized - Method: %s, Path: %s, Description: %s", method, path, desc))
+		s.router.Handle(method, path, timeoutMiddleware, handler)
 	} else {
-		group.Handle(method, path, handler)
-		s.logger.Info(fmt.Sprintf("Route initialized - Method: %s, Path: %s, Description: %s", method, group.BasePath()+path, desc))
+		group.Handle(method, path, timeoutMiddleware, handler)
+	}
+	s.logger.Info(fmt.Sprintf("Route initialized - Method: %s, Path: %s, Timeout: %s, Description: %s", method, fullPath, timeout, desc))
+}
+
+// routeTimeout returns the deadline configured for the route with WithRouteTimeout,
+// falling back to the server-wide request timeout.
+func (s *HTTPServer) routeTimeout(method string, fullPath string) time.Duration {
+	if timeout, ok := s.routeTimeouts[method+" "+fullPath]; ok {
+		return timeout
 	}
+	return s.RequestTimeout()
+}
+
+// RequestTimeout returns the deadline of the routes without their own, see WithRouteTimeout.
+func (s *HTTPServer) RequestTimeout() time.Duration {
+	return time.Duration(s.requestTimeout.Load())
+}
+
+// SetRequestTimeout changes the deadline of the routes without their own at runtime, the
+// requests in flight keep theirs.
+func (s *HTTPServer) SetRequestTimeout(timeout time.Duration) {
+	s.requestTimeout.Store(int64(timeout))
 }
-func (s *HTTPServer) Start() error {
-	addr := fmt.Sprintf("%s:%d", s.config.Host, s.config.Port)
 
-	s.logger.Info(fmt.Sprintf("Starting HTTP server on address: %v...", addr))
-	if err := s.router.Run(addr); err != nil {
+var _ lifecycle.Runnable = (*HTTPServer)(nil)
+
+// Start serves HTTP requests until the server fails or Shutdown is called.
+// A call to Shutdown is not an error, so Start returns nil in that case.
+// ctx is not propagated to handlers so that in-flight requests can drain on shutdown.
+func (s *HTTPServer) Start(_ context.Context) error {
+	s.logger.Info(fmt.Sprintf("Starting HTTP server on address: %v...", s.server.Addr))
+	if err := s.server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 		s.logger.Error(fmt.Sprintf("Failed to start HTTP server: %s", err))
 		return err
 	}
@@ -97,6 +156,30 @@ func (s *HTTPServer) Start() error {
 	return nil
 }
 
+// Shutdown stops accepting new connections and waits for in-flight requests to finish.
+// If ctx expires first, the remaining connections are closed and ctx's error is returned.
+func (s *HTTPServer) Shutdown(ctx context.Context) error {
+	s.logger.Info("Shutting down HTTP server...")
+	if err := s.server.Shutdown(ctx); err != nil {
+		s.logger.Error(fmt.Sprintf("HTTP server did not shut down gracefully: %s", err))
+		_ = s.server.Close()
+		return err
+	}
+
+	s.logger.Info("HTTP server stopped")
+	return nil
+}
+
+// Stop implements lifecycle.Runnable by gracefully shutting the server down.
+func (s *HTTPServer) Stop(ctx context.Context) error {
+	return s.Shutdown(ctx)
+}
+
+// ShutdownTimeout returns the grace period given to in-flight requests on shutdown.
+func (s *HTTPServer) ShutdownTimeout() time.Duration {
+	return time.Duration(s.config.ShutdownTimeoutInSecs) * time.Second
+}
+
 
 // === Optional configuration like logger, system config,.... ===
 func WithLogger(logger logger.ILogger) Option {
@@ -105,6 +188,24 @@ func WithLogger(logger logger.ILogger) Option {
 	}
 }
 
+// WithRouteTimeout overrides the request timeout of a single route. fullPath includes the
+// group prefix, e.g. WithRouteTimeout("GET", "/api/v1/users/:id", 2*time.Second).
+// A zero timeout disables the deadline for that route.
+func WithRouteTimeout(method string, fullPath string, timeout time.Duration) Option {
+	return func(s *HTTPServer) {
+		s.routeTimeouts[method+" "+fullPath] = timeout
+	}
+}
+
+// WithTenantResolution makes the /api routes resolve the tenant of each request with the
+// given resolvers, see middleware.Tenant. When required is set, requests naming no tenant
+// are rejected.
+func WithTenantResolution(required bool, resolvers ...middleware.TenantResolver) Option {
+	return func(s *HTTPServer) {
+		s.tenantMiddleware = middleware.Tenant(required, resolvers...)
+	}
+}
+
 func WithConfig(config utils.HttpServerConfig) Option {
 	return func(s *HTTPServer) {
 		if config == (utils.HttpServerConfig{}) { // Prevent assigning an empty config
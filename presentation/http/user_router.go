@@ -7,10 +7,16 @@ import (
 
 // SetupUserRouter configures the routes for the User resource.
 // It sets up a group (prefix) of routes for the User resource
-// and adds a single route, GET /users/:id, which retrieves a
-// User by ID.
+// with the CRUD endpoints under /users.
 func (h *HTTPServer) SetupUserRouter(router *gin.RouterGroup) {
 	userGroup := router.Group("/users")
-	userHandler := handler.NewUserHandler()
-	h.addRoute(userGroup, "GET", "/:id", userHandler.GetUserById)
-}
\ No newline at end of file
+	userHandler := handler.NewUserHandler(handler.WithLogger(h.logger))
+	h.addRoute(userGroup, "POST", "", userHandler.CreateUser, "Create a user")
+	h.addRoute(userGroup, "GET", "", userHandler.ListUsers, "List users, paged with ?page=&limit=")
+	h.addRoute(userGroup, "GET", "/:id", userHandler.GetUserById, "Get a user by ID")
+	h.addRoute(userGroup, "PUT", "/:id", userHandler.UpdateUser, "Replace a user")
+	h.addRoute(userGroup, "PATCH", "/:id", userHandler.PatchUser, "Update some fields of a user")
+	h.addRoute(userGroup, "DELETE", "/:id", userHandler.DeleteUser, "Soft-delete a user")
+	h.addRoute(userGroup, "POST", "/:id/restore", userHandler.RestoreUser, "Restore a soft-deleted user")
+	h.addRoute(userGroup, "POST", "/:id/verify-email", userHandler.VerifyEmail, "Mark the email address of a user as verified")
+}
@@ -0,0 +1,70 @@
+package presentation
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"proposal-template/pkg/kafka"
+	"proposal-template/pkg/lifecycle"
+
+	confluent "github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/golobby/container/v3"
+	"gorm.io/gorm"
+)
+
+// registerResourceClosers registers shutdown hooks for the infrastructure found in the IoC
+// container. Hooks are registered in dependency order (database, schema registry, Kafka
+// producers, Kafka consumers) so the supervisor releases them in reverse.
+// Resources that were never registered are skipped.
+func registerResourceClosers(supervisor *lifecycle.Supervisor) {
+	var db *gorm.DB
+	if err := container.Resolve(&db); err == nil && db != nil {
+		supervisor.OnShutdown("database", func(ctx context.Context) error {
+			sqlDB, err := db.DB()
+			if err != nil {
+				return fmt.Errorf("failed to get underlying sql.DB: %w", err)
+			}
+			return sqlDB.Close()
+		})
+	}
+
+	var schemaRegistry *kafka.SchemaRegistry
+	if err := container.Resolve(&schemaRegistry); err == nil && schemaRegistry != nil {
+		supervisor.OnShutdown("schema registry", func(ctx context.Context) error {
+			schemaRegistry.Close()
+			return nil
+		})
+	}
+
+	var producer *confluent.Producer
+	if err := container.Resolve(&producer); err == nil && producer != nil {
+		supervisor.OnShutdown("kafka producer", func(ctx context.Context) error {
+			defer producer.Close()
+			if remaining := producer.Flush(flushTimeoutMs(ctx)); remaining > 0 {
+				return fmt.Errorf("closed with %d undelivered messages", remaining)
+			}
+			return nil
+		})
+	}
+
+	var consumer *confluent.Consumer
+	if err := container.Resolve(&consumer); err == nil && consumer != nil {
+		supervisor.OnShutdown("kafka consumer", func(ctx context.Context) error {
+			return consumer.Close()
+		})
+	}
+}
+
+// flushTimeoutMs returns the time left before ctx expires, in milliseconds.
+func flushTimeoutMs(ctx context.Context) int {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return 0
+	}
+	remaining := time.Until(deadline)
+	if remaining < 0 {
+		return 0
+	}
+	return int(remaining / time.Millisecond)
+}